@@ -0,0 +1,313 @@
+package aof
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+// The appendfsync policies: sync after every write, sync at most once per
+// FSYNC_INTERVAL off the event loop, or leave syncing to the OS.
+const (
+	FSYNC_ALWAYS   string        = "always"
+	FSYNC_EVERYSEC string        = "everysec"
+	FSYNC_NO       string        = "no"
+	FSYNC_INTERVAL time.Duration = time.Second
+)
+
+var ErrInvalidFsyncPolicy = errors.New("argument(s) must be one of the following: always, everysec, no")
+
+// ValidateFsyncPolicy checks an appendfsync value.
+func ValidateFsyncPolicy(value string) error {
+	switch strings.ToLower(value) {
+	case FSYNC_ALWAYS, FSYNC_EVERYSEC, FSYNC_NO:
+		return nil
+	}
+	return ErrInvalidFsyncPolicy
+}
+
+// Options are the config parameters that decide whether and where the
+// append-only file is written and when it is rewritten. RDB is how the base
+// files are written and checked.
+type Options struct {
+	Enabled           bool
+	Dir               string
+	Dirname           string
+	Filename          string
+	RewritePercentage int
+	RewriteMinSize    int64
+	RDB               rdb.Options
+}
+
+// OptionsFromConfig reads the Options from the server's config parameters.
+func OptionsFromConfig(params map[string]string) Options {
+	percentage, _ := strconv.Atoi(params["auto-aof-rewrite-percentage"])
+	minSize, _ := utils.ParseMemory(params["auto-aof-rewrite-min-size"])
+	return Options{
+		Enabled:           strings.EqualFold(params["appendonly"], "yes"),
+		Dir:               params["dir"],
+		Dirname:           params["appenddirname"],
+		Filename:          params["appendfilename"],
+		RewritePercentage: percentage,
+		RewriteMinSize:    minSize,
+		RDB:               rdb.OptionsFromConfig(params),
+	}
+}
+
+// dirPath is the directory holding the files of the append-only file and
+// their manifest.
+func (o Options) dirPath() string {
+	return filepath.Join(o.Dir, o.Dirname)
+}
+
+// AOF appends the write commands of a server to its append-only file, which
+// is made of a base file, incremental files and a manifest listing them.
+// Commands go to the last incremental file. Fed commands are buffered on the
+// event loop and written out by Flush, which runs before replies are sent,
+// so a client is only told about a write once the file has it. Everything
+// but the fsync goroutine of the everysec policy and the writing of a
+// rewritten base file runs on the event loop; mu guards what those
+// goroutines share.
+type AOF struct {
+	dir        string
+	filename   string
+	manifest   *manifest
+	file       *os.File
+	buf        []byte
+	selectedDB int
+	size       int64 // of the base and incremental files
+	incrSize   int64 // of the last incremental file
+	baseSize   int64 // size as of the last rewrite or of the files loaded
+	unsynced   bool  // written since the last sync started
+
+	rewriting           bool
+	rewriteStart        time.Time
+	lastRewriteTry      time.Time
+	lastRewriteErr      error
+	lastRewriteDuration time.Duration
+	rewrites            int
+
+	mu              sync.Mutex
+	fsyncInProgress bool
+	lastFsync       time.Time
+	lastWriteErr    error
+	rewriteDone     bool
+	rewriteErr      error
+}
+
+func New() *AOF {
+	return &AOF{selectedDB: -1, lastRewriteDuration: -1}
+}
+
+// Open opens the last incremental file in the directory opts point to for
+// appending, making one if there is none. When there is no base file either
+// one is written from database first, so that the keys loaded from the RDB
+// file aren't lost once the server loads the append-only file instead.
+// History files left by a rewrite cut short are deleted.
+func (a *AOF) Open(opts Options, database map[int]map[string]entry.Entry) error {
+	dir := opts.dirPath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	m, err := loadManifest(dir, opts.Filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		m = &manifest{}
+	} else if err != nil {
+		return err
+	}
+	if m.base == nil && len(m.incrs) == 0 {
+		base := m.newBase(opts.Filename)
+		if err := writeBase(dir, base.name, database, opts.RDB); err != nil {
+			return err
+		}
+	}
+	if len(m.incrs) == 0 {
+		m.newIncr(opts.Filename)
+	}
+	var size, incrSize int64
+	for _, file := range m.files() {
+		info, err := os.Stat(filepath.Join(dir, file.name))
+		if errors.Is(err, fs.ErrNotExist) && file.fileType == FILE_TYPE_INCR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		if file != m.base {
+			incrSize = info.Size()
+		}
+	}
+	f, err := os.OpenFile(filepath.Join(dir, m.incrs[len(m.incrs)-1].name), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := persistManifest(dir, opts.Filename, m); err != nil {
+		f.Close()
+		return err
+	}
+	a.dir, a.filename, a.manifest = dir, opts.Filename, m
+	a.file = f
+	// Like Redis, growth is counted from what was loaded, so that a restart
+	// doesn't rewrite a file that only grew before it.
+	a.size, a.baseSize, a.incrSize = size, size, incrSize
+	a.selectedDB = -1
+	a.lastFsync = time.Now()
+	a.deleteHistory()
+	return nil
+}
+
+// writeBase writes database out as the base file name in dir.
+func writeBase(dir string, name string, database map[int]map[string]entry.Entry, opts rdb.Options) error {
+	opts.Dir, opts.Filename = dir, name
+	return rdb.SaveToFile(opts, database)
+}
+
+// Feed buffers a command run in database db for the next Flush, preceded by
+// a SELECT when the last command fed ran in another database.
+func (a *AOF) Feed(db int, args []string) {
+	if a.file == nil {
+		return
+	}
+	if db != a.selectedDB {
+		a.buf = append(a.buf, protocol.ToArrayBulkStrings([]string{"SELECT", strconv.Itoa(db)})...)
+		a.selectedDB = db
+	}
+	a.buf = append(a.buf, protocol.ToArrayBulkStrings(args)...)
+}
+
+// Flush writes out the buffered commands and syncs the file as fsync says.
+// With always a failed write or sync can't be recovered from and exits the
+// server. Otherwise a failed write is undone, the commands are kept for the
+// next Flush and the failure is reported until a write succeeds. As an
+// everysec sync is skipped while another runs or within FSYNC_INTERVAL of
+// the last one, Flush runs on every cron tick too, to sync what it skipped.
+func (a *AOF) Flush(fsync string) {
+	if a.file == nil {
+		return
+	}
+	fsync = strings.ToLower(fsync)
+	if len(a.buf) > 0 {
+		n, err := a.file.Write(a.buf)
+		a.size += int64(n)
+		a.incrSize += int64(n)
+		if err != nil {
+			if fsync == FSYNC_ALWAYS {
+				log.Fatalf("Can't recover from AOF write error when the AOF fsync policy is 'always': %s. Exiting...", err)
+			}
+			log.Printf("Error writing to the AOF file: %s", err)
+			a.undoPartialWrite(n)
+			a.setWriteErr(err)
+			return
+		}
+		a.buf = a.buf[:0]
+		a.unsynced = true
+		a.setWriteErr(nil)
+	}
+	if !a.unsynced {
+		return
+	}
+	switch fsync {
+	case FSYNC_ALWAYS:
+		if err := a.file.Sync(); err != nil {
+			log.Fatalf("Can't recover from AOF fsync error when the AOF fsync policy is 'always': %s. Exiting...", err)
+		}
+		a.unsynced = false
+		a.mu.Lock()
+		a.lastFsync = time.Now()
+		a.mu.Unlock()
+	case FSYNC_EVERYSEC:
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.fsyncInProgress || time.Since(a.lastFsync) < FSYNC_INTERVAL {
+			return
+		}
+		a.fsyncInProgress = true
+		a.lastFsync = time.Now()
+		a.unsynced = false
+		go a.backgroundFsync(a.file)
+	}
+}
+
+func (a *AOF) backgroundFsync(f *os.File) {
+	err := f.Sync()
+	if err != nil {
+		log.Printf("Error syncing the AOF file: %s", err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fsyncInProgress = false
+	if err != nil {
+		a.lastWriteErr = err
+	}
+}
+
+// undoPartialWrite truncates away the n bytes a failed write got into the
+// file, so it never holds half a command. If that fails too they are kept,
+// and dropped from the buffer instead.
+func (a *AOF) undoPartialWrite(n int) {
+	if n == 0 {
+		return
+	}
+	if err := a.file.Truncate(a.incrSize - int64(n)); err != nil {
+		log.Printf("Error truncating a partial AOF write: %s", err)
+		a.buf = a.buf[n:]
+		return
+	}
+	a.size -= int64(n)
+	a.incrSize -= int64(n)
+}
+
+func (a *AOF) setWriteErr(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastWriteErr = err
+}
+
+// AOFStatus is what INFO persistence reports about the append-only file.
+type AOFStatus struct {
+	Enabled             bool
+	LastWriteErr        error
+	CurrentSize         int64
+	BaseSize            int64
+	BufferLength        int
+	PendingFsync        bool
+	RewriteInProgress   bool
+	LastRewriteOK       bool
+	LastRewriteDuration time.Duration // -1 before the first rewrite
+	CurrentRewrite      time.Duration // -1 when none is running
+	Rewrites            int
+}
+
+func (a *AOF) Status() AOFStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	status := AOFStatus{
+		Enabled:             a.file != nil,
+		LastWriteErr:        a.lastWriteErr,
+		CurrentSize:         a.size,
+		BaseSize:            a.baseSize,
+		BufferLength:        len(a.buf),
+		PendingFsync:        a.fsyncInProgress,
+		RewriteInProgress:   a.rewriting,
+		LastRewriteOK:       a.lastRewriteErr == nil,
+		LastRewriteDuration: a.lastRewriteDuration,
+		CurrentRewrite:      -1,
+		Rewrites:            a.rewrites,
+	}
+	if a.rewriting {
+		status.CurrentRewrite = time.Since(a.rewriteStart)
+	}
+	return status
+}
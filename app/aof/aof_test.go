@@ -0,0 +1,293 @@
+package aof
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+)
+
+type replayed struct {
+	db   int
+	args string
+}
+
+func loadAll(t *testing.T, opts Options, database map[int]map[string]entry.Entry) []replayed {
+	got := []replayed{}
+	err := Load(opts, database, func(db int, args []string) error {
+		got = append(got, replayed{db, strings.Join(args, " ")})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error loading: %s", err)
+	}
+	return got
+}
+
+func newOptions(t *testing.T) Options {
+	return Options{Enabled: true, Dir: t.TempDir(), Dirname: "appendonlydir", Filename: "appendonly.aof", RDB: rdb.Options{Checksum: true}}
+}
+
+// waitForRewrite runs Cron until the rewrite in progress is finished.
+func waitForRewrite(t *testing.T, a *AOF, opts Options) {
+	for start := time.Now(); a.Status().RewriteInProgress; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("Expected the rewrite to finish")
+		}
+		a.Cron(opts, nil)
+	}
+}
+
+func dirFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestFeedAndLoad(t *testing.T) {
+	opts := newOptions(t)
+	a := New()
+	if err := a.Open(opts, map[int]map[string]entry.Entry{}); err != nil {
+		t.Fatalf("Error opening: %s", err)
+	}
+	a.Feed(0, []string{"SET", "a", "1"})
+	a.Feed(0, []string{"SET", "b", "line\r\nbreak"})
+	a.Flush(FSYNC_ALWAYS)
+	a.Feed(2, []string{"SADD", "s", "x"})
+	a.Feed(0, []string{"DEL", "a"})
+	a.Flush(FSYNC_EVERYSEC)
+
+	expected := []replayed{{0, "SET a 1"}, {0, "SET b line\r\nbreak"}, {2, "SADD s x"}, {0, "DEL a"}}
+	if got := loadAll(t, opts, map[int]map[string]entry.Entry{}); !slices.Equal(got, expected) {
+		t.Errorf("Expected %q; got %q", expected, got)
+	}
+	expectedFiles := []string{"appendonly.aof.1.base.rdb", "appendonly.aof.1.incr.aof", "appendonly.aof.manifest"}
+	if files := dirFiles(t, opts.dirPath()); !slices.Equal(files, expectedFiles) {
+		t.Errorf("Expected the files %q; got %q", expectedFiles, files)
+	}
+	data, _ := os.ReadFile(filepath.Join(opts.dirPath(), "appendonly.aof.1.incr.aof"))
+	if selects := strings.Count(string(data), "SELECT"); selects != 3 {
+		t.Errorf("Expected a SELECT for each change of database; got %d", selects)
+	}
+	base, _ := os.Stat(filepath.Join(opts.dirPath(), "appendonly.aof.1.base.rdb"))
+	if status := a.Status(); status.CurrentSize != base.Size()+int64(len(data)) || status.BaseSize != base.Size() || status.LastWriteErr != nil {
+		t.Errorf("Expected healthy files of %d bytes over a base of %d; got %+v", base.Size()+int64(len(data)), base.Size(), status)
+	}
+
+	reopened := New()
+	if err := reopened.Open(opts, map[int]map[string]entry.Entry{}); err != nil {
+		t.Fatalf("Error reopening: %s", err)
+	}
+	if status := reopened.Status(); status.BaseSize != status.CurrentSize || status.CurrentSize != base.Size()+int64(len(data)) {
+		t.Errorf("Expected growth to count from the %d bytes loaded; got %+v", base.Size()+int64(len(data)), status)
+	}
+}
+
+func TestLoadTruncated(t *testing.T) {
+	opts := newOptions(t)
+	if err := os.MkdirAll(opts.dirPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "file appendonly.aof.1.incr.aof seq 1 type i\n"
+	if err := os.WriteFile(filepath.Join(opts.dirPath(), "appendonly.aof.manifest"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(opts.dirPath(), "appendonly.aof.1.incr.aof")
+	complete := "*2\r\n$6\r\nSELECT\r\n$1\r\n0\r\n*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+	for _, tail := range []string{"*3\r\n$3\r\nSET\r\n$1\r\nb", "*3\r\n$3\r\nSET\r\n", "*3"} {
+		if err := os.WriteFile(path, []byte(complete+tail), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := loadAll(t, opts, nil); len(got) != 1 || got[0].args != "SET a 1" {
+			t.Errorf("Expected only the complete command before %q; got %q", tail, got)
+		}
+		if data, _ := os.ReadFile(path); string(data) != complete {
+			t.Errorf("Expected the file to be truncated to its complete commands; got %q", data)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(complete+"+OK\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(opts, nil, func(int, []string) error { return nil }); err == nil {
+		t.Errorf("Expected a file that isn't made of commands to fail")
+	}
+
+	if err := os.WriteFile(path, []byte(complete+"*3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest += "file appendonly.aof.2.incr.aof seq 2 type i\n"
+	if err := os.WriteFile(filepath.Join(opts.dirPath(), "appendonly.aof.manifest"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(opts, nil, func(int, []string) error { return nil }); err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing incremental file to fail; got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(opts.dirPath(), "appendonly.aof.2.incr.aof"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(opts, nil, func(int, []string) error { return nil }); err == nil {
+		t.Errorf("Expected a truncated file that isn't the last one to fail")
+	}
+
+	missing := opts
+	missing.Filename = "missing.aof"
+	if err := Load(missing, nil, nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing file to be reported as such; got %v", err)
+	}
+}
+
+func TestOpenWritesBase(t *testing.T) {
+	opts := newOptions(t)
+	database := map[int]map[string]entry.Entry{3: {"k": entry.NewRedisString("v", time.Time{})}}
+	a := New()
+	if err := a.Open(opts, database); err != nil {
+		t.Fatalf("Error opening: %s", err)
+	}
+	a.Feed(3, []string{"SET", "k2", "v2"})
+	a.Flush(FSYNC_NO)
+
+	loaded := map[int]map[string]entry.Entry{}
+	expected := []replayed{{3, "SET k2 v2"}}
+	if got := loadAll(t, opts, loaded); !slices.Equal(got, expected) {
+		t.Errorf("Expected %q after the base file; got %q", expected, got)
+	}
+	if s, ok := loaded[3]["k"].(*entry.RedisString); !ok || s.Value() != "v" {
+		t.Errorf("Expected k from the base file in database 3; got %v", loaded)
+	}
+
+	reopened := New()
+	if err := reopened.Open(opts, nil); err != nil {
+		t.Fatalf("Error reopening: %s", err)
+	}
+	if status := reopened.Status(); status.CurrentSize != a.Status().CurrentSize {
+		t.Errorf("Expected the files to be reopened as they were; got %+v", status)
+	}
+}
+
+func TestLoadUpgradesSingleFile(t *testing.T) {
+	opts := newOptions(t)
+	command := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+	if err := os.WriteFile(filepath.Join(opts.Dir, opts.Filename), []byte(command), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expected := []replayed{{0, "SET a 1"}}
+	if got := loadAll(t, opts, nil); !slices.Equal(got, expected) {
+		t.Errorf("Expected %q; got %q", expected, got)
+	}
+	if _, err := os.Stat(filepath.Join(opts.Dir, opts.Filename)); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the file to be moved; got %v", err)
+	}
+	if got := loadAll(t, opts, nil); !slices.Equal(got, expected) {
+		t.Errorf("Expected %q from the moved file; got %q", expected, got)
+	}
+	a := New()
+	if err := a.Open(opts, nil); err != nil {
+		t.Fatalf("Error opening: %s", err)
+	}
+	expectedFiles := []string{"appendonly.aof", "appendonly.aof.1.incr.aof", "appendonly.aof.manifest"}
+	if files := dirFiles(t, opts.dirPath()); !slices.Equal(files, expectedFiles) {
+		t.Errorf("Expected the moved file as base; got %q", files)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	opts := newOptions(t)
+	database := map[int]map[string]entry.Entry{0: {"a": entry.NewRedisString("1", time.Time{})}}
+	a := New()
+	if err := a.Open(opts, database); err != nil {
+		t.Fatalf("Error opening: %s", err)
+	}
+	database[1] = map[string]entry.Entry{"b": entry.NewRedisString("2", time.Time{})}
+	a.Feed(1, []string{"SET", "b", "2"})
+	a.Flush(FSYNC_NO)
+
+	if err := a.StartRewrite(opts, database); err != nil {
+		t.Fatalf("Error starting the rewrite: %s", err)
+	}
+	if err := a.StartRewrite(opts, database); !errors.Is(err, ErrRewriteInProgress) {
+		t.Errorf("Expected a second rewrite to be refused; got %v", err)
+	}
+	a.Feed(1, []string{"SET", "c", "3"})
+	a.Flush(FSYNC_NO)
+	waitForRewrite(t, a, opts)
+
+	expectedFiles := []string{"appendonly.aof.2.base.rdb", "appendonly.aof.2.incr.aof", "appendonly.aof.manifest"}
+	if files := dirFiles(t, opts.dirPath()); !slices.Equal(files, expectedFiles) {
+		t.Errorf("Expected the files %q; got %q", expectedFiles, files)
+	}
+	manifest, _ := os.ReadFile(filepath.Join(opts.dirPath(), "appendonly.aof.manifest"))
+	expectedManifest := "file appendonly.aof.2.base.rdb seq 2 type b\nfile appendonly.aof.2.incr.aof seq 2 type i\n"
+	if string(manifest) != expectedManifest {
+		t.Errorf("Expected the manifest %q; got %q", expectedManifest, manifest)
+	}
+	loaded := map[int]map[string]entry.Entry{}
+	expected := []replayed{{1, "SET c 3"}}
+	if got := loadAll(t, opts, loaded); !slices.Equal(got, expected) {
+		t.Errorf("Expected only the commands since the rewrite; got %q", got)
+	}
+	if len(loaded[0]) != 1 || len(loaded[1]) != 1 {
+		t.Errorf("Expected a and b from the new base file; got %v", loaded)
+	}
+	if status := a.Status(); status.Rewrites != 1 || !status.LastRewriteOK || status.BaseSize != status.CurrentSize {
+		t.Errorf("Expected a successful rewrite to be the new base size; got %+v", status)
+	}
+}
+
+func TestRewriteWhenOff(t *testing.T) {
+	opts := newOptions(t)
+	opts.Enabled = false
+	a := New()
+	database := map[int]map[string]entry.Entry{0: {"a": entry.NewRedisString("1", time.Time{})}}
+	for range 2 {
+		if err := a.StartRewrite(opts, database); err != nil {
+			t.Fatalf("Error starting the rewrite: %s", err)
+		}
+		waitForRewrite(t, a, opts)
+	}
+	expectedFiles := []string{"appendonly.aof.2.base.rdb", "appendonly.aof.manifest"}
+	if files := dirFiles(t, opts.dirPath()); !slices.Equal(files, expectedFiles) {
+		t.Errorf("Expected the files %q; got %q", expectedFiles, files)
+	}
+	if a.Status().Enabled {
+		t.Errorf("Expected the append-only file to stay off")
+	}
+}
+
+func TestAutoRewrite(t *testing.T) {
+	opts := newOptions(t)
+	opts.RewritePercentage = 100
+	a := New()
+	if err := a.Open(opts, map[int]map[string]entry.Entry{}); err != nil {
+		t.Fatalf("Error opening: %s", err)
+	}
+	opts.RewriteMinSize = 1 << 20
+	for a.Status().CurrentSize < 3*a.Status().BaseSize {
+		a.Feed(0, []string{"SET", "k", "v"})
+		a.Flush(FSYNC_NO)
+	}
+	if a.Cron(opts, nil); a.Status().RewriteInProgress {
+		t.Errorf("Expected no rewrite below auto-aof-rewrite-min-size")
+	}
+	opts.RewriteMinSize = 0
+	if a.Cron(opts, nil); !a.Status().RewriteInProgress {
+		t.Errorf("Expected a rewrite once the file doubled")
+	}
+	waitForRewrite(t, a, opts)
+	if a.Cron(opts, nil); a.Status().RewriteInProgress {
+		t.Errorf("Expected no rewrite right after one")
+	}
+}
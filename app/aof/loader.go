@@ -0,0 +1,222 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+)
+
+// MAX_BULK_LEN is the longest argument a command read back can have, the
+// 512mb default of proto-max-bulk-len.
+const MAX_BULK_LEN int = 512 * 1024 * 1024
+
+// Load replays the files the manifest in the directory opts point to lists:
+// the keys of the base file are added to database, and exec is then called
+// with every command of the incremental files and the database it ran in.
+// An append-only file from before the multi-part layout is moved into the
+// directory as the base file first. The error wraps fs.ErrNotExist when
+// there is neither.
+func Load(opts Options, database map[int]map[string]entry.Entry, exec func(db int, args []string) error) error {
+	m, err := loadManifest(opts.dirPath(), opts.Filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		m, err = upgrade(opts)
+	}
+	if err != nil {
+		return err
+	}
+	files := m.files()
+	if len(files) == 0 {
+		return fmt.Errorf("the AOF manifest lists no files: %w", fs.ErrNotExist)
+	}
+	for i, file := range files {
+		if err := loadFile(opts, file.name, database, exec, i == len(files)-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upgrade moves the append-only file in dir into the directory of the
+// multi-part layout as its base file, the way Redis 7 upgrades one. The
+// manifest is written first, so that the file is never in neither place.
+func upgrade(opts Options) (*manifest, error) {
+	path := filepath.Join(opts.Dir, opts.Filename)
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	dir := opts.dirPath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	m := &manifest{baseSeq: 1, base: &aofFile{name: opts.Filename, seq: 1, fileType: FILE_TYPE_BASE}}
+	if err := persistManifest(dir, opts.Filename, m); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(path, filepath.Join(dir, opts.Filename)); err != nil {
+		return nil, err
+	}
+	if err := syncDir(opts.Dir); err != nil {
+		return nil, err
+	}
+	log.Printf("Successfully migrated an old-style AOF %s into the AOF directory %s", opts.Filename, opts.Dirname)
+	return m, nil
+}
+
+// loadFile replays the file name in the directory opts point to. If it
+// starts with an RDB preamble its keys are added to database, and exec is
+// called with every command after it. Each file starts in database 0. A
+// command cut short at the end of the last file, as a crash in the middle of
+// a write leaves it, is truncated away with a warning, as Redis does with
+// aof-load-truncated.
+func loadFile(opts Options, name string, database map[int]map[string]entry.Entry, exec func(db int, args []string) error, last bool) error {
+	f, err := os.OpenFile(filepath.Join(opts.dirPath(), name), os.O_RDWR, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("the AOF file %s listed in the manifest doesn't exist", name)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(f)
+	var valid int64 // where the last complete command ends
+	if magic, _ := reader.Peek(len(rdb.MAGIC_WORD)); string(magic) == rdb.MAGIC_WORD {
+		preamble, err := rdb.NewRdbFromReader(reader, info.Size(), opts.RDB.Checksum)
+		if err != nil {
+			return fmt.Errorf("loading the RDB preamble of %s: %w", name, err)
+		}
+		for idx, keys := range preamble.Database {
+			if database[idx] == nil {
+				database[idx] = make(map[string]entry.Entry, len(keys))
+			}
+			for key, e := range keys {
+				database[idx][key] = e
+			}
+		}
+		pos, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		valid = pos - int64(reader.Buffered())
+	}
+
+	db := 0
+	for {
+		args, n, err := readCommand(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			if !last {
+				return fmt.Errorf("unexpected end of the AOF file %s, which isn't the last one, at byte %d", name, valid)
+			}
+			log.Printf("!!! Warning: short read while loading the AOF file %s !!!", name)
+			log.Printf("AOF loaded anyway, truncated to its last complete command at byte %d", valid)
+			return f.Truncate(valid)
+		}
+		if err != nil {
+			return fmt.Errorf("bad file format reading the append only file %s at byte %d: %w", name, valid, err)
+		}
+		if strings.EqualFold(args[0], "select") {
+			if len(args) != 2 {
+				return fmt.Errorf("bad SELECT in the append only file %s at byte %d", name, valid)
+			}
+			if db, err = strconv.Atoi(args[1]); err != nil || db < 0 {
+				return fmt.Errorf("bad SELECT in the append only file %s at byte %d", name, valid)
+			}
+		} else if err := exec(db, args); err != nil {
+			return fmt.Errorf("replaying the append only file %s at byte %d: %w", name, valid, err)
+		}
+		valid += int64(n)
+	}
+}
+
+// readCommand reads a command written as a RESP array of bulk strings and
+// returns it with the number of bytes it took. It returns io.EOF when the
+// file ends before the command starts and io.ErrUnexpectedEOF when it ends
+// inside it.
+func readCommand(reader *bufio.Reader) ([]string, int, error) {
+	read := 0
+	line, err := readLine(reader, &read)
+	if err != nil {
+		return nil, read, err
+	}
+	count, err := parseHeader(line, '*')
+	if err != nil {
+		return nil, read, err
+	}
+	if count < 1 {
+		return nil, read, fmt.Errorf("invalid command length %d", count)
+	}
+	args := make([]string, count)
+	for i := range args {
+		line, err := readLine(reader, &read)
+		if err == io.EOF {
+			return nil, read, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, read, err
+		}
+		length, err := parseHeader(line, '$')
+		if err != nil {
+			return nil, read, err
+		}
+		if length > MAX_BULK_LEN {
+			return nil, read, fmt.Errorf("bulk string of %d bytes is over the limit", length)
+		}
+		data := make([]byte, length+2)
+		n, err := io.ReadFull(reader, data)
+		read += n
+		if err != nil {
+			return nil, read, io.ErrUnexpectedEOF
+		}
+		if string(data[length:]) != "\r\n" {
+			return nil, read, errors.New("bulk string isn't terminated by CRLF")
+		}
+		args[i] = string(data[:length])
+	}
+	return args, read, nil
+}
+
+// readLine reads a CRLF-terminated line, adding the bytes it took to read.
+func readLine(reader *bufio.Reader, read *int) (string, error) {
+	line, err := reader.ReadString('\n')
+	*read += len(line)
+	if err == io.EOF {
+		if len(line) > 0 {
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", io.EOF
+	}
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", errors.New("line isn't terminated by CRLF")
+	}
+	return line[:len(line)-2], nil
+}
+
+func parseHeader(line string, prefix byte) (int, error) {
+	if len(line) < 2 || line[0] != prefix {
+		return 0, fmt.Errorf("expected %q, got %q", prefix, line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid length %q", line[1:])
+	}
+	return n, nil
+}
@@ -0,0 +1,262 @@
+package aof
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The types of the files a manifest lists: the base file holding a
+// snapshot, the incremental files holding the commands since, and history
+// files a rewrite made obsolete and that are yet to be deleted.
+const (
+	FILE_TYPE_BASE byte = 'b'
+	FILE_TYPE_HIST byte = 'h'
+	FILE_TYPE_INCR byte = 'i'
+)
+
+const (
+	MANIFEST_SUFFIX string = ".manifest"
+	BASE_SUFFIX     string = ".base.rdb"
+	INCR_SUFFIX     string = ".incr.aof"
+	TEMP_PREFIX     string = "temp-"
+)
+
+type aofFile struct {
+	name     string
+	seq      int
+	fileType byte
+}
+
+// manifest lists the files of a multi-part append-only file the way Redis 7
+// keeps them in appenddirname: the keys are loaded from the base file, and
+// then the commands of the incremental files are replayed in order.
+type manifest struct {
+	base    *aofFile
+	incrs   []*aofFile
+	history []*aofFile
+	baseSeq int // of the last base file made
+	incrSeq int // of the last incremental file made
+}
+
+func manifestName(filename string) string {
+	return filename + MANIFEST_SUFFIX
+}
+
+func (m *manifest) clone() *manifest {
+	c := *m
+	if m.base != nil {
+		base := *m.base
+		c.base = &base
+	}
+	c.incrs = cloneFiles(m.incrs)
+	c.history = cloneFiles(m.history)
+	return &c
+}
+
+func cloneFiles(files []*aofFile) []*aofFile {
+	cloned := make([]*aofFile, len(files))
+	for i, f := range files {
+		copied := *f
+		cloned[i] = &copied
+	}
+	return cloned
+}
+
+// files returns the files to load, in order.
+func (m *manifest) files() []*aofFile {
+	files := []*aofFile{}
+	if m.base != nil {
+		files = append(files, m.base)
+	}
+	return append(files, m.incrs...)
+}
+
+// newBase names the next base file, turning the current one into history.
+func (m *manifest) newBase(filename string) *aofFile {
+	if m.base != nil {
+		m.base.fileType = FILE_TYPE_HIST
+		m.history = append(m.history, m.base)
+	}
+	m.baseSeq++
+	m.base = &aofFile{name: fmt.Sprintf("%s.%d%s", filename, m.baseSeq, BASE_SUFFIX), seq: m.baseSeq, fileType: FILE_TYPE_BASE}
+	return m.base
+}
+
+// newIncr names the next incremental file and adds it after the others.
+func (m *manifest) newIncr(filename string) *aofFile {
+	m.incrSeq++
+	incr := &aofFile{name: fmt.Sprintf("%s.%d%s", filename, m.incrSeq, INCR_SUFFIX), seq: m.incrSeq, fileType: FILE_TYPE_INCR}
+	m.incrs = append(m.incrs, incr)
+	return incr
+}
+
+// incrsToHistory turns all but the last keep incremental files into
+// history.
+func (m *manifest) incrsToHistory(keep int) {
+	n := max(len(m.incrs)-keep, 0)
+	for _, incr := range m.incrs[:n] {
+		incr.fileType = FILE_TYPE_HIST
+		m.history = append(m.history, incr)
+	}
+	m.incrs = m.incrs[n:]
+}
+
+// String writes the manifest out as Redis does, one "file <name> seq <seq>
+// type <type>" line per file.
+func (m *manifest) String() string {
+	var b strings.Builder
+	files := []*aofFile{}
+	if m.base != nil {
+		files = append(files, m.base)
+	}
+	files = append(files, m.history...)
+	for _, f := range append(files, m.incrs...) {
+		name := f.name
+		if strings.ContainsAny(name, " \t\r\n\"'\\") {
+			name = strconv.Quote(name)
+		}
+		fmt.Fprintf(&b, "file %s seq %d type %c\n", name, f.seq, f.fileType)
+	}
+	return b.String()
+}
+
+// parseManifest reads a manifest written by String. Blank lines and lines
+// starting with # are skipped.
+func parseManifest(data string) (*manifest, error) {
+	m := &manifest{}
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		f, err := parseManifestLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AOF manifest line %d: %w", i+1, err)
+		}
+		switch f.fileType {
+		case FILE_TYPE_BASE:
+			if m.base != nil {
+				return nil, fmt.Errorf("invalid AOF manifest line %d: a second base file", i+1)
+			}
+			m.base = f
+			m.baseSeq = f.seq
+		case FILE_TYPE_HIST:
+			m.history = append(m.history, f)
+		case FILE_TYPE_INCR:
+			if f.seq <= m.incrSeq {
+				return nil, fmt.Errorf("invalid AOF manifest line %d: incremental files out of order", i+1)
+			}
+			m.incrs = append(m.incrs, f)
+			m.incrSeq = f.seq
+		}
+	}
+	return m, nil
+}
+
+func parseManifestLine(line string) (*aofFile, error) {
+	fields, err := splitManifestLine(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields)%2 != 0 {
+		return nil, errors.New("keys without values")
+	}
+	f := &aofFile{}
+	for i := 0; i < len(fields); i += 2 {
+		switch value := fields[i+1]; fields[i] {
+		case "file":
+			if value == "" || strings.ContainsRune(value, os.PathSeparator) {
+				return nil, fmt.Errorf("invalid file name %q", value)
+			}
+			f.name = value
+		case "seq":
+			if f.seq, err = strconv.Atoi(value); err != nil || f.seq < 1 {
+				return nil, fmt.Errorf("invalid seq %q", value)
+			}
+		case "type":
+			if len(value) != 1 || !strings.Contains(string([]byte{FILE_TYPE_BASE, FILE_TYPE_HIST, FILE_TYPE_INCR}), value) {
+				return nil, fmt.Errorf("invalid type %q", value)
+			}
+			f.fileType = value[0]
+		}
+	}
+	if f.name == "" || f.seq == 0 || f.fileType == 0 {
+		return nil, errors.New("file, seq and type are all needed")
+	}
+	return f, nil
+}
+
+// splitManifestLine splits line on spaces, reading a field that starts with
+// a double quote as a quoted string.
+func splitManifestLine(line string) ([]string, error) {
+	fields := []string{}
+	for line = strings.TrimLeft(line, " \t"); line != ""; line = strings.TrimLeft(line, " \t") {
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			fields = append(fields, line[:end])
+			line = line[end:]
+			continue
+		}
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return nil, err
+		}
+		field, _ := strconv.Unquote(quoted)
+		fields = append(fields, field)
+		line = line[len(quoted):]
+	}
+	return fields, nil
+}
+
+// loadManifest reads the manifest of filename in dir. The error wraps
+// fs.ErrNotExist when there is none.
+func loadManifest(dir string, filename string) (*manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName(filename)))
+	if err != nil {
+		return nil, err
+	}
+	return parseManifest(string(data))
+}
+
+// persistManifest writes m to a temporary file, syncs it and renames it
+// over the manifest of filename in dir, so that the files listed change all
+// at once.
+func persistManifest(dir string, filename string, m *manifest) error {
+	tmpPath := filepath.Join(dir, TEMP_PREFIX+manifestName(filename))
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(m.String())
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filepath.Join(dir, manifestName(filename)))
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir syncs dir so that a rename into it survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
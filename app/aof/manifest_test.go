@@ -0,0 +1,46 @@
+package aof
+
+import "testing"
+
+func TestManifestRoundTrip(t *testing.T) {
+	m := &manifest{}
+	m.newBase("appendonly.aof")
+	m.newIncr("appendonly.aof")
+	m.newIncr("appendonly.aof")
+	m.newBase("appendonly.aof")
+	m.incrsToHistory(1)
+	m.base.name = "with space.2.base.rdb"
+	expected := "file \"with space.2.base.rdb\" seq 2 type b\n" +
+		"file appendonly.aof.1.base.rdb seq 1 type h\n" +
+		"file appendonly.aof.1.incr.aof seq 1 type h\n" +
+		"file appendonly.aof.2.incr.aof seq 2 type i\n"
+	if got := m.String(); got != expected {
+		t.Fatalf("Expected\n%s; got\n%s", expected, got)
+	}
+	parsed, err := parseManifest("# comment\n\n" + expected)
+	if err != nil {
+		t.Fatalf("Error parsing: %s", err)
+	}
+	if got := parsed.String(); got != expected {
+		t.Errorf("Expected to read back\n%s; got\n%s", expected, got)
+	}
+	if parsed.baseSeq != 2 || parsed.incrSeq != 2 {
+		t.Errorf("Expected the sequences to be read back; got %d and %d", parsed.baseSeq, parsed.incrSeq)
+	}
+}
+
+func TestParseManifestInvalid(t *testing.T) {
+	for _, data := range []string{
+		"file a seq 1",
+		"file a seq 1 type x",
+		"file a seq 0 type i",
+		"file a/b seq 1 type i",
+		"file a seq 1 type b\nfile b seq 2 type b",
+		"file a seq 2 type i\nfile b seq 1 type i",
+		"file \"a seq 1 type i",
+	} {
+		if _, err := parseManifest(data); err == nil {
+			t.Errorf("Expected %q to fail", data)
+		}
+	}
+}
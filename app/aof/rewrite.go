@@ -0,0 +1,209 @@
+package aof
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+)
+
+// REWRITE_RETRY_DELAY is how long an automatic rewrite waits after one
+// failed.
+const REWRITE_RETRY_DELAY time.Duration = 5 * time.Second
+
+var ErrRewriteInProgress = errors.New("ERR Background append only file rewriting already in progress")
+
+// StartRewrite rewrites the append-only file in the background. The
+// commands fed from now on go to a new incremental file, and a snapshot of
+// database is written out as the next base file on another goroutine. Cron
+// swaps the new files in for the ones they replace once it is written. With
+// the append-only file off, the new base file replaces all the files of the
+// manifest in the directory opts point to. Taking the snapshot stalls the
+// caller the way rdb.Snapshot says.
+func (a *AOF) StartRewrite(opts Options, database map[int]map[string]entry.Entry) error {
+	if a.rewriting {
+		return ErrRewriteInProgress
+	}
+	a.lastRewriteTry = time.Now()
+	var err error
+	if a.file == nil {
+		err = a.useDir(opts)
+	} else {
+		err = a.openNewIncr()
+	}
+	if err != nil {
+		a.lastRewriteErr = err
+		return err
+	}
+	base := a.manifest.clone().newBase(a.filename)
+	snapshot, release := rdb.Snapshot(database)
+	a.rewriting = true
+	a.rewriteStart = time.Now()
+	a.mu.Lock()
+	a.rewriteDone = false
+	a.mu.Unlock()
+	dir, rdbOpts := a.dir, opts.RDB
+	go func() {
+		err := writeBase(dir, base.name, snapshot, rdbOpts)
+		release()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.rewriteDone = true
+		a.rewriteErr = err
+	}()
+	log.Printf("Background append only file rewriting started")
+	return nil
+}
+
+// useDir reads the manifest in the directory opts point to, for a rewrite
+// to replace the files it lists while the append-only file is off.
+func (a *AOF) useDir(opts Options) error {
+	dir := opts.dirPath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	m, err := loadManifest(dir, opts.Filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		m = &manifest{}
+	} else if err != nil {
+		return err
+	}
+	a.dir, a.filename, a.manifest = dir, opts.Filename, m
+	return nil
+}
+
+// openNewIncr makes a new incremental file the one commands are written to.
+// The buffered commands are written out first, as they may depend on the
+// database the old file last selected, and the new file is only used once
+// the manifest lists it.
+func (a *AOF) openNewIncr() error {
+	if a.Flush(FSYNC_NO); len(a.buf) > 0 {
+		return errors.New("the AOF buffer can't be written out")
+	}
+	m := a.manifest.clone()
+	incr := m.newIncr(a.filename)
+	path := filepath.Join(a.dir, incr.name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := persistManifest(a.dir, a.filename, m); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	old := a.file
+	go func() {
+		if err := old.Sync(); err != nil {
+			log.Printf("Error syncing the AOF file: %s", err)
+		}
+		old.Close()
+	}()
+	a.manifest = m
+	a.file = f
+	a.incrSize = 0
+	a.selectedDB = -1
+	a.unsynced = false
+	return nil
+}
+
+// Cron finishes a background rewrite once its base file is written, and
+// starts one when the append-only file has grown by opts.RewritePercentage
+// since the last rewrite and is at least opts.RewriteMinSize, unless the
+// last rewrite failed less than REWRITE_RETRY_DELAY ago.
+func (a *AOF) Cron(opts Options, database map[int]map[string]entry.Entry) {
+	if a.rewriting {
+		a.mu.Lock()
+		done, err := a.rewriteDone, a.rewriteErr
+		a.mu.Unlock()
+		if !done {
+			return
+		}
+		a.finishRewrite(err)
+	}
+	if a.file == nil || a.rewriting || opts.RewritePercentage <= 0 || a.size < opts.RewriteMinSize {
+		return
+	}
+	if a.lastRewriteErr != nil && time.Since(a.lastRewriteTry) <= REWRITE_RETRY_DELAY {
+		return
+	}
+	growth := a.size*100/max(a.baseSize, 1) - 100
+	if growth < int64(opts.RewritePercentage) {
+		return
+	}
+	log.Printf("Starting automatic rewriting of AOF on %d%% growth", growth)
+	if err := a.StartRewrite(opts, database); err != nil {
+		log.Printf("Error starting the AOF rewrite: %s", err)
+	}
+}
+
+func (a *AOF) finishRewrite(err error) {
+	a.rewriting = false
+	a.lastRewriteDuration = time.Since(a.rewriteStart)
+	if err == nil {
+		err = a.installRewrite()
+	}
+	a.lastRewriteErr = err
+	if err != nil {
+		log.Printf("Background AOF rewrite failed: %s", err)
+		return
+	}
+	a.rewrites++
+	log.Printf("Background AOF rewrite finished successfully")
+}
+
+// installRewrite lists the new base file in the manifest in place of the
+// files it replaces: the old base file and the incremental files before the
+// one opened when the rewrite started, or all of them with the append-only
+// file off. They are deleted once the manifest no longer lists them.
+func (a *AOF) installRewrite() error {
+	m := a.manifest.clone()
+	base := m.newBase(a.filename)
+	basePath := filepath.Join(a.dir, base.name)
+	keep := 0
+	if a.file != nil {
+		keep = 1
+	}
+	m.incrsToHistory(keep)
+	if err := persistManifest(a.dir, a.filename, m); err != nil {
+		os.Remove(basePath)
+		return fmt.Errorf("persisting the AOF manifest: %w", err)
+	}
+	a.manifest = m
+	if info, err := os.Stat(basePath); err == nil && a.file != nil {
+		a.size = info.Size() + a.incrSize
+		a.baseSize = a.size
+	}
+	a.deleteHistory()
+	return nil
+}
+
+// deleteHistory drops the history files from the manifest and then deletes
+// them, so that the manifest never lists a file that is gone. Files it
+// fails to delete are left behind.
+func (a *AOF) deleteHistory() {
+	if len(a.manifest.history) == 0 {
+		return
+	}
+	m := a.manifest.clone()
+	history := m.history
+	m.history = nil
+	if err := persistManifest(a.dir, a.filename, m); err != nil {
+		log.Printf("Error persisting the AOF manifest: %s", err)
+		return
+	}
+	a.manifest = m
+	for _, f := range history {
+		if err := os.Remove(filepath.Join(a.dir, f.name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Error removing the AOF history file %s: %s", f.name, err)
+			continue
+		}
+		log.Printf("Removed the AOF history file %s", f.name)
+	}
+}
@@ -0,0 +1,34 @@
+package command
+
+import (
+	"errors"
+
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Bgrewriteaof rewrites the append-only file in the background, replacing
+// its files with a base file holding a snapshot of the dataset.
+type Bgrewriteaof struct{}
+
+func (b *Bgrewriteaof) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 0 {
+		writeChan <- wrongNumberOfArgs("bgrewriteaof")
+		return
+	}
+	err := ctx.AOF.StartRewrite(aof.OptionsFromConfig(ctx.ConfigParams), ctx.Store)
+	if errors.Is(err, aof.ErrRewriteInProgress) {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if err != nil {
+		writeChan <- protocol.ToError("ERR Can't execute an AOF background rewriting: " + err.Error())
+		return
+	}
+	writeChan <- protocol.ToSimpleString("Background append only file rewriting started")
+}
+
+func (b *Bgrewriteaof) CanPropogateCommand(args []string) bool {
+	return false
+}
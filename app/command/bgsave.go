@@ -0,0 +1,26 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+)
+
+// Bgsave snapshots the dataset and writes it out in the background.
+type Bgsave struct{}
+
+func (b *Bgsave) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 0 {
+		writeChan <- protocol.ToError(syntaxError)
+		return
+	}
+	if err := ctx.Saver.BackgroundSave(rdb.OptionsFromConfig(ctx.ConfigParams), ctx.Store, nil); err != nil {
+		writeChan <- protocol.ToError(saveError(err))
+		return
+	}
+	writeChan <- protocol.ToSimpleString("Background saving started")
+}
+
+func (b *Bgsave) CanPropogateCommand(args []string) bool {
+	return false
+}
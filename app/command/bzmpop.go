@@ -0,0 +1,60 @@
+package command
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+type Bzmpop struct{}
+
+func (b *Bzmpop) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 4 {
+		writeChan <- wrongNumberOfArgs("bzmpop")
+		return
+	}
+	timeout, err := parseTimeout(args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	keys, fromMax, count, err := zmpopArgs(args[1:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	ctx.RewritePropagation()
+	key, popped, err := zmpopFirst(ctx, keys, fromMax, count)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if popped != nil {
+		ctx.AddDirty(len(popped))
+		ctx.RewritePropagation([]string{popCommandName(fromMax), key, strconv.Itoa(len(popped))})
+		writeChan <- zmpopResp(key, popped)
+		return
+	}
+	ctx.Blocking.Block(&event.BlockedClient{
+		Conn:     ctx.Conn,
+		Database: ctx.CurrentDatabase,
+		Keys:     keys,
+		Serve: func(key string) ([]byte, bool) {
+			_, popped, err := zmpopFirst(ctx, []string{key}, fromMax, count)
+			if err != nil || popped == nil {
+				return nil, false
+			}
+			propagateEffect(ctx, len(popped), []string{popCommandName(fromMax), key, strconv.Itoa(len(popped))})
+			return zmpopResp(key, popped), true
+		},
+		Timeout: func() {
+			utils.WriteToConnection(ctx.Conn, protocol.NullArray())
+		},
+	}, timeout)
+}
+
+func (b *Bzmpop) CanPropogateCommand(args []string) bool {
+	return true
+}
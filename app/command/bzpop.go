@@ -0,0 +1,75 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+// Bzpop implements BZPOPMIN and BZPOPMAX. Whether it pops straight away or
+// once it has been woken up, the pop reaches replicas as the equivalent
+// ZPOPMIN or ZPOPMAX.
+type Bzpop struct {
+	fromMax bool
+}
+
+func (b *Bzpop) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("b" + strings.ToLower(popCommandName(b.fromMax)))
+		return
+	}
+	keys := args[:len(args)-1]
+	timeout, err := parseTimeout(args[len(args)-1])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	ctx.RewritePropagation()
+	for _, key := range keys {
+		zset, err := lookupSortedSetWrite(ctx, key)
+		if err != nil {
+			writeChan <- protocol.ToError(err.Error())
+			return
+		}
+		if zset != nil && zset.Len() > 0 {
+			writeChan <- b.pop(ctx, key, zset)
+			ctx.AddDirty(1)
+			ctx.RewritePropagation([]string{popCommandName(b.fromMax), key})
+			return
+		}
+	}
+	ctx.Blocking.Block(&event.BlockedClient{
+		Conn:     ctx.Conn,
+		Database: ctx.CurrentDatabase,
+		Keys:     keys,
+		Serve: func(key string) ([]byte, bool) {
+			zset, err := lookupSortedSetWrite(ctx, key)
+			if err != nil || zset == nil || zset.Len() == 0 {
+				return nil, false
+			}
+			reply := b.pop(ctx, key, zset)
+			propagateEffect(ctx, 1, []string{popCommandName(b.fromMax), key})
+			return reply, true
+		},
+		Timeout: func() {
+			utils.WriteToConnection(ctx.Conn, protocol.NullArray())
+		},
+	}, timeout)
+}
+
+func (b *Bzpop) pop(ctx *event.Context, key string, zset *entry.SortedSet) []byte {
+	m := zset.Pop(1, b.fromMax)[0]
+	deleteIfEmpty(ctx, key, zset.Len())
+	ret := protocol.ToArrayHeader(3)
+	ret = append(ret, protocol.ToBulkString(key)...)
+	ret = append(ret, protocol.ToBulkString(m.Member)...)
+	ret = append(ret, protocol.ToBulkDouble(m.Score)...)
+	return ret
+}
+
+func (b *Bzpop) CanPropogateCommand(args []string) bool {
+	return true
+}
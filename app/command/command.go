@@ -6,6 +6,7 @@ import (
 
 	"github.com/codecrafters-io/redis-starter-go/app/event"
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
 	"github.com/codecrafters-io/redis-starter-go/app/replication"
 	"github.com/codecrafters-io/redis-starter-go/app/utils"
 )
@@ -28,12 +29,81 @@ func NewCommandRegistry() CommandRegistry {
 	m["config"] = &Config{}
 	m["keys"] = &Keys{}
 	m["info"] = &Info{}
+	m["save"] = &Save{}
+	m["bgsave"] = &Bgsave{}
+	m["lastsave"] = &Lastsave{}
+	m["bgrewriteaof"] = &Bgrewriteaof{}
 	m["replconf"] = &Replconf{}
 	m["psync"] = &Psync{}
 	m["wait"] = &Wait{}
 	m["type"] = &Type{}
 	m["xadd"] = &Xadd{}
-	m["xrange"] = &Xrange{}
+	m["xrange"] = &Xrange{name: "xrange"}
+	m["xrevrange"] = &Xrange{name: "xrevrange", reverse: true}
+	m["xread"] = &Xread{}
+	m["xgroup"] = &Xgroup{}
+	m["xreadgroup"] = &Xreadgroup{}
+	m["xack"] = &Xack{}
+	m["xackdel"] = &Xackdel{}
+	m["xclaim"] = &Xclaim{}
+	m["xautoclaim"] = &Xautoclaim{}
+	m["xpending"] = &Xpending{}
+	m["xinfo"] = &Xinfo{}
+	m["xlen"] = &Xlen{}
+	m["xdel"] = &Xdel{}
+	m["xdelex"] = &Xdelex{}
+	m["xtrim"] = &Xtrim{}
+	m["xsetid"] = &Xsetid{}
+	m["sadd"] = &Sadd{}
+	m["srem"] = &Srem{}
+	m["smembers"] = &Smembers{}
+	m["sismember"] = &Sismember{}
+	m["smismember"] = &Smismember{}
+	m["scard"] = &Scard{}
+	m["smove"] = &Smove{}
+	m["spop"] = &Spop{}
+	m["srandmember"] = &Srandmember{}
+	m["sinter"] = &Sinter{}
+	m["sunion"] = &Sunion{}
+	m["sdiff"] = &Sdiff{}
+	m["sinterstore"] = &Sinterstore{}
+	m["sunionstore"] = &Sunionstore{}
+	m["sdiffstore"] = &Sdiffstore{}
+	m["sintercard"] = &Sintercard{}
+	m["zadd"] = &Zadd{}
+	m["zincrby"] = &Zincrby{}
+	m["zscore"] = &Zscore{}
+	m["zmscore"] = &Zmscore{}
+	m["zrem"] = &Zrem{}
+	m["zcard"] = &Zcard{}
+	m["zrank"] = &Zrank{}
+	m["zrevrank"] = &Zrank{reverse: true}
+	m["zrange"] = &Zrange{name: "zrange"}
+	m["zrangestore"] = &Zrange{name: "zrangestore", store: true}
+	m["zrevrange"] = &Zrange{name: "zrevrange", rangeType: ZRANGE_RANK, reverse: true}
+	m["zrangebyscore"] = &Zrange{name: "zrangebyscore", rangeType: ZRANGE_SCORE}
+	m["zrevrangebyscore"] = &Zrange{name: "zrevrangebyscore", rangeType: ZRANGE_SCORE, reverse: true}
+	m["zrangebylex"] = &Zrange{name: "zrangebylex", rangeType: ZRANGE_LEX}
+	m["zrevrangebylex"] = &Zrange{name: "zrevrangebylex", rangeType: ZRANGE_LEX, reverse: true}
+	m["zcount"] = &Zcount{name: "zcount", rangeType: ZRANGE_SCORE}
+	m["zlexcount"] = &Zcount{name: "zlexcount", rangeType: ZRANGE_LEX}
+	m["zremrangebyrank"] = &Zremrange{name: "zremrangebyrank", rangeType: ZRANGE_RANK}
+	m["zremrangebyscore"] = &Zremrange{name: "zremrangebyscore", rangeType: ZRANGE_SCORE}
+	m["zremrangebylex"] = &Zremrange{name: "zremrangebylex", rangeType: ZRANGE_LEX}
+	m["zunion"] = &Zsetop{name: "zunion", op: ZSET_OP_UNION}
+	m["zinter"] = &Zsetop{name: "zinter", op: ZSET_OP_INTER}
+	m["zdiff"] = &Zsetop{name: "zdiff", op: ZSET_OP_DIFF}
+	m["zunionstore"] = &Zsetop{name: "zunionstore", op: ZSET_OP_UNION, store: true}
+	m["zinterstore"] = &Zsetop{name: "zinterstore", op: ZSET_OP_INTER, store: true}
+	m["zdiffstore"] = &Zsetop{name: "zdiffstore", op: ZSET_OP_DIFF, store: true}
+	m["zintercard"] = &Zintercard{}
+	m["zpopmin"] = &Zpop{}
+	m["zpopmax"] = &Zpop{fromMax: true}
+	m["zmpop"] = &Zmpop{}
+	m["bzpopmin"] = &Bzpop{}
+	m["bzpopmax"] = &Bzpop{fromMax: true}
+	m["bzmpop"] = &Bzmpop{}
+	m["zrandmember"] = &Zrandmember{}
 	return CommandRegistry{Commands: m}
 }
 
@@ -43,31 +113,114 @@ func (cr *CommandRegistry) Handle(cmd utils.Command, ctx *event.Context) error {
 	if !ok {
 		return fmt.Errorf("%s not a valid command", cmd.CMD)
 	}
+	if denied, ok := writesDenied(ctx); ok && (handler.CanPropogateCommand(cmd.ARGS) || cmdLower == "ping") {
+		utils.WriteToConnection(ctx.Conn, protocol.ToError(denied))
+		return nil
+	}
 	writeChan := make(chan []byte, 300)
 	go func() {
 		handler.Handle(cmd.ARGS, ctx, writeChan)
 		close(writeChan)
 	}()
+	// Replies, and those to the blocked clients the write serves, are held
+	// back until the write and what serving them changed are in the
+	// append-only file.
+	replies := [][]byte{}
+	for b := range writeChan {
+		replies = append(replies, b)
+	}
+	if ctx.ReplicationInfo.Role == replication.ROLE_REPLICA {
+		ctx.ReplicationInfo.IncrementServerOffset(cmd.ByteLen)
+	}
+	ctx.Saver.AddDirty(ctx.Dirty())
+	propagateCommand(handler, cmd, ctx)
+	served := ctx.Blocking.HandleReadyKeys()
+	ctx.AOF.Flush(ctx.ConfigParams["appendfsync"])
 	if canRespond(ctx, cmd) {
-		for b := range writeChan {
+		for _, b := range replies {
 			utils.WriteToConnection(ctx.Conn, b)
 		}
 	}
-	switch ctx.ReplicationInfo.Role {
-	case replication.ROLE_MASTER:
-		propagateCommand(handler, cmd, ctx)
-	case replication.ROLE_REPLICA:
-		ctx.ReplicationInfo.IncrementServerOffset(cmd.ByteLen)
+	for _, s := range served {
+		utils.WriteToConnection(s.Conn, s.Reply)
+	}
+	return nil
+}
+
+// Execute runs a command read back from the append-only file. Its replies
+// are dropped and, as it is already persisted, it is neither propagated nor
+// counted as a change.
+func (cr *CommandRegistry) Execute(cmd utils.Command, ctx *event.Context) error {
+	handler, ok := cr.Commands[strings.ToLower(cmd.CMD)]
+	if !ok {
+		return fmt.Errorf("unknown command '%s'", cmd.CMD)
+	}
+	writeChan := make(chan []byte, 300)
+	go func() {
+		handler.Handle(cmd.ARGS, ctx, writeChan)
+		close(writeChan)
+	}()
+	for range writeChan {
 	}
 	return nil
 }
 
+// propagateCommand propagates a write command that changed the dataset;
+// failed and no-op writes are left out.
 func propagateCommand(handler CommandHandler, cmd utils.Command, ctx *event.Context) {
-	if !handler.CanPropogateCommand(cmd.ARGS) {
+	if !handler.CanPropogateCommand(cmd.ARGS) || ctx.Dirty() == 0 {
 		return
 	}
-	msg := protocol.CommandAndArgsToBulkString(cmd.CMD, cmd.ARGS)
-	ctx.ReplicationInfo.PropogateToReplicas(msg)
+	if rewrites, ok := ctx.PropagationRewrite(); ok {
+		for _, rewrite := range rewrites {
+			propagate(ctx, rewrite)
+		}
+		return
+	}
+	propagate(ctx, append([]string{cmd.CMD}, cmd.ARGS...))
+}
+
+// propagate records a write command: it is fed to the append-only file and
+// is sent to the replicas when this server is a master.
+func propagate(ctx *event.Context, args []string) {
+	ctx.AOF.Feed(ctx.CurrentDatabase, args)
+	if ctx.ReplicationInfo.Role != replication.ROLE_MASTER {
+		return
+	}
+	ctx.ReplicationInfo.PropogateToReplicas(protocol.ToArrayBulkStrings(args))
+}
+
+// propagateEffect records changes made outside of a command's own call, by
+// a blocked client being served or by a background job: they count towards
+// the save points and args is propagated.
+func propagateEffect(ctx *event.Context, changes int, args []string) {
+	ctx.Saver.AddDirty(changes)
+	propagate(ctx, args)
+}
+
+const misconfError string = "MISCONF Redis is configured to save RDB snapshots, but it's currently unable to persist to disk. " +
+	"Commands that may modify the data set are disabled, because this instance is configured to report errors during writes " +
+	"if RDB snapshotting fails (stop-writes-on-bgsave-error option). Please check the Redis logs for details about the RDB error."
+
+// writesDenied returns the error a client's writes are refused with while
+// they can't be persisted: the last write to the append-only file failed, or
+// the last background save did while save points and
+// stop-writes-on-bgsave-error are on. Like Redis, it refuses PING as well,
+// so that monitoring notices.
+func writesDenied(ctx *event.Context) (string, bool) {
+	if ctx.ConnType != replication.CONN_TYPE_CLIENT {
+		return "", false
+	}
+	if status := ctx.AOF.Status(); status.Enabled && status.LastWriteErr != nil {
+		return "MISCONF Errors writing to the AOF file: " + status.LastWriteErr.Error(), true
+	}
+	if !strings.EqualFold(ctx.ConfigParams["stop-writes-on-bgsave-error"], "yes") {
+		return "", false
+	}
+	if points, err := rdb.ParseSavePoints(ctx.ConfigParams["save"]); err != nil || len(points) == 0 {
+		return "", false
+	}
+	return misconfError, !ctx.Saver.Status().LastBgsaveOK
 }
 
 func canRespond(ctx *event.Context, cmd utils.Command) bool {
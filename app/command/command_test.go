@@ -0,0 +1,207 @@
+package command
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+func newTestContext() *event.Context {
+	queue := event.NewEventQueue()
+	return &event.Context{
+		Store:        map[int]map[string]entry.Entry{},
+		ConfigParams: map[string]string{},
+		EventQueue:   queue,
+		Blocking:     event.NewBlockingRegistry(queue),
+	}
+}
+
+// run calls the handler of cmd with args and returns its replies as RESP.
+func run(t *testing.T, ctx *event.Context, cmd string, args ...string) string {
+	handler, ok := NewCommandRegistry().Commands[strings.ToLower(cmd)]
+	if !ok {
+		t.Fatalf("Unknown command %s", cmd)
+	}
+	writeChan := make(chan []byte, 16)
+	handler.Handle(args, ctx, writeChan)
+	close(writeChan)
+	var b strings.Builder
+	for reply := range writeChan {
+		b.Write(reply)
+	}
+	return b.String()
+}
+
+// newPropagationTestContext returns a test context whose propagated writes
+// are fed to an append-only file, and a function returning the commands fed
+// to it so far.
+func newPropagationTestContext(t *testing.T) (*event.Context, func() []string) {
+	ctx := newTestContext()
+	ctx.ReplicationInfo = replication.NewReplicationInfo("")
+	ctx.Saver = rdb.NewSaver()
+	ctx.AOF = aof.New()
+	opts := aof.Options{Enabled: true, Dir: t.TempDir(), Dirname: "appendonlydir", Filename: "appendonly.aof"}
+	if err := ctx.AOF.Open(opts, ctx.Store); err != nil {
+		t.Fatalf("Error opening the AOF: %s", err)
+	}
+	return ctx, func() []string {
+		ctx.AOF.Flush(aof.FSYNC_ALWAYS)
+		got := []string{}
+		err := aof.Load(opts, map[int]map[string]entry.Entry{}, func(db int, args []string) error {
+			got = append(got, strings.Join(args, " "))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Error loading the AOF: %s", err)
+		}
+		return got
+	}
+}
+
+// runPropagated runs cmd like run, on a fresh context sharing the state of
+// ctx as the event loop gives each command its own, and then propagates it
+// the way the event loop does.
+func runPropagated(t *testing.T, ctx *event.Context, cmd string, args ...string) string {
+	cmdCtx := event.Context{
+		Conn:            ctx.Conn,
+		CurrentDatabase: ctx.CurrentDatabase,
+		Store:           ctx.Store,
+		ConfigParams:    ctx.ConfigParams,
+		ReplicationInfo: ctx.ReplicationInfo,
+		EventQueue:      ctx.EventQueue,
+		Blocking:        ctx.Blocking,
+		Saver:           ctx.Saver,
+		AOF:             ctx.AOF,
+	}
+	got := run(t, &cmdCtx, cmd, args...)
+	propagateCommand(NewCommandRegistry().Commands[strings.ToLower(cmd)], utils.Command{CMD: cmd, ARGS: args}, &cmdCtx)
+	return got
+}
+
+// newTestClient gives ctx a connection, for the replies written to blocked
+// clients, and returns a function reading the next reply written to it.
+func newTestClient(t *testing.T, ctx *event.Context) func() string {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	ctx.Conn = server
+	return func() string {
+		client.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 1024)
+		n, err := client.Read(buf)
+		if err != nil {
+			t.Fatalf("Error reading a reply: %s", err)
+		}
+		return string(buf[:n])
+	}
+}
+
+// expireBlocked waits for the timeout of a blocked client and runs it as the
+// event loop would, returning the reply it writes.
+func expireBlocked(t *testing.T, ctx *event.Context, read func() string) string {
+	select {
+	case e := <-ctx.EventQueue.Queue:
+		go e.Callback()
+	case <-time.After(time.Second):
+		t.Fatal("Expected the blocked client to time out")
+	}
+	return read()
+}
+
+func TestDirty(t *testing.T) {
+	tests := []struct {
+		cmd      []string
+		expected int
+	}{
+		{[]string{"SADD", "s", "a", "b"}, 2},
+		{[]string{"SADD", "s", "a"}, 0},
+		{[]string{"SADD", "str", "a"}, 0},
+		{[]string{"SREM", "s", "x"}, 0},
+		{[]string{"SREM", "s", "a", "b"}, 2},
+		{[]string{"SET", "str", "v"}, 1},
+		{[]string{"SINTERSTORE", "dst", "missing"}, 0},
+		{[]string{"ZADD", "z", "1", "a", "2", "b"}, 2},
+		{[]string{"ZADD", "z", "1", "a"}, 0},
+		{[]string{"ZADD", "z", "CH", "5", "a"}, 1},
+		{[]string{"ZREM", "z", "x"}, 0},
+		{[]string{"XADD", "x", "1-1", "f", "v"}, 1},
+		{[]string{"XADD", "x", "1-1", "f", "v"}, 0},
+		{[]string{"XDEL", "x", "2-1"}, 0},
+		{[]string{"XTRIM", "x", "MAXLEN", "0"}, 1},
+		{[]string{"XGROUP", "CREATE", "x", "g", "$"}, 1},
+		{[]string{"XGROUP", "CREATE", "x", "g", "$"}, 0},
+		{[]string{"XGROUP", "DELCONSUMER", "x", "g", "c"}, 0},
+	}
+
+	ctx := newTestContext()
+	ctx.Store[0] = map[string]entry.Entry{"str": entry.NewRedisString("v", time.Time{})}
+	for _, tt := range tests {
+		cmdCtx := *ctx
+		run(t, &cmdCtx, tt.cmd[0], tt.cmd[1:]...)
+		if got := cmdCtx.Dirty(); got != tt.expected {
+			t.Errorf("%v: expected %d changes; got %d", tt.cmd, tt.expected, got)
+		}
+	}
+}
+
+func TestExpiredKeys(t *testing.T) {
+	ctx := newTestContext()
+	expired, expiring := entry.NewSet(entry.DEFAULT_SET_MAX_INTSET_ENTRIES), entry.NewSet(entry.DEFAULT_SET_MAX_INTSET_ENTRIES)
+	expired.Add("a")
+	expired.SetExpiryTime(time.Now().Add(-time.Second))
+	expiring.Add("a")
+	expiring.SetExpiryTime(time.Now().Add(time.Hour))
+	ctx.Store[0] = map[string]entry.Entry{"expired": expired, "expiring": expiring}
+
+	if got := run(t, ctx, "TYPE", "expired"); got != "+none\r\n" {
+		t.Errorf("Expected an expired set to be gone; got %q", got)
+	}
+	if _, ok := ctx.Store[0]["expired"]; ok {
+		t.Errorf("Expected the expired set to be deleted once looked up")
+	}
+	if got := run(t, ctx, "SCARD", "expiring"); got != ":1\r\n" {
+		t.Errorf("Expected a set yet to expire to be kept; got %q", got)
+	}
+}
+
+// TestWriteAfterSnapshot checks that commands copy the collections a
+// snapshot shares before writing to them.
+func TestWriteAfterSnapshot(t *testing.T) {
+	ctx := newTestContext()
+	run(t, ctx, "SADD", "s", "a")
+	run(t, ctx, "ZADD", "z", "1", "a")
+	run(t, ctx, "XADD", "x", "1-1", "f", "v")
+	snapshot, release := rdb.Snapshot(ctx.Store)
+	defer release()
+	run(t, ctx, "SADD", "s", "b")
+	run(t, ctx, "ZADD", "z", "2", "b")
+	run(t, ctx, "XADD", "x", "2-1", "f", "v")
+
+	for _, key := range []string{"s", "z", "x"} {
+		if snapshot[0][key] == ctx.Store[0][key] {
+			t.Errorf("%s: expected the keyspace to hold a copy of what the snapshot shares", key)
+		}
+	}
+	if got := snapshot[0]["s"].(*entry.Set).Len(); got != 1 {
+		t.Errorf("Expected the set in the snapshot to keep 1 member; got %d", got)
+	}
+	if got := snapshot[0]["z"].(*entry.SortedSet).Len(); got != 1 {
+		t.Errorf("Expected the sorted set in the snapshot to keep 1 member; got %d", got)
+	}
+	if got := snapshot[0]["x"].(*entry.Stream).Len(); got != 1 {
+		t.Errorf("Expected the stream in the snapshot to keep 1 entry; got %d", got)
+	}
+	if got := run(t, ctx, "SCARD", "s"); got != ":2\r\n" {
+		t.Errorf("Expected the set in the keyspace to have 2 members; got %q", got)
+	}
+}
@@ -1,14 +1,41 @@
 package command
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
 	"github.com/codecrafters-io/redis-starter-go/app/event"
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
 )
 
 type Config struct{}
 
+// configValidators are the parameters CONFIG SET can change, each with the
+// check its new values have to pass.
+var configValidators = map[string]func(value string) error{
+	"dir":                         validateDir,
+	"dbfilename":                  validateFilename,
+	"save":                        validateSavePoints,
+	"stop-writes-on-bgsave-error": validateYesNo,
+	"rdbcompression":              validateYesNo,
+	"rdbchecksum":                 validateYesNo,
+	"appendfsync":                 aof.ValidateFsyncPolicy,
+	"auto-aof-rewrite-percentage": validateNonNegativeInt,
+	"auto-aof-rewrite-min-size":   validateMemory,
+	"stream-retention":            ValidateStreamRetention,
+	"stream-node-max-bytes":       validateNonNegativeInt,
+	"stream-node-max-entries":     validateNonNegativeInt,
+	"set-max-intset-entries":      validateNonNegativeInt,
+	"zset-max-listpack-entries":   validateNonNegativeInt,
+	"zset-max-listpack-value":     validateNonNegativeInt,
+}
+
 func (c *Config) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
 	var ret []byte
 	switch strings.ToLower(args[0]) {
@@ -25,12 +52,124 @@ func (c *Config) Handle(args []string, ctx *event.Context, writeChan chan []byte
 			}
 		}
 		ret = protocol.ToArrayBulkStrings(strs)
+	case "set":
+		ret = configSet(args[1:], ctx)
 	default:
-		ret = []byte("Available CONFIG commands: GET")
+		ret = []byte("Available CONFIG commands: GET, SET")
 	}
 	writeChan <- ret
 }
 
+// configSet sets every parameter/value pair in args, or none of them if any
+// is unknown or has an invalid value.
+func configSet(args []string, ctx *event.Context) []byte {
+	if len(args) == 0 || len(args)%2 != 0 {
+		return wrongNumberOfArgs("config|set")
+	}
+	values := map[string]string{}
+	for i := 0; i < len(args); i += 2 {
+		name := strings.ToLower(args[i])
+		validate, ok := configValidators[name]
+		if !ok {
+			return protocol.ToError(fmt.Sprintf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", args[i]))
+		}
+		if _, ok := values[name]; ok {
+			return protocol.ToError(fmt.Sprintf("ERR CONFIG SET failed (possibly related to argument '%s') - duplicate parameter", args[i]))
+		}
+		if err := validate(args[i+1]); err != nil {
+			return protocol.ToError(fmt.Sprintf("ERR CONFIG SET failed (possibly related to argument '%s') - %s", args[i], err))
+		}
+		values[name] = args[i+1]
+	}
+	for name, value := range values {
+		ctx.ConfigParams[name] = value
+	}
+	return protocol.OkResp()
+}
+
+// startupValidators check the parameters that can only be set when the
+// server is started.
+var startupValidators = map[string]func(value string) error{
+	"appendonly":     validateYesNo,
+	"appendfilename": validateAppendFilename,
+	"appenddirname":  validateAppendDirname,
+}
+
+// ValidateConfig checks the parameters the server is started with. dir is
+// left out: the server runs without it and only fails to save.
+func ValidateConfig(params map[string]string) error {
+	for name, value := range params {
+		validate, ok := configValidators[name]
+		if !ok {
+			validate, ok = startupValidators[name]
+		}
+		if ok && name != "dir" {
+			if err := validate(value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateDir(value string) error {
+	info, err := os.Stat(value)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("not a directory")
+	}
+	return nil
+}
+
+func validateFilename(value string) error {
+	if value == "" || strings.ContainsRune(value, os.PathSeparator) {
+		return errors.New("dbfilename can't be a path, just a filename")
+	}
+	return nil
+}
+
+func validateAppendFilename(value string) error {
+	if value == "" || strings.ContainsRune(value, os.PathSeparator) {
+		return errors.New("appendfilename can't be a path, just a filename")
+	}
+	return nil
+}
+
+func validateAppendDirname(value string) error {
+	if value == "" || strings.ContainsRune(value, os.PathSeparator) {
+		return errors.New("appenddirname can't be a path, just a dirname")
+	}
+	return nil
+}
+
+func validateSavePoints(value string) error {
+	_, err := rdb.ParseSavePoints(value)
+	return err
+}
+
+func validateYesNo(value string) error {
+	if v := strings.ToLower(value); v != "yes" && v != "no" {
+		return errors.New("argument must be 'yes' or 'no'")
+	}
+	return nil
+}
+
+func validateNonNegativeInt(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n < 0 {
+		return errors.New("argument must be a non-negative integer")
+	}
+	return nil
+}
+
+func validateMemory(value string) error {
+	if _, err := utils.ParseMemory(value); err != nil {
+		return errors.New("argument must be a memory value")
+	}
+	return nil
+}
+
 func (c *Config) CanPropogateCommand(args []string) bool {
 	return false
 }
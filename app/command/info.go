@@ -3,19 +3,130 @@ package command
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/app/event"
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
 )
 
 type Info struct{}
 
+// infoSections are the sections INFO knows, in the order INFO with no
+// arguments lists them.
+var infoSections = []struct {
+	name   string
+	fields func(ctx *event.Context) []string
+}{
+	{"persistence", infoPersistence},
+	{"stats", infoStats},
+	{"replication", infoReplication},
+}
+
+// Handle lists the sections asked for, or every section when none is.
 func (i *Info) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
-	role := ctx.ReplicationInfo.Role.String()
-	offset := strconv.Itoa(ctx.ReplicationInfo.GetServerOffset())
-	id := ctx.ReplicationInfo.ReplicationId
-	output := fmt.Sprintf("role:%s\r\nmaster_repl_offset:%s\r\nmaster_replid:%s", role, offset, id)
-	writeChan <- protocol.ToBulkString(output)
+	sections := []string{}
+	for _, section := range infoSections {
+		if len(args) > 0 && !infoSectionRequested(args, section.name) {
+			continue
+		}
+		title := strings.ToUpper(section.name[:1]) + section.name[1:]
+		sections = append(sections, "# "+title+"\r\n"+strings.Join(section.fields(ctx), "\r\n"))
+	}
+	writeChan <- protocol.ToBulkString(strings.Join(sections, "\r\n\r\n"))
+}
+
+func infoSectionRequested(args []string, name string) bool {
+	for _, arg := range args {
+		if strings.EqualFold(arg, name) || strings.EqualFold(arg, "all") || strings.EqualFold(arg, "everything") {
+			return true
+		}
+	}
+	return false
+}
+
+func infoPersistence(ctx *event.Context) []string {
+	status := ctx.Saver.Status()
+	bgsaveStatus := "ok"
+	if !status.LastBgsaveOK {
+		bgsaveStatus = "err"
+	}
+	bgsaveInProgress := 0
+	if status.BgsaveInProgress {
+		bgsaveInProgress = 1
+	}
+	fields := []string{
+		"loading:0",
+		fmt.Sprintf("rdb_changes_since_last_save:%d", status.ChangesSinceLastSave),
+		fmt.Sprintf("rdb_bgsave_in_progress:%d", bgsaveInProgress),
+		fmt.Sprintf("rdb_last_save_time:%d", status.LastSave.Unix()),
+		"rdb_last_bgsave_status:" + bgsaveStatus,
+		fmt.Sprintf("rdb_last_bgsave_time_sec:%d", seconds(status.LastBgsaveDuration)),
+		fmt.Sprintf("rdb_current_bgsave_time_sec:%d", seconds(status.CurrentBgsave)),
+		fmt.Sprintf("rdb_saves:%d", status.Saves),
+	}
+	return append(fields, infoAOF(ctx)...)
+}
+
+// infoStats reports how long the event loop was stalled by the last
+// snapshot of the keyspace, which takes the place of Redis's fork.
+func infoStats(ctx *event.Context) []string {
+	return []string{
+		fmt.Sprintf("latest_fork_usec:%d", rdb.LatestSnapshotDuration().Microseconds()),
+	}
+}
+
+func infoAOF(ctx *event.Context) []string {
+	status := ctx.AOF.Status()
+	fields := []string{
+		fmt.Sprintf("aof_enabled:%d", boolToInt(status.Enabled)),
+		fmt.Sprintf("aof_rewrite_in_progress:%d", boolToInt(status.RewriteInProgress)),
+		fmt.Sprintf("aof_last_rewrite_time_sec:%d", seconds(status.LastRewriteDuration)),
+		fmt.Sprintf("aof_current_rewrite_time_sec:%d", seconds(status.CurrentRewrite)),
+		"aof_last_bgrewrite_status:" + okOrErr(status.LastRewriteOK),
+		fmt.Sprintf("aof_rewrites:%d", status.Rewrites),
+		"aof_last_write_status:" + okOrErr(status.LastWriteErr == nil),
+	}
+	if !status.Enabled {
+		return fields
+	}
+	return append(fields,
+		fmt.Sprintf("aof_current_size:%d", status.CurrentSize),
+		fmt.Sprintf("aof_base_size:%d", status.BaseSize),
+		fmt.Sprintf("aof_buffer_length:%d", status.BufferLength),
+		fmt.Sprintf("aof_pending_bio_fsync:%d", boolToInt(status.PendingFsync)),
+	)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func okOrErr(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "err"
+}
+
+// seconds rounds d to whole seconds, keeping -1 for "never".
+func seconds(d time.Duration) int {
+	if d < 0 {
+		return -1
+	}
+	return int(d.Round(time.Second) / time.Second)
+}
+
+func infoReplication(ctx *event.Context) []string {
+	return []string{
+		"role:" + ctx.ReplicationInfo.Role.String(),
+		"master_repl_offset:" + strconv.Itoa(ctx.ReplicationInfo.GetServerOffset()),
+		"master_replid:" + ctx.ReplicationInfo.ReplicationId,
+	}
 }
 
 func (i *Info) CanPropogateCommand(args []string) bool {
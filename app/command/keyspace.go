@@ -0,0 +1,200 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+const wrongTypeError string = "WRONGTYPE Operation against a key holding the wrong kind of value"
+const notIntegerError string = "ERR value is not an integer or out of range"
+const syntaxError string = "ERR syntax error"
+
+var errWrongType = errors.New(wrongTypeError)
+
+func wrongNumberOfArgs(cmd string) []byte {
+	return protocol.ToError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", cmd))
+}
+
+// database returns the current database of ctx, creating it if needed.
+func database(ctx *event.Context) map[string]entry.Entry {
+	if _, ok := ctx.Store[ctx.CurrentDatabase]; !ok {
+		ctx.Store[ctx.CurrentDatabase] = make(map[string]entry.Entry)
+	}
+	return ctx.Store[ctx.CurrentDatabase]
+}
+
+// lookupKey returns the entry at key, deleting it instead once it has
+// expired like GET does.
+func lookupKey(ctx *event.Context, key string) (entry.Entry, bool) {
+	e, ok := ctx.Store[ctx.CurrentDatabase][key]
+	if ok && entry.IsExpired(e, time.Now()) {
+		delete(ctx.Store[ctx.CurrentDatabase], key)
+		return nil, false
+	}
+	return e, ok
+}
+
+// lookupKeyWrite is lookupKey for a command about to change the entry at
+// key. An entry a snapshot still shares is replaced by a copy first.
+func lookupKeyWrite(ctx *event.Context, key string) (entry.Entry, bool) {
+	e, ok := lookupKey(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	if w := entry.Writable(e); w != e {
+		ctx.Store[ctx.CurrentDatabase][key] = w
+		return w, true
+	}
+	return e, true
+}
+
+// lookupSet returns the set at key, or nil if there is no such key.
+func lookupSet(ctx *event.Context, key string) (*entry.Set, error) {
+	return asSet(lookupKey(ctx, key))
+}
+
+// lookupSetWrite is lookupSet for a command about to change the set.
+func lookupSetWrite(ctx *event.Context, key string) (*entry.Set, error) {
+	return asSet(lookupKeyWrite(ctx, key))
+}
+
+func asSet(e entry.Entry, ok bool) (*entry.Set, error) {
+	if !ok {
+		return nil, nil
+	}
+	s, ok := e.(*entry.Set)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+// lookupOrCreateSet returns the set at key, storing a new empty set there if
+// there is no such key.
+func lookupOrCreateSet(ctx *event.Context, key string) (*entry.Set, error) {
+	s, err := lookupSetWrite(ctx, key)
+	if err != nil || s != nil {
+		return s, err
+	}
+	s = entry.NewSet(configInt(ctx, "set-max-intset-entries", entry.DEFAULT_SET_MAX_INTSET_ENTRIES))
+	database(ctx)[key] = s
+	return s, nil
+}
+
+// lookupSets returns the sets at keys in order, with nil for missing keys.
+func lookupSets(ctx *event.Context, keys []string) ([]*entry.Set, error) {
+	sets := make([]*entry.Set, len(keys))
+	for i, key := range keys {
+		s, err := lookupSet(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = s
+	}
+	return sets, nil
+}
+
+// storeSet replaces whatever is at key with a set of members, deleting the key
+// instead when there are no members.
+func storeSet(ctx *event.Context, key string, members []string) {
+	if len(members) == 0 {
+		deleteKey(ctx, key)
+		return
+	}
+	s := entry.NewSet(configInt(ctx, "set-max-intset-entries", entry.DEFAULT_SET_MAX_INTSET_ENTRIES))
+	for _, member := range members {
+		s.Add(member)
+	}
+	database(ctx)[key] = s
+	ctx.AddDirty(1)
+}
+
+// lookupSortedSet returns the sorted set at key, or nil if there is no such
+// key.
+func lookupSortedSet(ctx *event.Context, key string) (*entry.SortedSet, error) {
+	return asSortedSet(lookupKey(ctx, key))
+}
+
+// lookupSortedSetWrite is lookupSortedSet for a command about to change the
+// sorted set.
+func lookupSortedSetWrite(ctx *event.Context, key string) (*entry.SortedSet, error) {
+	return asSortedSet(lookupKeyWrite(ctx, key))
+}
+
+func asSortedSet(e entry.Entry, ok bool) (*entry.SortedSet, error) {
+	if !ok {
+		return nil, nil
+	}
+	z, ok := e.(*entry.SortedSet)
+	if !ok {
+		return nil, errWrongType
+	}
+	return z, nil
+}
+
+// lookupOrCreateSortedSet returns the sorted set at key, storing a new empty
+// sorted set there if there is no such key.
+func lookupOrCreateSortedSet(ctx *event.Context, key string) (*entry.SortedSet, error) {
+	z, err := lookupSortedSetWrite(ctx, key)
+	if err != nil || z != nil {
+		return z, err
+	}
+	z = newSortedSet(ctx)
+	database(ctx)[key] = z
+	signalKeyAsReady(ctx, key)
+	return z, nil
+}
+
+func newSortedSet(ctx *event.Context) *entry.SortedSet {
+	return entry.NewSortedSet(
+		configInt(ctx, "zset-max-listpack-entries", entry.DEFAULT_ZSET_MAX_LISTPACK_ENTRIES),
+		configInt(ctx, "zset-max-listpack-value", entry.DEFAULT_ZSET_MAX_LISTPACK_VALUE),
+	)
+}
+
+func newStream(ctx *event.Context) *entry.Stream {
+	return entry.NewStream(
+		configInt(ctx, "stream-node-max-bytes", entry.DEFAULT_STREAM_NODE_MAX_BYTES),
+		configInt(ctx, "stream-node-max-entries", entry.DEFAULT_STREAM_NODE_MAX_ENTRIES),
+	)
+}
+
+// signalKeyAsReady lets clients blocked on key know it may now have data for
+// them once the current command is done.
+func signalKeyAsReady(ctx *event.Context, key string) {
+	ctx.Blocking.SignalKeyAsReady(ctx.CurrentDatabase, key)
+}
+
+// deleteKey removes key, counting it as a change if it was there.
+func deleteKey(ctx *event.Context, key string) {
+	if _, ok := ctx.Store[ctx.CurrentDatabase][key]; ok {
+		delete(ctx.Store[ctx.CurrentDatabase], key)
+		ctx.AddDirty(1)
+	}
+}
+
+// deleteIfEmpty removes key once the collection stored there has no elements
+// left, as Redis never keeps empty aggregate values around.
+func deleteIfEmpty(ctx *event.Context, key string, length int) {
+	if length == 0 {
+		delete(ctx.Store[ctx.CurrentDatabase], key)
+	}
+}
+
+func configInt(ctx *event.Context, name string, defaultValue int) int {
+	v, ok := ctx.ConfigParams[name]
+	if !ok {
+		return defaultValue
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return defaultValue
+	}
+	return i
+}
@@ -0,0 +1,20 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Lastsave struct{}
+
+func (l *Lastsave) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 0 {
+		writeChan <- wrongNumberOfArgs("lastsave")
+		return
+	}
+	writeChan <- protocol.ToRespInt(int(ctx.Saver.LastSave().Unix()))
+}
+
+func (l *Lastsave) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Sadd struct{}
+
+func (s *Sadd) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("sadd")
+		return
+	}
+	set, err := lookupOrCreateSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	added := 0
+	for _, member := range args[1:] {
+		if set.Add(member) {
+			added++
+		}
+	}
+	ctx.AddDirty(added)
+	writeChan <- protocol.ToRespInt(added)
+}
+
+func (s *Sadd) CanPropogateCommand(args []string) bool {
+	return true
+}
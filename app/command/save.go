@@ -0,0 +1,38 @@
+package command
+
+import (
+	"errors"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+)
+
+// Save writes the dataset to dir/dbfilename before replying, blocking every
+// other client meanwhile.
+type Save struct{}
+
+func (s *Save) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 0 {
+		writeChan <- wrongNumberOfArgs("save")
+		return
+	}
+	if err := ctx.Saver.Save(rdb.OptionsFromConfig(ctx.ConfigParams), ctx.Store); err != nil {
+		writeChan <- protocol.ToError(saveError(err))
+		return
+	}
+	writeChan <- protocol.OkResp()
+}
+
+// saveError turns a failed save into an error reply, keeping the ones that
+// are already replies as they are.
+func saveError(err error) string {
+	if errors.Is(err, rdb.ErrBgsaveInProgress) {
+		return err.Error()
+	}
+	return "ERR " + err.Error()
+}
+
+func (s *Save) CanPropogateCommand(args []string) bool {
+	return false
+}
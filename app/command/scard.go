@@ -0,0 +1,29 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Scard struct{}
+
+func (s *Scard) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 1 {
+		writeChan <- wrongNumberOfArgs("scard")
+		return
+	}
+	set, err := lookupSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if set == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	writeChan <- protocol.ToRespInt(set.Len())
+}
+
+func (s *Scard) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -0,0 +1,28 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Sdiffstore struct{}
+
+func (s *Sdiffstore) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("sdiffstore")
+		return
+	}
+	sets, err := lookupSets(ctx, args[1:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	members := entry.SetDifference(sets)
+	storeSet(ctx, args[0], members)
+	writeChan <- protocol.ToRespInt(len(members))
+}
+
+func (s *Sdiffstore) CanPropogateCommand(args []string) bool {
+	return true
+}
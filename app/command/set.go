@@ -21,25 +21,34 @@ func (s *Set) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
 	}
 	var expTime time.Time
 	if len(args) == 4 {
-		if strings.ToLower(args[2]) != "px" {
+		option := strings.ToLower(args[2])
+		if option != "px" && option != "pxat" {
 			writeUsageString(ctx.Conn)
 			return
 		}
-		expiryDelta, err := strconv.Atoi(args[3])
+		expiry, err := strconv.ParseInt(args[3], 10, 64)
 		if err != nil {
 			writeUsageString(ctx.Conn)
 			return
 		}
-		expTime = time.Now().Add(time.Millisecond * time.Duration(expiryDelta))
+		if option == "px" {
+			expTime = time.Now().Add(time.Millisecond * time.Duration(expiry))
+		} else {
+			expTime = time.UnixMilli(expiry)
+		}
+		// A relative expiry would start over wherever the command is
+		// replayed, so it is propagated as the absolute time.
+		ctx.RewritePropagation([]string{"SET", args[0], args[1], "PXAT", strconv.FormatInt(expTime.UnixMilli(), 10)})
 	}
 	if _, ok := ctx.Store[ctx.CurrentDatabase]; !ok {
 		ctx.Store[ctx.CurrentDatabase] = make(map[string]entry.Entry)
 	}
 	ctx.Store[ctx.CurrentDatabase][args[0]] = entry.NewRedisString(args[1], expTime)
+	ctx.AddDirty(1)
 	writeChan <- protocol.OkResp()
 }
 
-const usageStr string = "Usage: SET <key> <value> [PX | milliseconds]"
+const usageStr string = "Usage: SET <key> <value> [PX milliseconds | PXAT unix-time-milliseconds]"
 
 func writeUsageString(conn net.Conn) {
 	utils.WriteToConnection(conn, []byte(usageStr))
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Sinter struct{}
+
+func (s *Sinter) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 1 {
+		writeChan <- wrongNumberOfArgs("sinter")
+		return
+	}
+	sets, err := lookupSets(ctx, args[0:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	writeChan <- protocol.ToArrayBulkStrings(entry.SetIntersection(sets, 0))
+}
+
+func (s *Sinter) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -0,0 +1,65 @@
+package command
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Sintercard struct{}
+
+func (s *Sintercard) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("sintercard")
+		return
+	}
+	numKeys, limit, err := parseIntercardArgs(args)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	sets, err := lookupSets(ctx, args[1:1+numKeys])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	writeChan <- protocol.ToRespInt(len(entry.SetIntersection(sets, limit)))
+}
+
+func (s *Sintercard) CanPropogateCommand(args []string) bool {
+	return false
+}
+
+// parseIntercardArgs parses the numkeys and the LIMIT option SINTERCARD and
+// ZINTERCARD take around their keys. A limit of 0 means none.
+func parseIntercardArgs(args []string) (int, int, error) {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, 0, errors.New(notIntegerError)
+	}
+	if numKeys <= 0 {
+		return 0, 0, errors.New("ERR numkeys should be greater than 0")
+	}
+	if numKeys > len(args)-1 {
+		return 0, 0, errors.New("ERR Number of keys can't be greater than number of args")
+	}
+	limit := 0
+	rest := args[1+numKeys:]
+	for i := 0; i < len(rest); i++ {
+		if strings.ToLower(rest[i]) != "limit" || i+1 >= len(rest) {
+			return 0, 0, errors.New(syntaxError)
+		}
+		i++
+		if limit, err = strconv.Atoi(rest[i]); err != nil {
+			return 0, 0, errors.New(notIntegerError)
+		}
+		if limit < 0 {
+			return 0, 0, errors.New("ERR LIMIT can't be negative")
+		}
+	}
+	return numKeys, limit, nil
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Sismember struct{}
+
+func (s *Sismember) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 2 {
+		writeChan <- wrongNumberOfArgs("sismember")
+		return
+	}
+	set, err := lookupSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if set == nil || !set.Contains(args[1]) {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	writeChan <- protocol.ToRespInt(1)
+}
+
+func (s *Sismember) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Smembers struct{}
+
+func (s *Smembers) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 1 {
+		writeChan <- wrongNumberOfArgs("smembers")
+		return
+	}
+	set, err := lookupSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if set == nil {
+		writeChan <- protocol.ToArrayBulkStrings([]string{})
+		return
+	}
+	writeChan <- protocol.ToArrayBulkStrings(set.Members())
+}
+
+func (s *Smembers) CanPropogateCommand(args []string) bool {
+	return false
+}
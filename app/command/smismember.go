@@ -0,0 +1,33 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Smismember struct{}
+
+func (s *Smismember) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("smismember")
+		return
+	}
+	set, err := lookupSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	ret := protocol.ToArrayHeader(len(args) - 1)
+	for _, member := range args[1:] {
+		if set != nil && set.Contains(member) {
+			ret = append(ret, protocol.ToRespInt(1)...)
+		} else {
+			ret = append(ret, protocol.ToRespInt(0)...)
+		}
+	}
+	writeChan <- ret
+}
+
+func (s *Smismember) CanPropogateCommand(args []string) bool {
+	return false
+}
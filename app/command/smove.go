@@ -0,0 +1,46 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Smove struct{}
+
+func (s *Smove) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 3 {
+		writeChan <- wrongNumberOfArgs("smove")
+		return
+	}
+	source, destination, member := args[0], args[1], args[2]
+	src, err := lookupSetWrite(ctx, source)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	dst, err := lookupSetWrite(ctx, destination)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if src == nil || !src.Contains(member) {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	if source == destination {
+		writeChan <- protocol.ToRespInt(1)
+		return
+	}
+	src.Remove(member)
+	deleteIfEmpty(ctx, source, src.Len())
+	if dst == nil {
+		dst, _ = lookupOrCreateSet(ctx, destination)
+	}
+	dst.Add(member)
+	ctx.AddDirty(1)
+	writeChan <- protocol.ToRespInt(1)
+}
+
+func (s *Smove) CanPropogateCommand(args []string) bool {
+	return true
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Spop struct{}
+
+// Handle propagates the popped members as SREM rather than SPOP, because
+// replicas would otherwise pick their own random members.
+func (s *Spop) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 1 && len(args) != 2 {
+		writeChan <- wrongNumberOfArgs("spop")
+		return
+	}
+	key := args[0]
+	count := 1
+	if len(args) == 2 {
+		c, err := strconv.Atoi(args[1])
+		if err != nil || c < 0 {
+			writeChan <- protocol.ToError("ERR value is out of range, must be positive")
+			return
+		}
+		count = c
+	}
+	set, err := lookupSetWrite(ctx, key)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if set == nil || count == 0 {
+		ctx.RewritePropagation()
+		if len(args) == 2 {
+			writeChan <- protocol.ToArrayBulkStrings([]string{})
+		} else {
+			writeChan <- protocol.NullBulkString()
+		}
+		return
+	}
+	popped := set.RandomMembers(count)
+	for _, member := range popped {
+		set.Remove(member)
+	}
+	deleteIfEmpty(ctx, key, set.Len())
+	ctx.AddDirty(len(popped))
+	ctx.RewritePropagation(append([]string{"SREM", key}, popped...))
+	if len(args) == 2 {
+		writeChan <- protocol.ToArrayBulkStrings(popped)
+		return
+	}
+	writeChan <- protocol.ToBulkString(popped[0])
+}
+
+func (s *Spop) CanPropogateCommand(args []string) bool {
+	return true
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Srandmember struct{}
+
+// Handle returns distinct members for a positive count and allows the same
+// member to be returned several times for a negative one.
+func (s *Srandmember) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 1 && len(args) != 2 {
+		writeChan <- wrongNumberOfArgs("srandmember")
+		return
+	}
+	set, err := lookupSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if len(args) == 1 {
+		if set == nil {
+			writeChan <- protocol.NullBulkString()
+			return
+		}
+		member, _ := set.RandomMember()
+		writeChan <- protocol.ToBulkString(member)
+		return
+	}
+	count, err := strconv.Atoi(args[1])
+	if err != nil {
+		writeChan <- protocol.ToError(notIntegerError)
+		return
+	}
+	if err := entry.CheckRandomCount(count, false); err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if set == nil || count == 0 {
+		writeChan <- protocol.ToArrayBulkStrings([]string{})
+		return
+	}
+	if count > 0 {
+		writeChan <- protocol.ToArrayBulkStrings(set.RandomMembers(count))
+		return
+	}
+	writeChan <- protocol.ToArrayBulkStrings(set.RandomMembersWithRepetition(-count))
+}
+
+func (s *Srandmember) CanPropogateCommand(args []string) bool {
+	return false
+}
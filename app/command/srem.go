@@ -0,0 +1,37 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Srem struct{}
+
+func (s *Srem) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("srem")
+		return
+	}
+	set, err := lookupSetWrite(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if set == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	removed := 0
+	for _, member := range args[1:] {
+		if set.Remove(member) {
+			removed++
+		}
+	}
+	deleteIfEmpty(ctx, args[0], set.Len())
+	ctx.AddDirty(removed)
+	writeChan <- protocol.ToRespInt(removed)
+}
+
+func (s *Srem) CanPropogateCommand(args []string) bool {
+	return true
+}
@@ -0,0 +1,260 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// lookupStream returns the stream at key, or nil if there is no such key.
+func lookupStream(ctx *event.Context, key string) (*entry.Stream, error) {
+	return asStream(lookupKey(ctx, key))
+}
+
+// lookupStreamWrite is lookupStream for a command about to change the
+// stream or its groups.
+func lookupStreamWrite(ctx *event.Context, key string) (*entry.Stream, error) {
+	return asStream(lookupKeyWrite(ctx, key))
+}
+
+func asStream(e entry.Entry, ok bool) (*entry.Stream, error) {
+	if !ok {
+		return nil, nil
+	}
+	s, ok := e.(*entry.Stream)
+	if !ok {
+		return nil, errWrongType
+	}
+	return s, nil
+}
+
+// parseStreamTrim parses "MAXLEN|MINID [=|~] threshold [LIMIT count]"
+// starting at args[i], returning the trim and the index of the first
+// argument after it.
+func parseStreamTrim(ctx *event.Context, args []string, i int) (entry.StreamTrim, int, error) {
+	var trim entry.StreamTrim
+	switch strings.ToLower(args[i]) {
+	case "maxlen":
+		trim.Strategy = entry.STREAM_TRIM_MAXLEN
+	case "minid":
+		trim.Strategy = entry.STREAM_TRIM_MINID
+	default:
+		return trim, i, errors.New(syntaxError)
+	}
+	i++
+	if i < len(args) && (args[i] == "=" || args[i] == "~") {
+		trim.Approx = args[i] == "~"
+		i++
+	}
+	if i >= len(args) {
+		return trim, i, errors.New(syntaxError)
+	}
+	if trim.Strategy == entry.STREAM_TRIM_MAXLEN {
+		maxLen, err := strconv.Atoi(args[i])
+		if err != nil {
+			return trim, i, errors.New(notIntegerError)
+		}
+		if maxLen < 0 {
+			return trim, i, errors.New("ERR The MAXLEN argument must be >= 0.")
+		}
+		trim.MaxLen = maxLen
+	} else {
+		minID, err := entry.ParseStreamID(args[i])
+		if err != nil {
+			return trim, i, err
+		}
+		trim.MinID = minID
+	}
+	i++
+	hasLimit := false
+	if i+1 < len(args) && strings.ToLower(args[i]) == "limit" {
+		limit, err := strconv.Atoi(args[i+1])
+		if err != nil || limit < 0 {
+			return trim, i, errors.New("ERR The LIMIT argument must be >= 0.")
+		}
+		trim.Limit = limit
+		hasLimit = true
+		i += 2
+	}
+	if hasLimit && !trim.Approx {
+		return trim, i, errors.New("ERR syntax error, LIMIT cannot be used without the special ~ option")
+	}
+	if trim.Approx && !hasLimit {
+		trim.Limit = 100 * configInt(ctx, "stream-node-max-entries", entry.DEFAULT_STREAM_NODE_MAX_ENTRIES)
+	}
+	return trim, i, nil
+}
+
+// effectiveTrimArgs returns the exact trim that leaves a stream the way an
+// approximate trim left s, so replicas remove exactly the same entries. An
+// ACKED trim may have kept entries below a MINID, so it is always sent as a
+// MAXLEN.
+func effectiveTrimArgs(trim entry.StreamTrim, s *entry.Stream) []string {
+	var args []string
+	if firstID := s.FirstID(); trim.Strategy == entry.STREAM_TRIM_MINID && trim.Policy != entry.STREAM_REF_ACKED && firstID != nil {
+		args = []string{"MINID", "=", firstID.String()}
+	} else {
+		args = []string{"MAXLEN", "=", strconv.Itoa(s.Len())}
+	}
+	if trim.Policy != entry.STREAM_REF_KEEPREF {
+		args = append(args, trim.Policy.String())
+	}
+	return args
+}
+
+// parseStreamRefPolicy parses KEEPREF, DELREF or ACKED.
+func parseStreamRefPolicy(arg string) (entry.StreamRefPolicy, bool) {
+	switch strings.ToLower(arg) {
+	case "keepref":
+		return entry.STREAM_REF_KEEPREF, true
+	case "delref":
+		return entry.STREAM_REF_DELREF, true
+	case "acked":
+		return entry.STREAM_REF_ACKED, true
+	}
+	return entry.STREAM_REF_KEEPREF, false
+}
+
+// parseStreamDeleteArgs parses "[KEEPREF | DELREF | ACKED] IDS numids id
+// [id ...]", which XDELEX and XACKDEL end with.
+func parseStreamDeleteArgs(args []string) (entry.StreamRefPolicy, []*entry.StreamID, error) {
+	policy := entry.STREAM_REF_KEEPREF
+	if len(args) > 0 {
+		if p, ok := parseStreamRefPolicy(args[0]); ok {
+			policy = p
+			args = args[1:]
+		}
+	}
+	if len(args) < 2 || strings.ToLower(args[0]) != "ids" {
+		return policy, nil, errors.New(syntaxError)
+	}
+	numIDs, err := strconv.Atoi(args[1])
+	if err != nil || numIDs <= 0 {
+		return policy, nil, errors.New("ERR Number of IDs must be a positive integer")
+	}
+	if numIDs != len(args)-2 {
+		return policy, nil, errors.New("ERR The `numids` parameter must match the number of arguments")
+	}
+	ids, err := parseStreamIDs(args[2:])
+	return policy, ids, err
+}
+
+// countDeleted counts the entries DeleteWithPolicy results say were deleted.
+func countDeleted(results []int) int {
+	deleted := 0
+	for _, r := range results {
+		if r == 1 {
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// streamDeleteResultsToResp encodes the per-ID results of
+// Stream.DeleteWithPolicy.
+func streamDeleteResultsToResp(results []int) []byte {
+	ret := protocol.ToArrayHeader(len(results))
+	for _, r := range results {
+		ret = append(ret, protocol.ToRespInt(r)...)
+	}
+	return ret
+}
+
+func parseStreamIDs(args []string) ([]*entry.StreamID, error) {
+	ids := make([]*entry.StreamID, len(args))
+	for i, arg := range args {
+		id, err := entry.ParseStreamID(arg)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+const xgroupKeyMissingError string = "ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically."
+
+// lookupStreamGroup returns the stream at key and its group called name,
+// either of which is nil if it does not exist. The stream is looked up for
+// writing, as nearly every command on a group changes it.
+func lookupStreamGroup(ctx *event.Context, key string, name string) (*entry.Stream, *entry.StreamGroup, error) {
+	s, err := lookupStreamWrite(ctx, key)
+	if err != nil || s == nil {
+		return nil, nil, err
+	}
+	return s, s.Group(name), nil
+}
+
+func noGroupError(key string, group string) error {
+	return fmt.Errorf("NOGROUP No such key '%s' or consumer group '%s'", key, group)
+}
+
+// parseGroupID parses the ID a group is set to, where "$" is the last ID of
+// the stream.
+func parseGroupID(s *entry.Stream, arg string) (*entry.StreamID, error) {
+	if arg == "$" {
+		return s.LastID(), nil
+	}
+	return entry.ParseStreamID(arg)
+}
+
+func parseEntriesRead(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, errors.New(notIntegerError)
+	}
+	if n < entry.STREAM_ENTRIES_READ_UNKNOWN {
+		return 0, errors.New("ERR value for ENTRIESREAD must be positive or -1")
+	}
+	return n, nil
+}
+
+// mstime returns the current unix time in milliseconds, the clock pending
+// entries and consumers are timed with.
+func mstime() int64 {
+	return time.Now().UnixMilli()
+}
+
+// xclaimEffect is the XCLAIM that gives a replica the same pending entry p.
+func xclaimEffect(key string, g *entry.StreamGroup, p *entry.StreamPendingEntry) []string {
+	return []string{
+		"XCLAIM", key, g.Name, p.Consumer.Name, "0", p.ID.String(),
+		"TIME", strconv.FormatInt(p.DeliveryTime, 10),
+		"RETRYCOUNT", strconv.Itoa(p.DeliveryCount),
+		"FORCE", "JUSTID", "LASTID", g.LastID().String(),
+	}
+}
+
+// xgroupSetIDEffect is the XGROUP SETID that moves a replica's copy of g to
+// where g is.
+func xgroupSetIDEffect(key string, g *entry.StreamGroup) []string {
+	return []string{"XGROUP", "SETID", key, g.Name, g.LastID().String(), "ENTRIESREAD", strconv.Itoa(g.EntriesRead())}
+}
+
+// xackEffect is the XACK that drops the pending entries for ids on a
+// replica.
+func xackEffect(key string, g *entry.StreamGroup, ids []*entry.StreamID) []string {
+	effect := []string{"XACK", key, g.Name}
+	for _, id := range ids {
+		effect = append(effect, id.String())
+	}
+	return effect
+}
+
+func xgroupCreateConsumerEffect(key string, g *entry.StreamGroup, consumer string) []string {
+	return []string{"XGROUP", "CREATECONSUMER", key, g.Name, consumer}
+}
+
+func streamIDsToResp(ids []*entry.StreamID) []byte {
+	ret := protocol.ToArrayHeader(len(ids))
+	for _, id := range ids {
+		ret = append(ret, protocol.ToBulkString(id.String())...)
+	}
+	return ret
+}
@@ -0,0 +1,207 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+const (
+	// STREAM_RETENTION_KEYS_PER_CYCLE is how many streams a cycle trims at
+	// most.
+	STREAM_RETENTION_KEYS_PER_CYCLE int = 20
+	// STREAM_RETENTION_PASS_INTERVAL is how often the keyspace is scanned
+	// for streams to trim.
+	STREAM_RETENTION_PASS_INTERVAL time.Duration = time.Second
+)
+
+// streamRetentionRule keeps the streams at keys matching pattern either to
+// entries younger than maxAge milliseconds, going by the time in their IDs,
+// or to maxLen entries. An approximate maxLen lets a stream run over by up to
+// a node's worth of entries before it is trimmed.
+type streamRetentionRule struct {
+	pattern  string
+	strategy entry.StreamTrimStrategy
+	maxAge   int64
+	maxLen   int
+	approx   bool
+}
+
+// parseStreamRetention parses the stream-retention config, a list of
+// "pattern maxage age" and "pattern maxlen [~]count" rules where the first
+// rule matching a key applies. An age is a number of seconds, or a number
+// followed by ms, s, m, h or d.
+func parseStreamRetention(value string) ([]streamRetentionRule, error) {
+	args := strings.Fields(value)
+	if len(args)%3 != 0 {
+		return nil, errors.New("Invalid stream-retention, expected pattern maxage|maxlen threshold triples")
+	}
+	rules := []streamRetentionRule{}
+	for i := 0; i < len(args); i += 3 {
+		rule := streamRetentionRule{pattern: args[i]}
+		threshold := args[i+2]
+		switch strings.ToLower(args[i+1]) {
+		case "maxage":
+			maxAge, err := parseRetentionAge(threshold)
+			if err != nil {
+				return nil, err
+			}
+			rule.strategy = entry.STREAM_TRIM_MINID
+			rule.maxAge = maxAge
+		case "maxlen":
+			rule.approx = strings.HasPrefix(threshold, "~")
+			maxLen, err := strconv.Atoi(strings.TrimLeft(threshold, "~="))
+			if err != nil || maxLen < 0 {
+				return nil, fmt.Errorf("Invalid stream-retention maxlen '%s'", threshold)
+			}
+			rule.strategy = entry.STREAM_TRIM_MAXLEN
+			rule.maxLen = maxLen
+		default:
+			return nil, fmt.Errorf("Invalid stream-retention policy '%s', expected maxage or maxlen", args[i+1])
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+var retentionAgeUnits = []struct {
+	suffix string
+	ms     int64
+}{
+	{"ms", 1},
+	{"s", 1000},
+	{"m", 60 * 1000},
+	{"h", 60 * 60 * 1000},
+	{"d", 24 * 60 * 60 * 1000},
+}
+
+// parseRetentionAge parses an age into milliseconds.
+func parseRetentionAge(age string) (int64, error) {
+	number, unit := age, int64(1000)
+	for _, u := range retentionAgeUnits {
+		if strings.HasSuffix(age, u.suffix) {
+			number, unit = strings.TrimSuffix(age, u.suffix), u.ms
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("Invalid stream-retention maxage '%s'", age)
+	}
+	return n * unit, nil
+}
+
+// retentionKey is a stream queued for trimming.
+type retentionKey struct {
+	db  int
+	key string
+}
+
+// ValidateStreamRetention checks a stream-retention config value.
+func ValidateStreamRetention(value string) error {
+	_, err := parseStreamRetention(value)
+	return err
+}
+
+// StreamRetention trims streams in the background as the stream-retention
+// config says. Each cycle trims a bounded number of streams by a bounded
+// number of entries, working through the streams found by the last scan of
+// the keyspace. A stream that still had entries to remove goes back at the
+// end of the queue. Its trims reach replicas as exact XTRIMs, and replicas
+// leave retention to their master.
+type StreamRetention struct {
+	config   string
+	rules    []streamRetentionRule
+	queue    []retentionKey
+	lastPass time.Time
+}
+
+func NewStreamRetention() *StreamRetention {
+	return &StreamRetention{}
+}
+
+// Cycle runs one round of trimming. It is called periodically on the event
+// loop.
+func (r *StreamRetention) Cycle(ctx *event.Context) {
+	if ctx.ReplicationInfo.Role != replication.ROLE_MASTER {
+		return
+	}
+	if config := ctx.ConfigParams["stream-retention"]; config != r.config {
+		rules, err := parseStreamRetention(config)
+		if err != nil {
+			rules = nil
+		}
+		r.config, r.rules, r.queue = config, rules, nil
+	}
+	if len(r.rules) == 0 {
+		return
+	}
+	if len(r.queue) == 0 && time.Since(r.lastPass) >= STREAM_RETENTION_PASS_INTERVAL {
+		r.scan(ctx)
+	}
+	for visited := 0; len(r.queue) > 0 && visited < STREAM_RETENTION_KEYS_PER_CYCLE; visited++ {
+		k := r.queue[0]
+		r.queue = r.queue[1:]
+		dbCtx := *ctx
+		dbCtx.CurrentDatabase = k.db
+		if r.enforce(&dbCtx, k.key) {
+			r.queue = append(r.queue, k)
+		}
+	}
+}
+
+// scan queues every stream some rule applies to, in every database.
+func (r *StreamRetention) scan(ctx *event.Context) {
+	r.lastPass = time.Now()
+	for _, db := range slices.Sorted(maps.Keys(ctx.Store)) {
+		for key, e := range ctx.Store[db] {
+			if _, ok := e.(*entry.Stream); ok && r.rule(key) != nil {
+				r.queue = append(r.queue, retentionKey{db: db, key: key})
+			}
+		}
+	}
+}
+
+func (r *StreamRetention) rule(key string) *streamRetentionRule {
+	for i := range r.rules {
+		if utils.GlobMatch(r.rules[i].pattern, key) {
+			return &r.rules[i]
+		}
+	}
+	return nil
+}
+
+// enforce trims the stream at key in the current database by as many
+// entries as an approximate trim would, and reports whether it may have more
+// to remove.
+func (r *StreamRetention) enforce(ctx *event.Context, key string) bool {
+	s, err := lookupStreamWrite(ctx, key)
+	rule := r.rule(key)
+	if err != nil || s == nil || rule == nil {
+		return false
+	}
+	nodeMaxEntries := configInt(ctx, "stream-node-max-entries", entry.DEFAULT_STREAM_NODE_MAX_ENTRIES)
+	trim := entry.StreamTrim{Strategy: rule.strategy, Limit: 100 * nodeMaxEntries}
+	if rule.strategy == entry.STREAM_TRIM_MINID {
+		trim.MinID = entry.NewStreamID(int(max(mstime()-rule.maxAge, 0)), 0)
+	} else {
+		if rule.approx && s.Len()-rule.maxLen < nodeMaxEntries {
+			return false
+		}
+		trim.MaxLen = rule.maxLen
+	}
+	removed := s.Trim(trim)
+	if removed > 0 {
+		propagateEffect(ctx, removed, append([]string{"XTRIM", key}, effectiveTrimArgs(trim, s)...))
+	}
+	return removed == trim.Limit
+}
@@ -0,0 +1,139 @@
+package command
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+)
+
+func TestParseStreamRetention(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []streamRetentionRule
+		valid    bool
+	}{
+		{"", []streamRetentionRule{}, true},
+		{"logs:* maxage 1h", []streamRetentionRule{{pattern: "logs:*", strategy: entry.STREAM_TRIM_MINID, maxAge: 3600000}}, true},
+		{"a MAXLEN ~100 b maxlen =5 * maxage 30", []streamRetentionRule{
+			{pattern: "a", strategy: entry.STREAM_TRIM_MAXLEN, maxLen: 100, approx: true},
+			{pattern: "b", strategy: entry.STREAM_TRIM_MAXLEN, maxLen: 5},
+			{pattern: "*", strategy: entry.STREAM_TRIM_MINID, maxAge: 30000},
+		}, true},
+		{"a maxlen", nil, false},
+		{"a maxlen -1", nil, false},
+		{"a maxlen ~x", nil, false},
+		{"a maxage 0", nil, false},
+		{"a keep 5", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseStreamRetention(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("%q: expected valid=%v; got %v", tt.value, tt.valid, err)
+			continue
+		}
+		if tt.valid && !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%q: expected %+v; got %+v", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestParseRetentionAge(t *testing.T) {
+	tests := []struct {
+		age      string
+		expected int64
+		valid    bool
+	}{
+		{"10", 10000, true},
+		{"250ms", 250, true},
+		{"10s", 10000, true},
+		{"2m", 120000, true},
+		{"1h", 3600000, true},
+		{"7d", 7 * 24 * 3600000, true},
+		{"0", 0, false},
+		{"-1s", 0, false},
+		{"1w", 0, false},
+		{"ms", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRetentionAge(tt.age)
+		if (err == nil) != tt.valid || got != tt.expected {
+			t.Errorf("%q: expected %d, valid=%v; got %d, %v", tt.age, tt.expected, tt.valid, got, err)
+		}
+	}
+}
+
+// TestStreamRetentionEnforce checks that streams in every database are
+// trimmed at most 100 nodes' worth of entries at a time, and that each trim
+// reaches the append-only file as an exact XTRIM.
+func TestStreamRetentionEnforce(t *testing.T) {
+	ctx := newTestContext()
+	ctx.ReplicationInfo = replication.NewReplicationInfo("")
+	ctx.Saver = rdb.NewSaver()
+	ctx.AOF = aof.New()
+	opts := aof.Options{Enabled: true, Dir: t.TempDir(), Dirname: "appendonlydir", Filename: "appendonly.aof"}
+	if err := ctx.AOF.Open(opts, ctx.Store); err != nil {
+		t.Fatalf("Error opening the AOF: %s", err)
+	}
+	ctx.ConfigParams["stream-node-max-entries"] = "1"
+	ctx.ConfigParams["stream-retention"] = "big maxlen 0 old maxage 1h"
+	for i := range 250 {
+		run(t, ctx, "XADD", "big", fmt.Sprintf("%d-1", i+1), "f", "v")
+	}
+	ctx.CurrentDatabase = 1
+	run(t, ctx, "XADD", "old", "1-1", "f", "v")
+	run(t, ctx, "XADD", "old", "2-1", "f", "v")
+	run(t, ctx, "XADD", "old", "*", "f", "v")
+	ctx.CurrentDatabase = 0
+
+	r := NewStreamRetention()
+	rules, _ := parseStreamRetention(ctx.ConfigParams["stream-retention"])
+	r.rules = rules
+	if more := r.enforce(ctx, "big"); !more || run(t, ctx, "XLEN", "big") != ":150\r\n" {
+		t.Errorf("Expected the first trim to remove 100 entries and have more to remove")
+	}
+	r.Cycle(ctx)
+	if got := run(t, ctx, "XLEN", "big"); got != ":0\r\n" {
+		t.Errorf("Expected big to be trimmed to 0 entries; got %q", got)
+	}
+	ctx.CurrentDatabase = 1
+	if got := run(t, ctx, "XLEN", "old"); got != ":1\r\n" {
+		t.Errorf("Expected the entries of old over an hour old to be trimmed; got %q", got)
+	}
+	newest := ctx.Store[1]["old"].(*entry.Stream).FirstID().String()
+
+	ctx.AOF.Flush(aof.FSYNC_ALWAYS)
+	got := []string{}
+	err := aof.Load(opts, map[int]map[string]entry.Entry{}, func(db int, args []string) error {
+		got = append(got, fmt.Sprintf("%d %s", db, strings.Join(args, " ")))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error loading the AOF: %s", err)
+	}
+	expected := []string{"0 XTRIM big MAXLEN = 150", "0 XTRIM big MAXLEN = 50", "1 XTRIM old MINID = " + newest, "0 XTRIM big MAXLEN = 0"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected %q to be propagated; got %q", expected, got)
+	}
+}
+
+func TestStreamRetentionApproximate(t *testing.T) {
+	ctx := newTestContext()
+	r := NewStreamRetention()
+	r.rules, _ = parseStreamRetention("s maxlen ~100")
+	for i := range 199 {
+		run(t, ctx, "XADD", "s", fmt.Sprintf("%d-1", i+1), "f", "v")
+	}
+	// Too few entries over the maxlen to fill a node are kept.
+	if r.enforce(ctx, "s") || run(t, ctx, "XLEN", "s") != ":199\r\n" {
+		t.Errorf("Expected 99 entries over an approximate maxlen of 100 to be kept")
+	}
+}
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Sunion struct{}
+
+func (s *Sunion) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 1 {
+		writeChan <- wrongNumberOfArgs("sunion")
+		return
+	}
+	sets, err := lookupSets(ctx, args[0:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	writeChan <- protocol.ToArrayBulkStrings(entry.SetUnion(sets))
+}
+
+func (s *Sunion) CanPropogateCommand(args []string) bool {
+	return false
+}
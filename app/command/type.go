@@ -12,7 +12,7 @@ func (t *Type) Handle(args []string, ctx *event.Context, writeChan chan []byte)
 		writeChan <- []byte("Usage: TYPE <key>")
 		return
 	}
-	if val, ok := ctx.Store[ctx.CurrentDatabase][args[0]]; ok {
+	if val, ok := lookupKey(ctx, args[0]); ok {
 		writeChan <- []byte(protocol.ToSimpleString(val.Type()))
 		return
 	}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Xack struct{}
+
+func (x *Xack) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 3 {
+		writeChan <- wrongNumberOfArgs("xack")
+		return
+	}
+	ctx.RewritePropagation()
+	ids, err := parseStreamIDs(args[2:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	_, g, err := lookupStreamGroup(ctx, args[0], args[1])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if g == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	acked := g.Ack(ids)
+	ctx.AddDirty(acked)
+	if acked > 0 {
+		ctx.RewritePropagation(append([]string{"XACK"}, args...))
+	}
+	writeChan <- protocol.ToRespInt(acked)
+}
+
+func (x *Xack) CanPropogateCommand(args []string) bool {
+	return true
+}
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Xackdel serves XACKDEL, which acknowledges entries in one group and then
+// deletes them from the stream as its reference policy allows.
+type Xackdel struct{}
+
+func (x *Xackdel) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 5 {
+		writeChan <- wrongNumberOfArgs("xackdel")
+		return
+	}
+	ctx.RewritePropagation()
+	key, group := args[0], args[1]
+	policy, ids, err := parseStreamDeleteArgs(args[2:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	s, g, err := lookupStreamGroup(ctx, key, group)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if g == nil {
+		writeChan <- protocol.ToError(noGroupError(key, group).Error())
+		return
+	}
+	acked := g.Ack(ids)
+	results := s.DeleteWithPolicy(ids, policy)
+	ctx.AddDirty(acked + countDeleted(results))
+	ctx.RewritePropagation(append([]string{"XACKDEL"}, args...))
+	writeChan <- streamDeleteResultsToResp(results)
+}
+
+func (x *Xackdel) CanPropogateCommand(args []string) bool {
+	return true
+}
@@ -1,6 +1,8 @@
 package command
 
 import (
+	"strings"
+
 	"github.com/codecrafters-io/redis-starter-go/app/entry"
 	"github.com/codecrafters-io/redis-starter-go/app/event"
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
@@ -8,32 +10,87 @@ import (
 
 type Xadd struct{}
 
-const xaddUsageStr string = "XADD key <* | id> field value [field value ...]"
+const xaddUsageStr string = "XADD key [NOMKSTREAM] [KEEPREF | DELREF | ACKED] [<MAXLEN | MINID> [= | ~] threshold [LIMIT count]] <* | id> field value [field value ...]"
 
+// Handle propagates the entry with the ID it was actually given, and any trim
+// as the exact trim that took place, so that replicas end up with identical
+// entries.
 func (x *Xadd) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
 	if len(args) < 4 {
 		writeChan <- []byte(xaddUsageStr)
 		return
 	}
-	key, id, field, value := args[0], args[1], args[2], args[3]
-	if _, ok := ctx.Store[ctx.CurrentDatabase]; !ok {
-		ctx.Store[ctx.CurrentDatabase] = make(map[string]entry.Entry)
+	ctx.RewritePropagation()
+	key := args[0]
+	noMkStream := false
+	policy := entry.STREAM_REF_KEEPREF
+	trim := entry.StreamTrim{Strategy: entry.STREAM_TRIM_NONE}
+	i := 1
+parseOptions:
+	for i < len(args) {
+		switch strings.ToLower(args[i]) {
+		case "nomkstream":
+			noMkStream = true
+			i++
+		case "keepref", "delref", "acked":
+			policy, _ = parseStreamRefPolicy(args[i])
+			i++
+		case "maxlen", "minid":
+			var err error
+			trim, i, err = parseStreamTrim(ctx, args, i)
+			if err != nil {
+				writeChan <- protocol.ToError(err.Error())
+				return
+			}
+		default:
+			break parseOptions
+		}
+	}
+	if i >= len(args) {
+		writeChan <- protocol.ToError(syntaxError)
+		return
+	}
+	trim.Policy = policy
+	id, fieldValues := args[i], args[i+1:]
+	if len(fieldValues) == 0 || len(fieldValues)%2 != 0 {
+		writeChan <- wrongNumberOfArgs("xadd")
+		return
 	}
-	e, ok := ctx.Store[ctx.CurrentDatabase][key]
+	e, ok := lookupKeyWrite(ctx, key)
 	if !ok {
-		e = entry.NewStream()
-		ctx.Store[ctx.CurrentDatabase][key] = e
+		if noMkStream {
+			writeChan <- protocol.NullBulkString()
+			return
+		}
+		e = newStream(ctx)
 	}
 	stream, ok := e.(*entry.Stream)
 	if !ok {
 		writeChan <- protocol.ToError("WRONGTYPE entry at key is not a stream")
 		return
 	}
-	streamID, err := stream.Add(id, field, value)
+	fields := make([]*entry.KeyValue, 0, len(fieldValues)/2)
+	for j := 0; j < len(fieldValues); j += 2 {
+		fields = append(fields, &entry.KeyValue{Key: fieldValues[j], Value: fieldValues[j+1]})
+	}
+	streamID, err := stream.Add(id, fields)
 	if err != nil {
 		writeChan <- protocol.ToError(err.Error())
 		return
 	}
+	database(ctx)[key] = stream
+	ctx.AddDirty(1 + stream.Trim(trim))
+	signalKeyAsReady(ctx, key)
+
+	rewrite := []string{"XADD", key}
+	if noMkStream {
+		rewrite = append(rewrite, "NOMKSTREAM")
+	}
+	if trim.Strategy != entry.STREAM_TRIM_NONE {
+		rewrite = append(rewrite, effectiveTrimArgs(trim, stream)...)
+	}
+	rewrite = append(rewrite, streamID.String())
+	ctx.RewritePropagation(append(rewrite, fieldValues...))
 	writeChan <- protocol.ToBulkString(streamID.String())
 }
 
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestXaddAutoIDAfterTopID(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    [][]string
+		expected string
+	}{
+		{"after XSETID into the future", [][]string{{"XADD", "s", "1-1", "f", "v"}, {"XSETID", "s", "99999999999999-0"}}, "99999999999999-1"},
+		{"with the clock behind the last ID", [][]string{{"XADD", "s", "99999999999999-5", "f", "v"}}, "99999999999999-6"},
+		{"once the sequence is used up", [][]string{{"XADD", "s", "99999999999999-9223372036854775807", "f", "v"}}, "100000000000000-0"},
+	}
+
+	for _, tt := range tests {
+		ctx := newTestContext()
+		for _, cmd := range tt.setup {
+			run(t, ctx, cmd[0], cmd[1:]...)
+		}
+		if got := run(t, ctx, "XADD", "s", "*", "f", "v"); got != fmt.Sprintf("$%d\r\n%s\r\n", len(tt.expected), tt.expected) {
+			t.Errorf("%s: expected %s; got %q", tt.name, tt.expected, got)
+		}
+		if got := run(t, ctx, "XLEN", "s"); got != ":2\r\n" {
+			t.Errorf("%s: expected the entry to be stored; got %q", tt.name, got)
+		}
+	}
+
+	ctx := newTestContext()
+	now := time.Now().UnixMilli()
+	run(t, ctx, "XADD", "s", "1-1", "f", "v")
+	got := run(t, ctx, "XADD", "s", "*", "f", "v")
+	var ms, seq int64
+	if _, err := fmt.Sscanf(strings.Split(got, "\r\n")[1], "%d-%d", &ms, &seq); err != nil || ms < now || seq != 0 {
+		t.Errorf("Expected an ID of the current time; got %q", got)
+	}
+
+	ctx = newTestContext()
+	run(t, ctx, "XADD", "s", "9223372036854775807-9223372036854775806", "f", "v")
+	if got := run(t, ctx, "XADD", "s", "*", "f", "v"); got != "$39\r\n9223372036854775807-9223372036854775807\r\n" {
+		t.Errorf("Expected the last possible ID; got %q", got)
+	}
+	if got := run(t, ctx, "XADD", "s", "*", "f", "v"); got != "-ERR The stream has exhausted the last possible ID, unable to add more items\r\n" {
+		t.Errorf("Expected the IDs to be exhausted; got %q", got)
+	}
+	if got := run(t, ctx, "XADD", "s", "9223372036854775807-*", "f", "v"); got != "-ERR The ID specified in XADD is equal or smaller than the target stream top item\r\n" {
+		t.Errorf("Expected no sequence after the last one; got %q", got)
+	}
+}
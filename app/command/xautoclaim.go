@@ -0,0 +1,95 @@
+package command
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+const DEFAULT_XAUTOCLAIM_COUNT int = 100
+
+// Xautoclaim serves XAUTOCLAIM, which replicas see the same way as XCLAIM.
+type Xautoclaim struct{}
+
+func (x *Xautoclaim) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 5 {
+		writeChan <- wrongNumberOfArgs("xautoclaim")
+		return
+	}
+	ctx.RewritePropagation()
+	key, group, consumer := args[0], args[1], args[2]
+	s, g, err := lookupStreamGroup(ctx, key, group)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if g == nil {
+		writeChan <- protocol.ToError(noGroupError(key, group).Error())
+		return
+	}
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		writeChan <- protocol.ToError("ERR Invalid min-idle-time argument for XAUTOCLAIM")
+		return
+	}
+	start, _, err := entry.ParseStreamInterval(args[4], "+")
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	count := DEFAULT_XAUTOCLAIM_COUNT
+	justID := false
+	for i := 5; i < len(args); i++ {
+		switch opt := strings.ToLower(args[i]); {
+		case opt == "justid":
+			justID = true
+		case opt == "count" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 || n > math.MaxInt/10 {
+				writeChan <- protocol.ToError("ERR COUNT must be > 0")
+				return
+			}
+			count = n
+			i++
+		default:
+			writeChan <- protocol.ToError(syntaxError)
+			return
+		}
+	}
+
+	now := mstime()
+	effects := [][]string{}
+	c, created := g.CreateConsumer(consumer, now)
+	if created {
+		effects = append(effects, xgroupCreateConsumerEffect(key, g, consumer))
+	}
+	claim := entry.StreamClaim{MinIdle: max(minIdle, 0), DeliveryTime: now, RetryCount: -1, JustID: justID}
+	claimed, deleted, next := s.AutoClaim(g, c, start, count, claim, now)
+	claimedIDs := make([]*entry.StreamID, len(claimed))
+	for j, p := range claimed {
+		claimedIDs[j] = p.ID
+		effects = append(effects, xclaimEffect(key, g, p))
+	}
+	if len(deleted) > 0 {
+		effects = append(effects, xackEffect(key, g, deleted))
+	}
+	ctx.AddDirty(len(effects))
+	ctx.RewritePropagation(effects...)
+
+	reply := protocol.ToArrayHeader(3)
+	reply = append(reply, protocol.ToBulkString(next.String())...)
+	if justID {
+		reply = append(reply, streamIDsToResp(claimedIDs)...)
+	} else {
+		reply = append(reply, s.Entries(claimedIDs).Encoded()...)
+	}
+	writeChan <- append(reply, streamIDsToResp(deleted)...)
+}
+
+func (x *Xautoclaim) CanPropogateCommand(args []string) bool {
+	return true
+}
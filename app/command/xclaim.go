@@ -0,0 +1,130 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Xclaim serves XCLAIM. Every entry it claims reaches replicas as its own
+// XCLAIM carrying the resulting delivery time and count, so they end up with
+// the same pending entries whatever their clocks say. Pending entries of
+// deleted stream entries are dropped, which replicas see as an XACK.
+type Xclaim struct{}
+
+func (x *Xclaim) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 5 {
+		writeChan <- wrongNumberOfArgs("xclaim")
+		return
+	}
+	ctx.RewritePropagation()
+	key, group, consumer := args[0], args[1], args[2]
+	s, g, err := lookupStreamGroup(ctx, key, group)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if g == nil {
+		writeChan <- protocol.ToError(noGroupError(key, group).Error())
+		return
+	}
+	minIdle, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil {
+		writeChan <- protocol.ToError("ERR Invalid min-idle-time argument for XCLAIM")
+		return
+	}
+	i := 4
+	ids := []*entry.StreamID{}
+	for ; i < len(args); i++ {
+		id, err := entry.ParseStreamID(args[i])
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+	now := mstime()
+	claim := entry.StreamClaim{MinIdle: max(minIdle, 0), DeliveryTime: now, RetryCount: -1}
+	for ; i < len(args); i++ {
+		opt := strings.ToLower(args[i])
+		hasValue := i+1 < len(args)
+		switch {
+		case opt == "force":
+			claim.Force = true
+		case opt == "justid":
+			claim.JustID = true
+		case opt == "idle" && hasValue:
+			idle, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				writeChan <- protocol.ToError("ERR Invalid IDLE option argument for XCLAIM")
+				return
+			}
+			claim.DeliveryTime = now - idle
+			i++
+		case opt == "time" && hasValue:
+			t, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				writeChan <- protocol.ToError("ERR Invalid TIME option argument for XCLAIM")
+				return
+			}
+			claim.DeliveryTime = t
+			i++
+		case opt == "retrycount" && hasValue:
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 0 {
+				writeChan <- protocol.ToError("ERR Invalid RETRYCOUNT option argument for XCLAIM")
+				return
+			}
+			claim.RetryCount = n
+			i++
+		case opt == "lastid" && hasValue:
+			id, err := entry.ParseStreamID(args[i+1])
+			if err != nil {
+				writeChan <- protocol.ToError(err.Error())
+				return
+			}
+			claim.LastID = id
+			i++
+		default:
+			writeChan <- protocol.ToError(fmt.Sprintf("ERR Unrecognized XCLAIM option '%s'", args[i]))
+			return
+		}
+	}
+	if claim.DeliveryTime < 0 || claim.DeliveryTime > now {
+		claim.DeliveryTime = now
+	}
+
+	effects := [][]string{}
+	c, created := g.CreateConsumer(consumer, now)
+	if created {
+		effects = append(effects, xgroupCreateConsumerEffect(key, g, consumer))
+	}
+	lastID := g.LastID()
+	claimed, deleted := s.Claim(g, c, ids, claim, now)
+	claimedIDs := make([]*entry.StreamID, len(claimed))
+	for j, p := range claimed {
+		claimedIDs[j] = p.ID
+		effects = append(effects, xclaimEffect(key, g, p))
+	}
+	if len(deleted) > 0 {
+		effects = append(effects, xackEffect(key, g, deleted))
+	}
+	if g.LastID() != lastID {
+		effects = append(effects, xgroupSetIDEffect(key, g))
+	}
+	ctx.AddDirty(len(effects))
+	ctx.RewritePropagation(effects...)
+
+	if claim.JustID {
+		writeChan <- streamIDsToResp(claimedIDs)
+		return
+	}
+	writeChan <- s.Entries(claimedIDs).Encoded()
+}
+
+func (x *Xclaim) CanPropogateCommand(args []string) bool {
+	return true
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Xdel struct{}
+
+func (x *Xdel) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("xdel")
+		return
+	}
+	ids, err := parseStreamIDs(args[1:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	stream, err := lookupStreamWrite(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if stream == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	deleted := stream.Delete(ids)
+	ctx.AddDirty(deleted)
+	writeChan <- protocol.ToRespInt(deleted)
+}
+
+func (x *Xdel) CanPropogateCommand(args []string) bool {
+	return true
+}
@@ -0,0 +1,44 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Xdelex serves XDELEX, an XDEL that takes the consumer groups into account
+// as its reference policy says.
+type Xdelex struct{}
+
+func (x *Xdelex) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 4 {
+		writeChan <- wrongNumberOfArgs("xdelex")
+		return
+	}
+	ctx.RewritePropagation()
+	policy, ids, err := parseStreamDeleteArgs(args[1:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	stream, err := lookupStreamWrite(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	results := make([]int, len(ids))
+	if stream == nil {
+		for i := range results {
+			results[i] = -1
+		}
+		writeChan <- streamDeleteResultsToResp(results)
+		return
+	}
+	results = stream.DeleteWithPolicy(ids, policy)
+	ctx.AddDirty(countDeleted(results))
+	ctx.RewritePropagation(append([]string{"XDELEX"}, args...))
+	writeChan <- streamDeleteResultsToResp(results)
+}
+
+func (x *Xdelex) CanPropogateCommand(args []string) bool {
+	return true
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+)
+
+// TestXdelex covers XDELEX and XACKDEL under each reference policy, on a
+// stream of four entries where group g1 has read them all and acknowledged
+// 1-1, and group g2 has read 1-1 and 2-1 but not 3-1 and 4-1.
+func TestXdelex(t *testing.T) {
+	tests := []struct {
+		cmd        []string
+		expected   string
+		propagated []string
+		// length is what XLEN s returns afterwards.
+		length string
+		// pending is how many entries g1 and g2 have pending afterwards.
+		pending [2]int
+	}{
+		// KEEPREF, the default, deletes the entries and leaves them pending.
+		{[]string{"XDELEX", "s", "IDS", "2", "1-1", "9-1"}, "*2\r\n:1\r\n:-1\r\n", []string{"XDELEX s IDS 2 1-1 9-1"}, ":3\r\n", [2]int{3, 2}},
+		{[]string{"XDELEX", "s", "keepref", "ids", "1", "2-1"}, "*1\r\n:1\r\n", []string{"XDELEX s keepref ids 1 2-1"}, ":3\r\n", [2]int{3, 2}},
+		// DELREF drops the entries from every group too.
+		{[]string{"XDELEX", "s", "DELREF", "IDS", "2", "1-1", "2-1"}, "*2\r\n:1\r\n:1\r\n", []string{"XDELEX s DELREF IDS 2 1-1 2-1"}, ":2\r\n", [2]int{2, 0}},
+		// ACKED keeps entries pending in a group or that a group is yet to
+		// read.
+		{[]string{"XDELEX", "s", "ACKED", "IDS", "3", "1-1", "3-1", "9-1"}, "*3\r\n:2\r\n:2\r\n:-1\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XDELEX", "missing", "IDS", "1", "1-1"}, "*1\r\n:-1\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XACKDEL", "s", "g2", "IDS", "1", "1-1"}, "*1\r\n:1\r\n", []string{"XACKDEL s g2 IDS 1 1-1"}, ":3\r\n", [2]int{3, 1}},
+		{[]string{"XACKDEL", "s", "g1", "DELREF", "IDS", "1", "2-1"}, "*1\r\n:1\r\n", []string{"XACKDEL s g1 DELREF IDS 1 2-1"}, ":3\r\n", [2]int{2, 1}},
+		// XACKDEL acknowledges before deleting, so an entry only g2 had
+		// pending goes while one g1 still has pending stays.
+		{[]string{"XACKDEL", "s", "g2", "ACKED", "IDS", "2", "1-1", "2-1"}, "*2\r\n:1\r\n:2\r\n", []string{"XACKDEL s g2 ACKED IDS 2 1-1 2-1"}, ":3\r\n", [2]int{3, 0}},
+		// An entry kept is still propagated when it was acknowledged.
+		{[]string{"XACKDEL", "s", "g1", "ACKED", "IDS", "1", "4-1"}, "*1\r\n:2\r\n", []string{"XACKDEL s g1 ACKED IDS 1 4-1"}, ":4\r\n", [2]int{2, 2}},
+		{[]string{"XACKDEL", "s", "g1", "ACKED", "IDS", "1", "1-1"}, "*1\r\n:2\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XDELEX", "s", "IDS", "0", "1-1"}, "-ERR Number of IDs must be a positive integer\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XDELEX", "s", "IDS", "2", "1-1"}, "-ERR The `numids` parameter must match the number of arguments\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XDELEX", "s", "ALL", "IDS", "1", "1-1"}, "-" + syntaxError + "\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XDELEX", "s", "IDS", "1", "x"}, "-ERR Invalid stream ID specified as stream command argument\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XDELEX", "str", "IDS", "1", "1-1"}, "-" + wrongTypeError + "\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XDELEX", "s", "IDS", "1"}, "-ERR wrong number of arguments for 'xdelex' command\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XACKDEL", "s", "nope", "IDS", "1", "1-1"}, "-NOGROUP No such key 's' or consumer group 'nope'\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+		{[]string{"XACKDEL", "s", "g1", "IDS", "1"}, "-ERR wrong number of arguments for 'xackdel' command\r\n", []string{}, ":4\r\n", [2]int{3, 2}},
+	}
+
+	for _, tt := range tests {
+		ctx, propagated := newPropagationTestContext(t)
+		for _, id := range []string{"1-1", "2-1", "3-1", "4-1"} {
+			run(t, ctx, "XADD", "s", id, "f", "v")
+		}
+		run(t, ctx, "XGROUP", "CREATE", "s", "g1", "0")
+		run(t, ctx, "XGROUP", "CREATE", "s", "g2", "0")
+		run(t, ctx, "XREADGROUP", "GROUP", "g1", "alice", "STREAMS", "s", ">")
+		run(t, ctx, "XACK", "s", "g1", "1-1")
+		run(t, ctx, "XREADGROUP", "GROUP", "g2", "bob", "COUNT", "2", "STREAMS", "s", ">")
+		run(t, ctx, "SET", "str", "v")
+
+		if got := runPropagated(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+		if got := propagated(); !slices.Equal(got, tt.propagated) {
+			t.Errorf("%v: expected %q to be propagated; got %q", tt.cmd, tt.propagated, got)
+		}
+		if got := run(t, ctx, "XLEN", "s"); got != tt.length {
+			t.Errorf("%v: expected XLEN %q; got %q", tt.cmd, tt.length, got)
+		}
+		s := ctx.Store[0]["s"].(*entry.Stream)
+		if got := [2]int{s.Group("g1").PendingLen(), s.Group("g2").PendingLen()}; got != tt.pending {
+			t.Errorf("%v: expected %v entries pending in g1 and g2; got %v", tt.cmd, tt.pending, got)
+		}
+	}
+}
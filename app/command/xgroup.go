@@ -0,0 +1,172 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Xgroup struct{}
+
+func (x *Xgroup) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) == 0 {
+		writeChan <- wrongNumberOfArgs("xgroup")
+		return
+	}
+	ctx.RewritePropagation()
+	sub := strings.ToLower(args[0])
+	var minArgs, maxArgs int
+	switch sub {
+	case "create":
+		minArgs, maxArgs = 4, 7
+	case "setid":
+		minArgs, maxArgs = 4, 6
+	case "destroy":
+		minArgs, maxArgs = 3, 3
+	case "createconsumer", "delconsumer":
+		minArgs, maxArgs = 4, 4
+	default:
+		writeChan <- protocol.ToError(fmt.Sprintf("ERR unknown subcommand '%s'. Try XGROUP HELP.", args[0]))
+		return
+	}
+	if len(args) < minArgs || len(args) > maxArgs {
+		writeChan <- wrongNumberOfArgs("xgroup|" + sub)
+		return
+	}
+	key, name := args[1], args[2]
+	s, err := lookupStreamWrite(ctx, key)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if sub == "create" {
+		x.create(args, ctx, s, writeChan)
+		return
+	}
+	if s == nil {
+		writeChan <- protocol.ToError(xgroupKeyMissingError)
+		return
+	}
+	if sub == "destroy" {
+		destroyed := s.DestroyGroup(name)
+		if destroyed {
+			ctx.AddDirty(1)
+			// Readers blocked on the group are told it is gone.
+			signalKeyAsReady(ctx, key)
+			ctx.RewritePropagation(append([]string{"XGROUP"}, args...))
+			writeChan <- protocol.ToRespInt(1)
+			return
+		}
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	g := s.Group(name)
+	if g == nil {
+		writeChan <- protocol.ToError(fmt.Sprintf("NOGROUP No such consumer group '%s' for key name '%s'", name, key))
+		return
+	}
+	var reply []byte
+	switch sub {
+	case "setid":
+		id, err := parseGroupID(s, args[3])
+		if err != nil {
+			writeChan <- protocol.ToError(err.Error())
+			return
+		}
+		entriesRead := entry.STREAM_ENTRIES_READ_UNKNOWN
+		if len(args) > 4 {
+			if len(args) != 6 || strings.ToLower(args[4]) != "entriesread" {
+				writeChan <- protocol.ToError(syntaxError)
+				return
+			}
+			if entriesRead, err = parseEntriesRead(args[5]); err != nil {
+				writeChan <- protocol.ToError(err.Error())
+				return
+			}
+		}
+		g.SetID(id, entriesRead)
+		ctx.AddDirty(1)
+		reply = protocol.OkResp()
+	case "createconsumer":
+		_, created := g.CreateConsumer(args[3], mstime())
+		reply = protocol.ToRespInt(0)
+		if created {
+			ctx.AddDirty(1)
+			reply = protocol.ToRespInt(1)
+		}
+	case "delconsumer":
+		if g.Consumer(args[3]) != nil {
+			ctx.AddDirty(1)
+		}
+		reply = protocol.ToRespInt(g.DeleteConsumer(args[3]))
+	}
+	ctx.RewritePropagation(append([]string{"XGROUP"}, args...))
+	writeChan <- reply
+}
+
+// create handles XGROUP CREATE key group id|$ [MKSTREAM] [ENTRIESREAD n].
+func (x *Xgroup) create(args []string, ctx *event.Context, s *entry.Stream, writeChan chan []byte) {
+	key, name := args[1], args[2]
+	mkStream, entriesRead, err := parseXgroupCreateOptions(args[4:])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if s == nil && !mkStream {
+		writeChan <- protocol.ToError(xgroupKeyMissingError)
+		return
+	}
+	created := s == nil
+	if created {
+		s = newStream(ctx)
+	}
+	id, err := parseGroupID(s, args[3])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if !s.CreateGroup(name, id, entriesRead) {
+		writeChan <- protocol.ToError("BUSYGROUP Consumer Group name already exists")
+		return
+	}
+	if created && mkStream {
+		database(ctx)[key] = s
+	}
+	ctx.AddDirty(1)
+	ctx.RewritePropagation(append([]string{"XGROUP"}, args...))
+	writeChan <- protocol.OkResp()
+}
+
+// parseXgroupCreateOptions reads the MKSTREAM and ENTRIESREAD options of
+// XGROUP CREATE, which can come in any order.
+func parseXgroupCreateOptions(args []string) (bool, int, error) {
+	mkStream := false
+	entriesRead := entry.STREAM_ENTRIES_READ_UNKNOWN
+	for i := 0; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "mkstream":
+			mkStream = true
+		case "entriesread":
+			if i+1 >= len(args) {
+				return false, 0, errors.New(syntaxError)
+			}
+			n, err := parseEntriesRead(args[i+1])
+			if err != nil {
+				return false, 0, err
+			}
+			entriesRead = n
+			i++
+		default:
+			return false, 0, errors.New(syntaxError)
+		}
+	}
+	return mkStream, entriesRead, nil
+}
+
+func (x *Xgroup) CanPropogateCommand(args []string) bool {
+	return true
+}
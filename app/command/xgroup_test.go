@@ -0,0 +1,29 @@
+package command
+
+import "testing"
+
+func TestXgroupCreateOptions(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"create", "s", "g", "$", "MKSTREAM"}, "+OK\r\n"},
+		{[]string{"create", "s", "g", "$", "ENTRIESREAD", "0", "MKSTREAM"}, "+OK\r\n"},
+		{[]string{"create", "s", "g", "$", "mkstream", "entriesread", "0"}, "+OK\r\n"},
+		{[]string{"create", "s", "g", "$", "ENTRIESREAD", "0"}, "-" + xgroupKeyMissingError + "\r\n"},
+		{[]string{"create", "s", "g", "$"}, "-" + xgroupKeyMissingError + "\r\n"},
+		{[]string{"create", "s", "g", "$", "ENTRIESREAD"}, "-" + syntaxError + "\r\n"},
+		{[]string{"create", "s", "g", "$", "MKSTREAM", "NOPE"}, "-" + syntaxError + "\r\n"},
+	}
+
+	for _, tt := range tests {
+		ctx := newTestContext()
+		if got := run(t, ctx, "xgroup", tt.args...); got != tt.expected {
+			t.Errorf("XGROUP %v: expected %q; got %q", tt.args, tt.expected, got)
+		}
+		_, created := ctx.Store[0]["s"]
+		if expectCreated := tt.expected == "+OK\r\n"; created != expectCreated {
+			t.Errorf("XGROUP %v: expected the stream to be created: %v", tt.args, expectCreated)
+		}
+	}
+}
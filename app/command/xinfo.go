@@ -0,0 +1,247 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+const DEFAULT_XINFO_FULL_COUNT int = 10
+
+// Xinfo serves XINFO STREAM, GROUPS and CONSUMERS. Their replies are maps,
+// sent as flat arrays of alternating field names and values.
+type Xinfo struct{}
+
+func (x *Xinfo) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("xinfo")
+		return
+	}
+	sub := strings.ToLower(args[0])
+	if sub != "stream" && sub != "groups" && sub != "consumers" {
+		writeChan <- protocol.ToError(fmt.Sprintf("ERR unknown subcommand '%s'. Try XINFO HELP.", args[0]))
+		return
+	}
+	s, err := lookupStream(ctx, args[1])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if s == nil {
+		writeChan <- protocol.ToError("ERR no such key")
+		return
+	}
+	now := mstime()
+	switch sub {
+	case "stream":
+		full, count, err := parseXinfoStreamArgs(args[2:])
+		if err != nil {
+			writeChan <- protocol.ToError(err.Error())
+			return
+		}
+		if full {
+			writeChan <- xinfoStreamFull(s, count)
+			return
+		}
+		writeChan <- xinfoStream(s)
+	case "groups":
+		if len(args) != 2 {
+			writeChan <- wrongNumberOfArgs("xinfo|groups")
+			return
+		}
+		groups := s.Groups()
+		reply := protocol.ToArrayHeader(len(groups))
+		for _, g := range groups {
+			reply = append(reply, xinfoGroup(s, g)...)
+		}
+		writeChan <- reply
+	case "consumers":
+		if len(args) != 3 {
+			writeChan <- wrongNumberOfArgs("xinfo|consumers")
+			return
+		}
+		g := s.Group(args[2])
+		if g == nil {
+			writeChan <- protocol.ToError(fmt.Sprintf("NOGROUP No such consumer group '%s' for key name '%s'", args[2], args[1]))
+			return
+		}
+		consumers := g.Consumers()
+		reply := protocol.ToArrayHeader(len(consumers))
+		for _, c := range consumers {
+			reply = append(reply, xinfoConsumer(c, now)...)
+		}
+		writeChan <- reply
+	}
+}
+
+// parseXinfoStreamArgs parses "[FULL [COUNT count]]", where a zero count
+// lists everything.
+func parseXinfoStreamArgs(args []string) (bool, int, error) {
+	switch {
+	case len(args) == 0:
+		return false, 0, nil
+	case len(args) == 1 && strings.ToLower(args[0]) == "full":
+		return true, DEFAULT_XINFO_FULL_COUNT, nil
+	case len(args) == 3 && strings.ToLower(args[0]) == "full" && strings.ToLower(args[1]) == "count":
+		count, err := strconv.Atoi(args[2])
+		if err != nil {
+			return false, 0, errors.New(notIntegerError)
+		}
+		return true, max(count, 0), nil
+	}
+	return false, 0, errors.New(syntaxError)
+}
+
+// xinfoMap builds a map reply from alternating field names and encoded
+// values.
+type xinfoMap struct {
+	fields int
+	body   []byte
+}
+
+func (m *xinfoMap) add(field string, value []byte) {
+	m.fields++
+	m.body = append(m.body, protocol.ToBulkString(field)...)
+	m.body = append(m.body, value...)
+}
+
+func (m *xinfoMap) encoded() []byte {
+	return append(protocol.ToArrayHeader(2*m.fields), m.body...)
+}
+
+func xinfoStreamHeader(s *entry.Stream) *xinfoMap {
+	m := &xinfoMap{}
+	m.add("length", protocol.ToRespInt(s.Len()))
+	m.add("radix-tree-keys", protocol.ToRespInt(s.RadixTreeKeys()))
+	m.add("radix-tree-nodes", protocol.ToRespInt(s.RadixTreeNodes()))
+	m.add("last-generated-id", protocol.ToBulkString(s.LastID().String()))
+	m.add("max-deleted-entry-id", protocol.ToBulkString(s.MaxDeletedID().String()))
+	m.add("entries-added", protocol.ToRespInt(s.EntriesAdded()))
+	firstID := entry.NewStreamID(0, 0)
+	if id := s.FirstID(); id != nil {
+		firstID = id
+	}
+	m.add("recorded-first-entry-id", protocol.ToBulkString(firstID.String()))
+	return m
+}
+
+func xinfoStream(s *entry.Stream) []byte {
+	m := xinfoStreamHeader(s)
+	m.add("groups", protocol.ToRespInt(len(s.Groups())))
+	m.add("first-entry", xinfoEntry(s.FirstEntry()))
+	m.add("last-entry", xinfoEntry(s.LastEntry()))
+	return m.encoded()
+}
+
+// xinfoEntry encodes the only entry of items, or nil if there is none.
+func xinfoEntry(items entry.StreamRangeData) []byte {
+	if len(items) == 0 {
+		return protocol.NullBulkString()
+	}
+	return items[0].Encoded()
+}
+
+func xinfoStreamFull(s *entry.Stream, count int) []byte {
+	limit := count
+	if limit == 0 {
+		limit = -1
+	}
+	m := xinfoStreamHeader(s)
+	items, _ := s.GetDataFromRange("-", "+", limit, false)
+	m.add("entries", items.Encoded())
+	groups := s.Groups()
+	groupsReply := protocol.ToArrayHeader(len(groups))
+	for _, g := range groups {
+		gm := &xinfoMap{}
+		gm.add("name", protocol.ToBulkString(g.Name))
+		addGroupPosition(gm, s, g)
+		pending := truncatePending(g.Pending(nil), count)
+		pendingReply := protocol.ToArrayHeader(len(pending))
+		for _, p := range pending {
+			pendingReply = append(pendingReply, protocol.ToArrayHeader(4)...)
+			pendingReply = append(pendingReply, protocol.ToBulkString(p.ID.String())...)
+			pendingReply = append(pendingReply, protocol.ToBulkString(p.Consumer.Name)...)
+			pendingReply = append(pendingReply, protocol.ToRespInt(int(p.DeliveryTime))...)
+			pendingReply = append(pendingReply, protocol.ToRespInt(p.DeliveryCount)...)
+		}
+		gm.add("pel-count", protocol.ToRespInt(g.PendingLen()))
+		gm.add("pending", pendingReply)
+		consumers := g.Consumers()
+		consumersReply := protocol.ToArrayHeader(len(consumers))
+		for _, c := range consumers {
+			cm := &xinfoMap{}
+			cm.add("name", protocol.ToBulkString(c.Name))
+			cm.add("seen-time", protocol.ToRespInt(int(c.SeenTime)))
+			cm.add("active-time", protocol.ToRespInt(int(c.ActiveTime)))
+			cm.add("pel-count", protocol.ToRespInt(c.PendingLen()))
+			pending := truncatePending(g.Pending(c), count)
+			pendingReply := protocol.ToArrayHeader(len(pending))
+			for _, p := range pending {
+				pendingReply = append(pendingReply, protocol.ToArrayHeader(3)...)
+				pendingReply = append(pendingReply, protocol.ToBulkString(p.ID.String())...)
+				pendingReply = append(pendingReply, protocol.ToRespInt(int(p.DeliveryTime))...)
+				pendingReply = append(pendingReply, protocol.ToRespInt(p.DeliveryCount)...)
+			}
+			cm.add("pending", pendingReply)
+			consumersReply = append(consumersReply, cm.encoded()...)
+		}
+		gm.add("consumers", consumersReply)
+		groupsReply = append(groupsReply, gm.encoded()...)
+	}
+	m.add("groups", groupsReply)
+	return m.encoded()
+}
+
+func truncatePending(pending []*entry.StreamPendingEntry, count int) []*entry.StreamPendingEntry {
+	if count > 0 && len(pending) > count {
+		return pending[:count]
+	}
+	return pending
+}
+
+// addGroupPosition adds where g is in the stream, which XINFO GROUPS and
+// XINFO STREAM FULL both report.
+func addGroupPosition(m *xinfoMap, s *entry.Stream, g *entry.StreamGroup) {
+	m.add("last-delivered-id", protocol.ToBulkString(g.LastID().String()))
+	if g.EntriesRead() == entry.STREAM_ENTRIES_READ_UNKNOWN {
+		m.add("entries-read", protocol.NullBulkString())
+	} else {
+		m.add("entries-read", protocol.ToRespInt(g.EntriesRead()))
+	}
+	if lag, ok := s.Lag(g); ok {
+		m.add("lag", protocol.ToRespInt(lag))
+	} else {
+		m.add("lag", protocol.NullBulkString())
+	}
+}
+
+func xinfoGroup(s *entry.Stream, g *entry.StreamGroup) []byte {
+	m := &xinfoMap{}
+	m.add("name", protocol.ToBulkString(g.Name))
+	m.add("consumers", protocol.ToRespInt(len(g.Consumers())))
+	m.add("pending", protocol.ToRespInt(g.PendingLen()))
+	addGroupPosition(m, s, g)
+	return m.encoded()
+}
+
+func xinfoConsumer(c *entry.StreamConsumer, now int64) []byte {
+	m := &xinfoMap{}
+	m.add("name", protocol.ToBulkString(c.Name))
+	m.add("pending", protocol.ToRespInt(c.PendingLen()))
+	m.add("idle", protocol.ToRespInt(int(now-c.SeenTime)))
+	inactive := -1
+	if c.ActiveTime >= 0 {
+		inactive = int(now - c.ActiveTime)
+	}
+	m.add("inactive", protocol.ToRespInt(inactive))
+	return m.encoded()
+}
+
+func (x *Xinfo) CanPropogateCommand(args []string) bool {
+	return false
+}
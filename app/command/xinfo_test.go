@@ -0,0 +1,184 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// respMap is the reply for a map sent as a flat array of field names and
+// their encoded values.
+func respMap(fieldValues ...string) string {
+	reply := fmt.Sprintf("*%d\r\n", len(fieldValues))
+	for i := 0; i+1 < len(fieldValues); i += 2 {
+		reply += string(protocol.ToBulkString(fieldValues[i])) + fieldValues[i+1]
+	}
+	return reply
+}
+
+func bulk(s string) string {
+	return string(protocol.ToBulkString(s))
+}
+
+func integer(i int) string {
+	return string(protocol.ToRespInt(i))
+}
+
+// newXinfoTestContext returns a context holding stream s with three entries,
+// group g where alice has read the first two, bob has read nothing, and
+// group g2 created at the end of the stream. The times the group keeps are
+// pinned to 1000 so that the replies do not depend on the clock.
+func newXinfoTestContext(t *testing.T) *event.Context {
+	ctx := newTestContext()
+	run(t, ctx, "XADD", "s", "1-1", "f", "a")
+	run(t, ctx, "XADD", "s", "2-1", "f", "b")
+	run(t, ctx, "XADD", "s", "3-1", "f", "c")
+	run(t, ctx, "XGROUP", "CREATE", "s", "g", "0")
+	run(t, ctx, "XREADGROUP", "GROUP", "g", "alice", "COUNT", "2", "STREAMS", "s", ">")
+	run(t, ctx, "XGROUP", "CREATECONSUMER", "s", "g", "bob")
+	run(t, ctx, "XGROUP", "CREATE", "s", "g2", "$")
+	run(t, ctx, "SET", "str", "v")
+	g := ctx.Store[0]["s"].(*entry.Stream).Group("g")
+	for _, c := range g.Consumers() {
+		c.SeenTime = 1000
+		if c.ActiveTime >= 0 {
+			c.ActiveTime = 1000
+		}
+	}
+	for _, p := range g.Pending(nil) {
+		p.DeliveryTime = 1000
+	}
+	return ctx
+}
+
+func TestXinfoStream(t *testing.T) {
+	ctx := newXinfoTestContext(t)
+	e1, e2, e3 := streamEntry("1-1", "f", "a"), streamEntry("2-1", "f", "b"), streamEntry("3-1", "f", "c")
+	header := []string{
+		"length", integer(3),
+		"radix-tree-keys", integer(1),
+		"radix-tree-nodes", integer(2),
+		"last-generated-id", bulk("3-1"),
+		"max-deleted-entry-id", bulk("0-0"),
+		"entries-added", integer(3),
+		"recorded-first-entry-id", bulk("1-1"),
+	}
+	// full is the FULL reply where count pending entries are listed per group
+	// and per consumer.
+	full := func(entries string, count int) string {
+		pending := []string{
+			"*4\r\n" + bulk("1-1") + bulk("alice") + integer(1000) + integer(1),
+			"*4\r\n" + bulk("2-1") + bulk("alice") + integer(1000) + integer(1),
+		}
+		consumerPending := []string{
+			"*3\r\n" + bulk("1-1") + integer(1000) + integer(1),
+			"*3\r\n" + bulk("2-1") + integer(1000) + integer(1),
+		}
+		g := respMap(
+			"name", bulk("g"),
+			"last-delivered-id", bulk("2-1"),
+			"entries-read", integer(2),
+			"lag", integer(1),
+			"pel-count", integer(2),
+			"pending", streamEntries(pending[:count]...),
+			"consumers", streamEntries(
+				respMap("name", bulk("alice"), "seen-time", integer(1000), "active-time", integer(1000), "pel-count", integer(2),
+					"pending", streamEntries(consumerPending[:count]...)),
+				respMap("name", bulk("bob"), "seen-time", integer(1000), "active-time", integer(-1), "pel-count", integer(0),
+					"pending", streamEntries()),
+			),
+		)
+		// g2 is at the end of the stream without knowing how many entries it
+		// skipped, so its entries-read is unknown while its lag is 0.
+		g2 := respMap(
+			"name", bulk("g2"),
+			"last-delivered-id", bulk("3-1"),
+			"entries-read", "$-1\r\n",
+			"lag", integer(0),
+			"pel-count", integer(0),
+			"pending", streamEntries(),
+			"consumers", streamEntries(),
+		)
+		return respMap(append(header, "entries", entries, "groups", streamEntries(g, g2))...)
+	}
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"STREAM", "s"}, respMap(append(header, "groups", integer(2), "first-entry", e1, "last-entry", e3)...)},
+		{[]string{"stream", "s", "full"}, full(streamEntries(e1, e2, e3), 2)},
+		{[]string{"STREAM", "s", "FULL", "COUNT", "1"}, full(streamEntries(e1), 1)},
+		{[]string{"STREAM", "s", "FULL", "COUNT", "0"}, full(streamEntries(e1, e2, e3), 2)},
+		{[]string{"STREAM", "s", "FULL", "COUNT", "x"}, "-" + notIntegerError + "\r\n"},
+		{[]string{"STREAM", "s", "COUNT", "1"}, "-" + syntaxError + "\r\n"},
+		{[]string{"STREAM", "missing"}, "-ERR no such key\r\n"},
+		{[]string{"STREAM", "str"}, "-" + wrongTypeError + "\r\n"},
+		{[]string{"STREAM"}, "-ERR wrong number of arguments for 'xinfo' command\r\n"},
+		{[]string{"HELPME", "s"}, "-ERR unknown subcommand 'HELPME'. Try XINFO HELP.\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(t, ctx, "XINFO", tt.args...); got != tt.expected {
+			t.Errorf("XINFO %v: expected %q; got %q", tt.args, tt.expected, got)
+		}
+	}
+
+	// An empty stream has no first or last entry. Trimming, unlike XDEL,
+	// leaves max-deleted-entry-id alone.
+	run(t, ctx, "XTRIM", "s", "MAXLEN", "0")
+	got := run(t, ctx, "XINFO", "STREAM", "s")
+	expected := respMap(
+		"length", integer(0),
+		"radix-tree-keys", integer(0),
+		"radix-tree-nodes", integer(1),
+		"last-generated-id", bulk("3-1"),
+		"max-deleted-entry-id", bulk("0-0"),
+		"entries-added", integer(3),
+		"recorded-first-entry-id", bulk("0-0"),
+		"groups", integer(2),
+		"first-entry", "$-1\r\n",
+		"last-entry", "$-1\r\n",
+	)
+	if got != expected {
+		t.Errorf("XINFO STREAM on an empty stream: expected %q; got %q", expected, got)
+	}
+}
+
+// xinfoIdle matches the idle times XINFO CONSUMERS reports, which depend on
+// the clock. Unknown times, reported as -1, are left as they are.
+var xinfoIdle = regexp.MustCompile(`(\$4\r\nidle\r\n|\$8\r\ninactive\r\n):\d+\r\n`)
+
+func TestXinfoGroupsAndConsumers(t *testing.T) {
+	ctx := newXinfoTestContext(t)
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"GROUPS", "s"}, streamEntries(
+			respMap("name", bulk("g"), "consumers", integer(2), "pending", integer(2),
+				"last-delivered-id", bulk("2-1"), "entries-read", integer(2), "lag", integer(1)),
+			respMap("name", bulk("g2"), "consumers", integer(0), "pending", integer(0),
+				"last-delivered-id", bulk("3-1"), "entries-read", "$-1\r\n", "lag", integer(0)),
+		)},
+		{[]string{"CONSUMERS", "s", "g"}, streamEntries(
+			respMap("name", bulk("alice"), "pending", integer(2), "idle", ":<ms>\r\n", "inactive", ":<ms>\r\n"),
+			respMap("name", bulk("bob"), "pending", integer(0), "idle", ":<ms>\r\n", "inactive", integer(-1)),
+		)},
+		{[]string{"CONSUMERS", "s", "g2"}, "*0\r\n"},
+		{[]string{"CONSUMERS", "s", "nope"}, "-NOGROUP No such consumer group 'nope' for key name 's'\r\n"},
+		{[]string{"GROUPS", "missing"}, "-ERR no such key\r\n"},
+		{[]string{"CONSUMERS", "str", "g"}, "-" + wrongTypeError + "\r\n"},
+		{[]string{"GROUPS", "s", "g"}, "-ERR wrong number of arguments for 'xinfo|groups' command\r\n"},
+		{[]string{"CONSUMERS", "s"}, "-ERR wrong number of arguments for 'xinfo|consumers' command\r\n"},
+	}
+	for _, tt := range tests {
+		got := xinfoIdle.ReplaceAllString(run(t, ctx, "XINFO", tt.args...), "$1:<ms>\r\n")
+		if got != tt.expected {
+			t.Errorf("XINFO %v: expected %q; got %q", tt.args, tt.expected, got)
+		}
+	}
+}
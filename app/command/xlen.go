@@ -0,0 +1,29 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Xlen struct{}
+
+func (x *Xlen) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 1 {
+		writeChan <- wrongNumberOfArgs("xlen")
+		return
+	}
+	stream, err := lookupStream(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if stream == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	writeChan <- protocol.ToRespInt(stream.Len())
+}
+
+func (x *Xlen) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Xpending serves both forms of XPENDING: the summary of a group's pending
+// entries, and with a range the pending entries themselves.
+type Xpending struct{}
+
+func (x *Xpending) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("xpending")
+		return
+	}
+	key, group := args[0], args[1]
+	minIdle := int64(0)
+	i := 2
+	if len(args) > i && strings.ToLower(args[i]) == "idle" {
+		if len(args) <= i+1 {
+			writeChan <- protocol.ToError(syntaxError)
+			return
+		}
+		n, err := strconv.ParseInt(args[i+1], 10, 64)
+		if err != nil {
+			writeChan <- protocol.ToError(notIntegerError)
+			return
+		}
+		minIdle = max(n, 0)
+		i += 2
+	}
+	extended := len(args) > 2
+	if extended && len(args)-i != 3 && len(args)-i != 4 {
+		writeChan <- protocol.ToError(syntaxError)
+		return
+	}
+	var start, end *entry.StreamID
+	count := 0
+	if extended {
+		var err error
+		start, end, err = entry.ParseStreamInterval(args[i], args[i+1])
+		if err != nil {
+			writeChan <- protocol.ToError(err.Error())
+			return
+		}
+		if count, err = strconv.Atoi(args[i+2]); err != nil {
+			writeChan <- protocol.ToError(notIntegerError)
+			return
+		}
+	}
+	_, g, err := lookupStreamGroup(ctx, key, group)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if g == nil {
+		writeChan <- protocol.ToError(noGroupError(key, group).Error())
+		return
+	}
+	if !extended {
+		writeChan <- pendingSummary(g)
+		return
+	}
+	var c *entry.StreamConsumer
+	if len(args)-i == 4 {
+		if c = g.Consumer(args[i+3]); c == nil {
+			writeChan <- protocol.ToArrayHeader(0)
+			return
+		}
+	}
+	now := mstime()
+	pending := g.PendingRange(c, start, end, count, minIdle, now)
+	reply := protocol.ToArrayHeader(len(pending))
+	for _, p := range pending {
+		reply = append(reply, protocol.ToArrayHeader(4)...)
+		reply = append(reply, protocol.ToBulkString(p.ID.String())...)
+		reply = append(reply, protocol.ToBulkString(p.Consumer.Name)...)
+		reply = append(reply, protocol.ToRespInt(int(now-p.DeliveryTime))...)
+		reply = append(reply, protocol.ToRespInt(p.DeliveryCount)...)
+	}
+	writeChan <- reply
+}
+
+func pendingSummary(g *entry.StreamGroup) []byte {
+	reply := protocol.ToArrayHeader(4)
+	reply = append(reply, protocol.ToRespInt(g.PendingLen())...)
+	first, last := g.PendingBounds()
+	if first == nil {
+		reply = append(reply, protocol.NullBulkString()...)
+		reply = append(reply, protocol.NullBulkString()...)
+		return append(reply, protocol.NullArray()...)
+	}
+	reply = append(reply, protocol.ToBulkString(first.String())...)
+	reply = append(reply, protocol.ToBulkString(last.String())...)
+	consumers := []*entry.StreamConsumer{}
+	for _, c := range g.Consumers() {
+		if c.PendingLen() > 0 {
+			consumers = append(consumers, c)
+		}
+	}
+	reply = append(reply, protocol.ToArrayHeader(len(consumers))...)
+	for _, c := range consumers {
+		reply = append(reply, protocol.ToArrayHeader(2)...)
+		reply = append(reply, protocol.ToBulkString(c.Name)...)
+		reply = append(reply, protocol.ToBulkString(strconv.Itoa(c.PendingLen()))...)
+	}
+	return reply
+}
+
+func (x *Xpending) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -1,38 +1,52 @@
 package command
 
 import (
-	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"strconv"
+	"strings"
+
 	"github.com/codecrafters-io/redis-starter-go/app/event"
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
 )
 
-type Xrange struct{}
-
-const xrangeUsageStr string = "XADD key start end"
-const streamNotExistError string = "ERR Stream does not exist"
-const entryNotStreamError string = "ERR Entry not a stream"
+// Xrange serves both XRANGE and XREVRANGE, the latter taking its end ID
+// before its start ID.
+type Xrange struct {
+	name    string
+	reverse bool
+}
 
 func (x *Xrange) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
-	if len(args) != 3 {
-		writeChan <- []byte(xrangeUsageStr)
+	if len(args) != 3 && len(args) != 5 {
+		writeChan <- wrongNumberOfArgs(x.name)
 		return
 	}
 	key, start, end := args[0], args[1], args[2]
-	if _, ok := ctx.Store[ctx.CurrentDatabase]; !ok {
-		writeChan <- protocol.ToError(streamNotExistError)
-		return
+	if x.reverse {
+		start, end = end, start
 	}
-	e, ok := ctx.Store[ctx.CurrentDatabase][key]
-	if !ok {
-		writeChan <- protocol.ToError(streamNotExistError)
+	count := -1
+	if len(args) == 5 {
+		if strings.ToLower(args[3]) != "count" {
+			writeChan <- protocol.ToError(syntaxError)
+			return
+		}
+		n, err := strconv.Atoi(args[4])
+		if err != nil {
+			writeChan <- protocol.ToError(notIntegerError)
+			return
+		}
+		count = max(n, 0)
+	}
+	s, err := lookupStream(ctx, key)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
 		return
 	}
-	s, ok := e.(*entry.Stream)
-	if !ok {
-		writeChan <- protocol.ToError(entryNotStreamError)
+	if s == nil {
+		writeChan <- protocol.ToArrayHeader(0)
 		return
 	}
-	items, err := s.GetDataFromRange(start, end)
+	items, err := s.GetDataFromRange(start, end, count, x.reverse)
 	if err != nil {
 		writeChan <- protocol.ToError(err.Error())
 		return
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// streamEntry is the reply for a stream entry: its ID and field-value pairs.
+func streamEntry(id string, fieldValues ...string) string {
+	return "*2\r\n" + string(protocol.ToBulkString(id)) + array(fieldValues...)
+}
+
+// streamEntries is the reply for a list of stream entries.
+func streamEntries(entries ...string) string {
+	return fmt.Sprintf("*%d\r\n", len(entries)) + strings.Join(entries, "")
+}
+
+func TestXrange(t *testing.T) {
+	ctx := newTestContext()
+	for i, id := range []string{"1-1", "1-2", "2-1", "3-1"} {
+		run(t, ctx, "XADD", "s", id, "f", string(rune('a'+i)))
+	}
+	run(t, ctx, "SET", "str", "v")
+	e11, e12, e21, e31 := streamEntry("1-1", "f", "a"), streamEntry("1-2", "f", "b"), streamEntry("2-1", "f", "c"), streamEntry("3-1", "f", "d")
+
+	tests := []struct {
+		cmd      []string
+		expected string
+	}{
+		{[]string{"XRANGE", "s", "-", "+"}, streamEntries(e11, e12, e21, e31)},
+		// An ID without a sequence number covers the whole millisecond.
+		{[]string{"XRANGE", "s", "1", "1"}, streamEntries(e11, e12)},
+		{[]string{"XRANGE", "s", "1-2", "2"}, streamEntries(e12, e21)},
+		{[]string{"XRANGE", "s", "-", "+", "COUNT", "2"}, streamEntries(e11, e12)},
+		{[]string{"XRANGE", "s", "-", "+", "count", "0"}, streamEntries()},
+		{[]string{"XRANGE", "s", "-", "+", "COUNT", "-1"}, streamEntries()},
+		{[]string{"XRANGE", "s", "(1-1", "+"}, streamEntries(e12, e21, e31)},
+		{[]string{"XRANGE", "s", "-", "(3-1"}, streamEntries(e11, e12, e21)},
+		{[]string{"XRANGE", "s", "(1-1", "(2-1"}, streamEntries(e12)},
+		{[]string{"XRANGE", "s", "(1-2", "+", "COUNT", "1"}, streamEntries(e21)},
+		{[]string{"XRANGE", "s", "3", "1"}, streamEntries()},
+		{[]string{"XRANGE", "missing", "-", "+"}, streamEntries()},
+		{[]string{"XREVRANGE", "s", "+", "-"}, streamEntries(e31, e21, e12, e11)},
+		{[]string{"XREVRANGE", "s", "+", "-", "COUNT", "2"}, streamEntries(e31, e21)},
+		{[]string{"XREVRANGE", "s", "(3-1", "(1-1"}, streamEntries(e21, e12)},
+		{[]string{"XREVRANGE", "s", "1", "1"}, streamEntries(e12, e11)},
+		{[]string{"XREVRANGE", "s", "-", "+"}, streamEntries()},
+		{[]string{"XRANGE", "s", "(18446744073709551615-18446744073709551615", "+"}, "-ERR Invalid stream ID specified as stream command argument\r\n"},
+		{[]string{"XRANGE", "s", "(9223372036854775807-9223372036854775807", "+"}, "-ERR invalid start ID for the interval\r\n"},
+		{[]string{"XRANGE", "s", "-", "(0-0"}, "-ERR invalid end ID for the interval\r\n"},
+		{[]string{"XRANGE", "s", "x", "+"}, "-ERR Invalid stream ID specified as stream command argument\r\n"},
+		{[]string{"XRANGE", "s", "-", "+", "LIMIT", "1"}, "-" + syntaxError + "\r\n"},
+		{[]string{"XRANGE", "s", "-", "+", "COUNT", "x"}, "-" + notIntegerError + "\r\n"},
+		{[]string{"XRANGE", "s", "-"}, "-ERR wrong number of arguments for 'xrange' command\r\n"},
+		{[]string{"XREVRANGE", "s", "+", "-", "COUNT"}, "-ERR wrong number of arguments for 'xrevrange' command\r\n"},
+		{[]string{"XRANGE", "str", "-", "+"}, "-" + wrongTypeError + "\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+	}
+}
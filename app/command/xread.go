@@ -0,0 +1,183 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+// Xread serves XREAD. With BLOCK it waits for the first stream to get an
+// entry past the requested ID and replies with that stream alone.
+type Xread struct{}
+
+// xreadLastEntry stands for the "+" ID, which reads the last entry of a
+// stream whatever its ID.
+var xreadLastEntry = &entry.StreamID{}
+
+func (x *Xread) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	opts, err := parseXreadArgs(args, false)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	count, block, keys, idArgs := opts.count, opts.block, opts.keys, opts.ids
+	ids := make([]*entry.StreamID, len(keys))
+	for j, key := range keys {
+		s, err := lookupStream(ctx, key)
+		if err != nil {
+			writeChan <- protocol.ToError(err.Error())
+			return
+		}
+		switch idArgs[j] {
+		case "$":
+			ids[j] = entry.NewStreamID(0, 0)
+			if s != nil {
+				ids[j] = s.LastID()
+			}
+		case "+":
+			ids[j] = xreadLastEntry
+		case ">":
+			writeChan <- protocol.ToError("ERR The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option.")
+			return
+		default:
+			ids[j], err = entry.ParseStreamID(idArgs[j])
+			if err != nil {
+				writeChan <- protocol.ToError(err.Error())
+				return
+			}
+		}
+	}
+	if reply := readStreams(ctx, keys, ids, count); reply != nil {
+		writeChan <- reply
+		return
+	}
+	if block < 0 {
+		writeChan <- protocol.NullArray()
+		return
+	}
+	ctx.Blocking.Block(&event.BlockedClient{
+		Conn:     ctx.Conn,
+		Database: ctx.CurrentDatabase,
+		Keys:     keys,
+		Serve: func(key string) ([]byte, bool) {
+			j := slices.Index(keys, key)
+			reply := readStreams(ctx, keys[j:j+1], ids[j:j+1], count)
+			return reply, reply != nil
+		},
+		Timeout: func() {
+			utils.WriteToConnection(ctx.Conn, protocol.NullArray())
+		},
+	}, block)
+}
+
+// xreadArgs holds the options shared by XREAD and XREADGROUP. A negative
+// count reads every entry and a negative block does not block.
+type xreadArgs struct {
+	count    int
+	block    time.Duration
+	noack    bool
+	group    string
+	consumer string
+	keys     []string
+	ids      []string
+}
+
+func parseXreadArgs(args []string, withGroup bool) (*xreadArgs, error) {
+	opts := &xreadArgs{count: -1, block: -1}
+	i := 0
+	for ; i < len(args); i++ {
+		opt := strings.ToLower(args[i])
+		if opt == "streams" {
+			break
+		}
+		switch {
+		case opt == "count" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return nil, errors.New(notIntegerError)
+			}
+			if n > 0 {
+				opts.count = n
+			}
+			i++
+		case opt == "block" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return nil, errors.New("ERR timeout is not an integer or out of range")
+			}
+			if n < 0 {
+				return nil, errors.New("ERR timeout is negative")
+			}
+			opts.block = time.Duration(n) * time.Millisecond
+			i++
+		case opt == "group" && i+2 < len(args):
+			if !withGroup {
+				return nil, errors.New("ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead.")
+			}
+			opts.group, opts.consumer = args[i+1], args[i+2]
+			i += 2
+		case opt == "noack" && withGroup:
+			opts.noack = true
+		default:
+			return nil, errors.New(syntaxError)
+		}
+	}
+	if i == len(args) {
+		return nil, errors.New(syntaxError)
+	}
+	streams := args[i+1:]
+	if len(streams) == 0 || len(streams)%2 != 0 {
+		name := "xread"
+		if withGroup {
+			name = "xreadgroup"
+		}
+		return nil, fmt.Errorf("ERR Unbalanced '%s' list of streams: for each stream key an ID or '$' must be specified.", name)
+	}
+	if withGroup && opts.group == "" {
+		return nil, errors.New("ERR Missing GROUP option for XREADGROUP")
+	}
+	opts.keys, opts.ids = streams[:len(streams)/2], streams[len(streams)/2:]
+	return opts, nil
+}
+
+// readStreams returns the XREAD reply for the entries after ids in keys,
+// leaving out streams with nothing to read, or nil if none of them has any.
+func readStreams(ctx *event.Context, keys []string, ids []*entry.StreamID, count int) []byte {
+	found := 0
+	var body []byte
+	for j, key := range keys {
+		s, _ := lookupStream(ctx, key)
+		if s == nil {
+			continue
+		}
+		var items entry.StreamRangeData
+		if ids[j] == xreadLastEntry {
+			items = s.LastEntry()
+		} else {
+			items = s.EntriesAfter(ids[j], count)
+		}
+		if len(items) == 0 {
+			continue
+		}
+		found++
+		body = append(body, protocol.ToArrayHeader(2)...)
+		body = append(body, protocol.ToBulkString(key)...)
+		body = append(body, items.Encoded()...)
+	}
+	if found == 0 {
+		return nil
+	}
+	return append(protocol.ToArrayHeader(found), body...)
+}
+
+func (x *Xread) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -0,0 +1,133 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// xreadReply is the reply for the entries read from a stream.
+func xreadReply(key string, entries ...string) string {
+	return "*2\r\n" + string(protocol.ToBulkString(key)) + streamEntries(entries...)
+}
+
+func TestXread(t *testing.T) {
+	ctx := newTestContext()
+	run(t, ctx, "XADD", "a", "1-1", "f", "a1")
+	run(t, ctx, "XADD", "a", "2-1", "f", "a2")
+	run(t, ctx, "XADD", "b", "1-5", "f", "b1")
+	run(t, ctx, "XADD", "empty", "1-1", "f", "v")
+	run(t, ctx, "XDEL", "empty", "1-1")
+	run(t, ctx, "SET", "str", "v")
+	a1, a2, b1 := streamEntry("1-1", "f", "a1"), streamEntry("2-1", "f", "a2"), streamEntry("1-5", "f", "b1")
+
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"STREAMS", "a", "b", "0", "0"}, "*2\r\n" + xreadReply("a", a1, a2) + xreadReply("b", b1)},
+		{[]string{"COUNT", "1", "STREAMS", "a", "b", "0", "0"}, "*2\r\n" + xreadReply("a", a1) + xreadReply("b", b1)},
+		{[]string{"count", "0", "streams", "a", "1-1"}, "*1\r\n" + xreadReply("a", a2)},
+		// Streams with nothing past their ID are left out of the reply.
+		{[]string{"STREAMS", "a", "b", "1-1", "1-5"}, "*1\r\n" + xreadReply("a", a2)},
+		{[]string{"STREAMS", "missing", "b", "0", "0"}, "*1\r\n" + xreadReply("b", b1)},
+		{[]string{"STREAMS", "a", "b", "2-1", "1-5"}, "*-1\r\n"},
+		// "$" reads nothing without BLOCK, and "+" the last entry.
+		{[]string{"STREAMS", "a", "b", "$", "$"}, "*-1\r\n"},
+		{[]string{"STREAMS", "a", "b", "+", "+"}, "*2\r\n" + xreadReply("a", a2) + xreadReply("b", b1)},
+		{[]string{"STREAMS", "a", "b", "+", "$"}, "*1\r\n" + xreadReply("a", a2)},
+		{[]string{"STREAMS", "a", "b", "$", "0"}, "*1\r\n" + xreadReply("b", b1)},
+		{[]string{"STREAMS", "empty", "missing", "a", "+", "+", "1-1"}, "*1\r\n" + xreadReply("a", a2)},
+		{[]string{"STREAMS", "a", "b", "0"}, "-ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.\r\n"},
+		{[]string{"STREAMS", "a", ">"}, "-ERR The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option.\r\n"},
+		{[]string{"STREAMS", "a", "x"}, "-ERR Invalid stream ID specified as stream command argument\r\n"},
+		{[]string{"STREAMS", "a", "str", "0", "0"}, "-" + wrongTypeError + "\r\n"},
+		{[]string{"GROUP", "g", "c", "STREAMS", "a", "0"}, "-ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead.\r\n"},
+		{[]string{"COUNT", "x", "STREAMS", "a", "0"}, "-" + notIntegerError + "\r\n"},
+		{[]string{"COUNT", "1", "a", "0"}, "-" + syntaxError + "\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(t, ctx, "XREAD", tt.args...); got != tt.expected {
+			t.Errorf("XREAD %v: expected %q; got %q", tt.args, tt.expected, got)
+		}
+	}
+}
+
+// TestXreadBlock checks that a blocked XREAD is served by the next entry
+// added to one of its streams, with only that stream in the reply.
+func TestXreadBlock(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"BLOCK", "0", "STREAMS", "a", "b", "$", "$"}, "*1\r\n" + xreadReply("b", streamEntry("5-1", "f", "new"))},
+		{[]string{"BLOCK", "0", "STREAMS", "b", "a", "0", "2-1"}, "*1\r\n" + xreadReply("b", streamEntry("5-1", "f", "new"))},
+		{[]string{"COUNT", "1", "BLOCK", "1000", "STREAMS", "missing", "b", "+", "$"}, "*1\r\n" + xreadReply("b", streamEntry("5-1", "f", "new"))},
+	}
+	for _, tt := range tests {
+		ctx := newTestContext()
+		run(t, ctx, "XADD", "a", "1-1", "f", "a1")
+		run(t, ctx, "XADD", "a", "2-1", "f", "a2")
+		newTestClient(t, ctx)
+		if got := run(t, ctx, "XREAD", tt.args...); got != "" {
+			t.Errorf("XREAD %v: expected to block; got %q", tt.args, got)
+		}
+		// A write to a key the client is not blocked on does not wake it.
+		run(t, ctx, "XADD", "other", "1-1", "f", "v")
+		if served := ctx.Blocking.HandleReadyKeys(); len(served) != 0 {
+			t.Errorf("XREAD %v: expected to stay blocked by a write to another key; got %v", tt.args, served)
+		}
+		run(t, ctx, "XADD", "b", "5-1", "f", "new")
+		served := ctx.Blocking.HandleReadyKeys()
+		if len(served) != 1 || string(served[0].Reply) != tt.expected {
+			t.Errorf("XREAD %v: expected to be served %q; got %v", tt.args, tt.expected, served)
+		}
+		run(t, ctx, "XADD", "b", "6-1", "f", "v")
+		if served := ctx.Blocking.HandleReadyKeys(); len(served) != 0 {
+			t.Errorf("XREAD %v: expected to be served once; got %v", tt.args, served)
+		}
+	}
+}
+
+func TestXreadBlockTimeout(t *testing.T) {
+	ctx := newTestContext()
+	read := newTestClient(t, ctx)
+	run(t, ctx, "XREAD", "BLOCK", "10", "STREAMS", "s", "$")
+	if got := expireBlocked(t, ctx, read); got != "*-1\r\n" {
+		t.Errorf("Expected a null array on timeout; got %q", got)
+	}
+	run(t, ctx, "XADD", "s", "1-1", "f", "v")
+	if served := ctx.Blocking.HandleReadyKeys(); len(served) != 0 {
+		t.Errorf("Expected no client left to serve after the timeout; got %v", served)
+	}
+
+	for _, tt := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"BLOCK", "x", "STREAMS", "s", "$"}, "-ERR timeout is not an integer or out of range\r\n"},
+		{[]string{"BLOCK", "-1", "STREAMS", "s", "$"}, "-ERR timeout is negative\r\n"},
+	} {
+		if got := run(t, ctx, "XREAD", tt.args...); got != tt.expected {
+			t.Errorf("XREAD %v: expected %q; got %q", tt.args, tt.expected, got)
+		}
+	}
+}
+
+// TestXreadBlockDisconnect checks that a client disconnecting while blocked
+// is no longer served.
+func TestXreadBlockDisconnect(t *testing.T) {
+	ctx := newTestContext()
+	newTestClient(t, ctx)
+	run(t, ctx, "XREAD", "BLOCK", "0", "STREAMS", "s", "$")
+	ctx.Blocking.UnblockConn(ctx.Conn)
+	run(t, ctx, "XADD", "s", "1-1", "f", "v")
+	if served := ctx.Blocking.HandleReadyKeys(); len(served) != 0 {
+		t.Errorf("Expected a disconnected client not to be served; got %v", served)
+	}
+	select {
+	case e := <-ctx.EventQueue.Queue:
+		t.Errorf("Expected no timeout for a client blocked forever; got %v", e)
+	default:
+	}
+}
@@ -0,0 +1,136 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+// Xreadgroup serves XREADGROUP. The ">" ID reads entries the group has not
+// delivered yet and any other ID re-reads the consumer's own pending entries
+// after it. Replicas are sent the resulting pending entries as XCLAIMs and
+// the new position of the group as an XGROUP SETID.
+type Xreadgroup struct{}
+
+func (x *Xreadgroup) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	opts, err := parseXreadArgs(args, true)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	ctx.RewritePropagation()
+	// A nil ID stands for ">".
+	ids := make([]*entry.StreamID, len(opts.keys))
+	for j, key := range opts.keys {
+		_, g, err := lookupStreamGroup(ctx, key, opts.group)
+		if err != nil {
+			writeChan <- protocol.ToError(err.Error())
+			return
+		}
+		if g == nil {
+			writeChan <- protocol.ToError(fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s' in XREADGROUP with GROUP option", key, opts.group))
+			return
+		}
+		switch opts.ids[j] {
+		case ">":
+		case "$":
+			writeChan <- protocol.ToError("ERR The $ ID is meaningless in the context of XREADGROUP")
+			return
+		default:
+			if ids[j], err = entry.ParseStreamID(opts.ids[j]); err != nil {
+				writeChan <- protocol.ToError(err.Error())
+				return
+			}
+		}
+	}
+
+	now := mstime()
+	effects := [][]string{}
+	found := 0
+	var body []byte
+	for j, key := range opts.keys {
+		s, g, _ := lookupStreamGroup(ctx, key, opts.group)
+		c, created := g.CreateConsumer(opts.consumer, now)
+		if created {
+			effects = append(effects, xgroupCreateConsumerEffect(key, g, opts.consumer))
+		}
+		var items entry.StreamRangeData
+		if ids[j] == nil {
+			var readEffects [][]string
+			items, readEffects = readGroup(key, s, g, c, opts, now)
+			effects = append(effects, readEffects...)
+			if len(items) == 0 {
+				continue
+			}
+		} else {
+			var delivered []*entry.StreamPendingEntry
+			items, delivered = s.ReadHistory(g, c, ids[j], opts.count, now)
+			for _, p := range delivered {
+				effects = append(effects, xclaimEffect(key, g, p))
+			}
+		}
+		found++
+		body = append(body, protocol.ToArrayHeader(2)...)
+		body = append(body, protocol.ToBulkString(key)...)
+		body = append(body, items.Encoded()...)
+	}
+	ctx.AddDirty(len(effects))
+	ctx.RewritePropagation(effects...)
+	if found > 0 {
+		writeChan <- append(protocol.ToArrayHeader(found), body...)
+		return
+	}
+	if opts.block < 0 {
+		writeChan <- protocol.NullArray()
+		return
+	}
+	ctx.Blocking.Block(&event.BlockedClient{
+		Conn:     ctx.Conn,
+		Database: ctx.CurrentDatabase,
+		Keys:     opts.keys,
+		Serve: func(key string) ([]byte, bool) {
+			s, g, err := lookupStreamGroup(ctx, key, opts.group)
+			if err != nil || g == nil {
+				return protocol.ToError("NOGROUP the consumer group this client was blocked on no longer exists"), true
+			}
+			now := mstime()
+			c, _ := g.CreateConsumer(opts.consumer, now)
+			items, readEffects := readGroup(key, s, g, c, opts, now)
+			if len(items) == 0 {
+				return nil, false
+			}
+			reply := protocol.ToArrayHeader(1)
+			reply = append(reply, protocol.ToArrayHeader(2)...)
+			reply = append(reply, protocol.ToBulkString(key)...)
+			reply = append(reply, items.Encoded()...)
+			for _, effect := range readEffects {
+				propagateEffect(ctx, 1, effect)
+			}
+			return reply, true
+		},
+		Timeout: func() {
+			utils.WriteToConnection(ctx.Conn, protocol.NullArray())
+		},
+	}, opts.block)
+}
+
+// readGroup delivers new entries of s to c and returns them along with the
+// commands that repeat the delivery on a replica.
+func readGroup(key string, s *entry.Stream, g *entry.StreamGroup, c *entry.StreamConsumer, opts *xreadArgs, now int64) (entry.StreamRangeData, [][]string) {
+	items, delivered := s.ReadGroup(g, c, opts.count, opts.noack, now)
+	if len(items) == 0 {
+		return items, nil
+	}
+	effects := [][]string{}
+	for _, p := range delivered {
+		effects = append(effects, xclaimEffect(key, g, p))
+	}
+	return items, append(effects, xgroupSetIDEffect(key, g))
+}
+
+func (x *Xreadgroup) CanPropogateCommand(args []string) bool {
+	return true
+}
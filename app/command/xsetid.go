@@ -0,0 +1,68 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Xsetid struct{}
+
+func (x *Xsetid) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 2 && len(args) != 4 && len(args) != 6 {
+		writeChan <- wrongNumberOfArgs("xsetid")
+		return
+	}
+	lastID, err := entry.ParseStreamID(args[1])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	entriesAdded := -1
+	var maxDeletedID *entry.StreamID
+	for i := 2; i < len(args); i += 2 {
+		switch strings.ToLower(args[i]) {
+		case "entriesadded":
+			entriesAdded, err = strconv.Atoi(args[i+1])
+			if err != nil {
+				writeChan <- protocol.ToError(notIntegerError)
+				return
+			}
+			if entriesAdded < 0 {
+				writeChan <- protocol.ToError("ERR entries_added must be positive")
+				return
+			}
+		case "maxdeletedid":
+			maxDeletedID, err = entry.ParseStreamID(args[i+1])
+			if err != nil {
+				writeChan <- protocol.ToError(err.Error())
+				return
+			}
+		default:
+			writeChan <- protocol.ToError(syntaxError)
+			return
+		}
+	}
+	stream, err := lookupStreamWrite(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if stream == nil {
+		writeChan <- protocol.ToError("ERR no such key")
+		return
+	}
+	if err := stream.SetID(lastID, entriesAdded, maxDeletedID); err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	ctx.AddDirty(1)
+	writeChan <- protocol.OkResp()
+}
+
+func (x *Xsetid) CanPropogateCommand(args []string) bool {
+	return true
+}
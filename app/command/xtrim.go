@@ -0,0 +1,54 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Xtrim struct{}
+
+// Handle propagates an approximate trim as the exact trim that took place.
+// The reference policy follows the trim, as in
+// "XTRIM key MAXLEN ~ 100 LIMIT 10 ACKED".
+func (x *Xtrim) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 3 {
+		writeChan <- wrongNumberOfArgs("xtrim")
+		return
+	}
+	ctx.RewritePropagation()
+	key := args[0]
+	trim, i, err := parseStreamTrim(ctx, args, 1)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if i < len(args) {
+		if policy, ok := parseStreamRefPolicy(args[i]); ok {
+			trim.Policy = policy
+			i++
+		}
+	}
+	if i != len(args) {
+		writeChan <- protocol.ToError(syntaxError)
+		return
+	}
+	stream, err := lookupStreamWrite(ctx, key)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if stream == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	removed := stream.Trim(trim)
+	ctx.AddDirty(removed)
+	if removed > 0 {
+		ctx.RewritePropagation(append([]string{"XTRIM", key}, effectiveTrimArgs(trim, stream)...))
+	}
+	writeChan <- protocol.ToRespInt(removed)
+}
+
+func (x *Xtrim) CanPropogateCommand(args []string) bool {
+	return true
+}
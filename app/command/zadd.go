@@ -0,0 +1,137 @@
+package command
+
+import (
+	"math"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zadd struct{}
+
+type zaddFlags struct {
+	nx, xx, gt, lt, ch, incr bool
+}
+
+// Handle parses every score before touching the set so that a bad score
+// rejects the whole command rather than leaving it half applied.
+func (z *Zadd) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 3 {
+		writeChan <- wrongNumberOfArgs("zadd")
+		return
+	}
+	key := args[0]
+	var flags zaddFlags
+	idx := 1
+parseFlags:
+	for ; idx < len(args); idx++ {
+		switch strings.ToLower(args[idx]) {
+		case "nx":
+			flags.nx = true
+		case "xx":
+			flags.xx = true
+		case "gt":
+			flags.gt = true
+		case "lt":
+			flags.lt = true
+		case "ch":
+			flags.ch = true
+		case "incr":
+			flags.incr = true
+		default:
+			break parseFlags
+		}
+	}
+	pairs := args[idx:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		writeChan <- protocol.ToError(syntaxError)
+		return
+	}
+	if flags.nx && flags.xx {
+		writeChan <- protocol.ToError("ERR XX and NX options at the same time are not compatible")
+		return
+	}
+	if (flags.gt && flags.nx) || (flags.lt && flags.nx) || (flags.gt && flags.lt) {
+		writeChan <- protocol.ToError("ERR GT, LT, and/or NX options at the same time are not compatible")
+		return
+	}
+	if flags.incr && len(pairs) > 2 {
+		writeChan <- protocol.ToError("ERR INCR option supports a single increment-element pair")
+		return
+	}
+	scores := make([]float64, len(pairs)/2)
+	for i := range scores {
+		score, err := parseScore(pairs[2*i])
+		if err != nil {
+			writeChan <- protocol.ToError(err.Error())
+			return
+		}
+		scores[i] = score
+	}
+	zset, err := lookupSortedSetWrite(ctx, key)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if zset == nil {
+		if flags.xx {
+			if flags.incr {
+				writeChan <- protocol.NullBulkString()
+			} else {
+				writeChan <- protocol.ToRespInt(0)
+			}
+			return
+		}
+		zset, _ = lookupOrCreateSortedSet(ctx, key)
+	}
+
+	added, updated := 0, 0
+	var incrResult float64
+	incrApplied := false
+	for i, score := range scores {
+		member := pairs[2*i+1]
+		current, exists := zset.Score(member)
+		if (exists && flags.nx) || (!exists && flags.xx) {
+			continue
+		}
+		newScore := score
+		if flags.incr && exists {
+			newScore = current + score
+			if math.IsNaN(newScore) {
+				writeChan <- protocol.ToError("ERR resulting score is not a number (NaN)")
+				deleteIfEmpty(ctx, key, zset.Len())
+				return
+			}
+		}
+		if exists && ((flags.gt && newScore <= current) || (flags.lt && newScore >= current)) {
+			continue
+		}
+		if !exists {
+			added++
+		} else if newScore != current {
+			updated++
+		}
+		zset.Add(member, newScore)
+		incrResult, incrApplied = newScore, true
+	}
+	deleteIfEmpty(ctx, key, zset.Len())
+	ctx.AddDirty(added + updated)
+	if flags.incr {
+		if !incrApplied {
+			writeChan <- protocol.NullBulkString()
+			return
+		}
+		writeChan <- protocol.ToBulkDouble(incrResult)
+		return
+	}
+	if flags.ch {
+		writeChan <- protocol.ToRespInt(added + updated)
+		return
+	}
+	writeChan <- protocol.ToRespInt(added)
+}
+
+func (z *Zadd) CanPropogateCommand(args []string) bool {
+	return true
+}
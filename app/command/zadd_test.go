@@ -0,0 +1,138 @@
+package command
+
+import "testing"
+
+func TestZadd(t *testing.T) {
+	tests := []struct {
+		cmd      []string
+		expected string
+		// remaining is what ZRANGE z 0 -1 WITHSCORES returns afterwards.
+		remaining string
+	}{
+		{[]string{"ZADD", "z", "3", "c", "1", "a"}, ":1\r\n", array("a", "1", "b", "2", "c", "3")},
+		{[]string{"ZADD", "z", "CH", "5", "a", "3", "c", "2", "b"}, ":2\r\n", array("b", "2", "c", "3", "a", "5")},
+		{[]string{"ZADD", "z", "NX", "5", "a", "3", "c"}, ":1\r\n", array("a", "1", "b", "2", "c", "3")},
+		{[]string{"ZADD", "z", "XX", "5", "a", "3", "c"}, ":0\r\n", array("b", "2", "a", "5")},
+		{[]string{"ZADD", "z", "XX", "CH", "5", "a", "3", "c"}, ":1\r\n", array("b", "2", "a", "5")},
+		// GT and LT only hold back updates, new members are still added.
+		{[]string{"ZADD", "z", "GT", "CH", "0", "a", "5", "b", "3", "c"}, ":2\r\n", array("a", "1", "c", "3", "b", "5")},
+		{[]string{"ZADD", "z", "lt", "ch", "0", "a", "5", "b"}, ":1\r\n", array("a", "0", "b", "2")},
+		{[]string{"ZADD", "z", "XX", "GT", "0", "a", "5", "b", "3", "c"}, ":0\r\n", array("a", "1", "b", "5")},
+		{[]string{"ZADD", "z", "INCR", "2.5", "a"}, "$3\r\n3.5\r\n", array("b", "2", "a", "3.5")},
+		{[]string{"ZADD", "z", "INCR", "-1", "c"}, "$2\r\n-1\r\n", array("c", "-1", "a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "NX", "INCR", "1", "a"}, "$-1\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "XX", "INCR", "1", "c"}, "$-1\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "GT", "INCR", "-1", "b"}, "$-1\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "LT", "INCR", "-1", "b"}, "$1\r\n1\r\n", array("a", "1", "b", "1")},
+		{[]string{"ZADD", "missing", "XX", "1", "a"}, ":0\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "missing", "XX", "INCR", "1", "a"}, "$-1\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "NX", "XX", "1", "a"}, "-ERR XX and NX options at the same time are not compatible\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "NX", "GT", "1", "a"}, "-ERR GT, LT, and/or NX options at the same time are not compatible\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "GT", "LT", "1", "a"}, "-ERR GT, LT, and/or NX options at the same time are not compatible\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "INCR", "1", "a", "2", "b"}, "-ERR INCR option supports a single increment-element pair\r\n", array("a", "1", "b", "2")},
+		// A bad score rejects the whole command.
+		{[]string{"ZADD", "z", "5", "a", "x", "b"}, "-" + notFloatError + "\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "nan", "a"}, "-" + notFloatError + "\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "1", "a", "2"}, "-" + syntaxError + "\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "1"}, "-ERR wrong number of arguments for 'zadd' command\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "z", "NX", "CH", "1"}, "-" + syntaxError + "\r\n", array("a", "1", "b", "2")},
+		{[]string{"ZADD", "str", "1", "a"}, "-" + wrongTypeError + "\r\n", array("a", "1", "b", "2")},
+	}
+
+	for _, tt := range tests {
+		ctx := newTestContext()
+		run(t, ctx, "ZADD", "z", "1", "a", "2", "b")
+		run(t, ctx, "SET", "str", "v")
+		if got := run(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+		if got := run(t, ctx, "ZRANGE", "z", "0", "-1", "WITHSCORES"); got != tt.remaining {
+			t.Errorf("%v: expected %q left; got %q", tt.cmd, tt.remaining, got)
+		}
+		if _, ok := ctx.Store[0]["missing"]; ok {
+			t.Errorf("%v: expected no sorted set to be created without a member", tt.cmd)
+		}
+	}
+}
+
+func TestZincrby(t *testing.T) {
+	ctx := newTestContext()
+	run(t, ctx, "ZADD", "z", "1", "a", "inf", "b")
+	run(t, ctx, "SET", "str", "v")
+	tests := []struct {
+		cmd      []string
+		expected string
+	}{
+		{[]string{"ZINCRBY", "z", "2.5", "a"}, "$3\r\n3.5\r\n"},
+		{[]string{"ZINCRBY", "z", "-4", "a"}, "$4\r\n-0.5\r\n"},
+		{[]string{"ZINCRBY", "z", "5", "c"}, "$1\r\n5\r\n"},
+		{[]string{"ZINCRBY", "new", "1e3", "a"}, "$4\r\n1000\r\n"},
+		{[]string{"ZRANGE", "z", "0", "-1", "WITHSCORES"}, array("a", "-0.5", "c", "5", "b", "inf")},
+		{[]string{"ZINCRBY", "z", "-inf", "b"}, "-ERR resulting score is not a number (NaN)\r\n"},
+		{[]string{"ZINCRBY", "z", "x", "a"}, "-" + notFloatError + "\r\n"},
+		{[]string{"ZINCRBY", "z", "nan", "a"}, "-" + notFloatError + "\r\n"},
+		{[]string{"ZINCRBY", "str", "1", "a"}, "-" + wrongTypeError + "\r\n"},
+		{[]string{"ZINCRBY", "z", "1"}, "-ERR wrong number of arguments for 'zincrby' command\r\n"},
+		// A result that is not a number leaves the score as it was.
+		{[]string{"ZADD", "inf", "inf", "a"}, ":1\r\n"},
+		{[]string{"ZINCRBY", "inf", "-inf", "a"}, "-ERR resulting score is not a number (NaN)\r\n"},
+		{[]string{"ZSCORE", "inf", "a"}, "$3\r\ninf\r\n"},
+		{[]string{"ZINCRBY", "nokey", "-inf", "a"}, "$4\r\n-inf\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+	}
+}
+
+func TestZmscore(t *testing.T) {
+	ctx := newTestContext()
+	run(t, ctx, "ZADD", "z", "1.5", "a", "-inf", "b")
+	run(t, ctx, "SET", "str", "v")
+	tests := []struct {
+		cmd      []string
+		expected string
+	}{
+		{[]string{"ZMSCORE", "z", "a", "x", "b"}, "*3\r\n$3\r\n1.5\r\n$-1\r\n$4\r\n-inf\r\n"},
+		{[]string{"ZMSCORE", "z", "a", "a"}, "*2\r\n$3\r\n1.5\r\n$3\r\n1.5\r\n"},
+		{[]string{"ZMSCORE", "missing", "a", "b"}, "*2\r\n$-1\r\n$-1\r\n"},
+		{[]string{"ZMSCORE", "str", "a"}, "-" + wrongTypeError + "\r\n"},
+		{[]string{"ZMSCORE", "z"}, "-ERR wrong number of arguments for 'zmscore' command\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+	}
+}
+
+func TestZrank(t *testing.T) {
+	ctx := newTestContext()
+	run(t, ctx, "ZADD", "z", "1", "a", "2", "b", "2.5", "c")
+	run(t, ctx, "SET", "str", "v")
+	tests := []struct {
+		cmd      []string
+		expected string
+	}{
+		{[]string{"ZRANK", "z", "a"}, ":0\r\n"},
+		{[]string{"ZRANK", "z", "c"}, ":2\r\n"},
+		{[]string{"ZREVRANK", "z", "c"}, ":0\r\n"},
+		{[]string{"ZRANK", "z", "b", "WITHSCORE"}, "*2\r\n:1\r\n$1\r\n2\r\n"},
+		{[]string{"ZREVRANK", "z", "a", "withscore"}, "*2\r\n:2\r\n$1\r\n1\r\n"},
+		{[]string{"ZREVRANK", "z", "c", "WITHSCORE"}, "*2\r\n:0\r\n$3\r\n2.5\r\n"},
+		{[]string{"ZRANK", "z", "x"}, "$-1\r\n"},
+		{[]string{"ZRANK", "z", "x", "WITHSCORE"}, "*-1\r\n"},
+		{[]string{"ZREVRANK", "missing", "a"}, "$-1\r\n"},
+		{[]string{"ZREVRANK", "missing", "a", "WITHSCORE"}, "*-1\r\n"},
+		{[]string{"ZRANK", "z", "a", "WITHSCORES"}, "-" + syntaxError + "\r\n"},
+		{[]string{"ZRANK", "str", "a"}, "-" + wrongTypeError + "\r\n"},
+		{[]string{"ZRANK", "z"}, "-ERR wrong number of arguments for 'zrank' command\r\n"},
+		{[]string{"ZREVRANK", "z", "a", "WITHSCORE", "x"}, "-ERR wrong number of arguments for 'zrevrank' command\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zcard struct{}
+
+func (z *Zcard) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 1 {
+		writeChan <- wrongNumberOfArgs("zcard")
+		return
+	}
+	zset, err := lookupSortedSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if zset == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	writeChan <- protocol.ToRespInt(zset.Len())
+}
+
+func (z *Zcard) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -0,0 +1,35 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Zcount implements ZCOUNT and ZLEXCOUNT, which both answer from two rank
+// lookups instead of walking the range.
+type Zcount struct {
+	name      string
+	rangeType zrangeType
+}
+
+func (z *Zcount) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 3 {
+		writeChan <- wrongNumberOfArgs(z.name)
+		return
+	}
+	zset, err := lookupSortedSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	start, end, err := rankRange(zset, z.rangeType, false, args[1], args[2])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	writeChan <- protocol.ToRespInt(end - start)
+}
+
+func (z *Zcount) CanPropogateCommand(args []string) bool {
+	return false
+}
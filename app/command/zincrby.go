@@ -0,0 +1,42 @@
+package command
+
+import (
+	"math"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zincrby struct{}
+
+func (z *Zincrby) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 3 {
+		writeChan <- wrongNumberOfArgs("zincrby")
+		return
+	}
+	key, member := args[0], args[2]
+	increment, err := parseScore(args[1])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	zset, err := lookupOrCreateSortedSet(ctx, key)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	current, _ := zset.Score(member)
+	newScore := current + increment
+	if math.IsNaN(newScore) {
+		deleteIfEmpty(ctx, key, zset.Len())
+		writeChan <- protocol.ToError("ERR resulting score is not a number (NaN)")
+		return
+	}
+	zset.Add(member, newScore)
+	ctx.AddDirty(1)
+	writeChan <- protocol.ToBulkDouble(newScore)
+}
+
+func (z *Zincrby) CanPropogateCommand(args []string) bool {
+	return true
+}
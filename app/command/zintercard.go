@@ -0,0 +1,32 @@
+package command
+
+import (
+	"slices"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zintercard struct{}
+
+func (z *Zintercard) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("zintercard")
+		return
+	}
+	numKeys, limit, err := parseIntercardArgs(args)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	inputs, err := lookupZsetOpInputs(ctx, args[1:1+numKeys], slices.Repeat([]float64{1}, numKeys))
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	writeChan <- protocol.ToRespInt(len(zsetInter(inputs, ZSET_AGGREGATE_SUM, limit)))
+}
+
+func (z *Zintercard) CanPropogateCommand(args []string) bool {
+	return false
+}
@@ -0,0 +1,41 @@
+package command
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zmpop struct{}
+
+// Handle propagates the pop as ZPOPMIN or ZPOPMAX on the key that was popped
+// from, so replicas do not have to repeat the search through the keys.
+func (z *Zmpop) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 3 {
+		writeChan <- wrongNumberOfArgs("zmpop")
+		return
+	}
+	keys, fromMax, count, err := zmpopArgs(args)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	key, popped, err := zmpopFirst(ctx, keys, fromMax, count)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if popped == nil {
+		ctx.RewritePropagation()
+		writeChan <- protocol.NullArray()
+		return
+	}
+	ctx.AddDirty(len(popped))
+	ctx.RewritePropagation([]string{popCommandName(fromMax), key, strconv.Itoa(len(popped))})
+	writeChan <- zmpopResp(key, popped)
+}
+
+func (z *Zmpop) CanPropogateCommand(args []string) bool {
+	return true
+}
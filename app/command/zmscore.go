@@ -0,0 +1,38 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zmscore struct{}
+
+func (z *Zmscore) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("zmscore")
+		return
+	}
+	zset, err := lookupSortedSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	ret := protocol.ToArrayHeader(len(args) - 1)
+	for _, member := range args[1:] {
+		if zset == nil {
+			ret = append(ret, protocol.NullBulkString()...)
+			continue
+		}
+		score, ok := zset.Score(member)
+		if !ok {
+			ret = append(ret, protocol.NullBulkString()...)
+			continue
+		}
+		ret = append(ret, protocol.ToBulkDouble(score)...)
+	}
+	writeChan <- ret
+}
+
+func (z *Zmscore) CanPropogateCommand(args []string) bool {
+	return false
+}
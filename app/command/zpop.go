@@ -0,0 +1,51 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Zpop implements ZPOPMIN and ZPOPMAX.
+type Zpop struct {
+	fromMax bool
+}
+
+func (z *Zpop) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 1 && len(args) != 2 {
+		writeChan <- wrongNumberOfArgs(strings.ToLower(popCommandName(z.fromMax)))
+		return
+	}
+	count := 1
+	if len(args) == 2 {
+		c, err := strconv.Atoi(args[1])
+		if err != nil {
+			writeChan <- protocol.ToError(notIntegerError)
+			return
+		}
+		if c < 0 {
+			writeChan <- protocol.ToError("ERR value is out of range, must be positive")
+			return
+		}
+		count = c
+	}
+	zset, err := lookupSortedSetWrite(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if zset == nil || count == 0 {
+		writeChan <- protocol.ToArrayHeader(0)
+		return
+	}
+	popped := zset.Pop(count, z.fromMax)
+	deleteIfEmpty(ctx, args[0], zset.Len())
+	ctx.AddDirty(len(popped))
+	writeChan <- sortedSetMembersToResp(popped, true)
+}
+
+func (z *Zpop) CanPropogateCommand(args []string) bool {
+	return true
+}
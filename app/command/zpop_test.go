@@ -0,0 +1,178 @@
+package command
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestZpop(t *testing.T) {
+	tests := []struct {
+		cmd      []string
+		expected string
+		// propagated is what reaches the append-only file and replicas.
+		propagated []string
+		// remaining is what ZRANGE z 0 -1 returns afterwards.
+		remaining string
+	}{
+		{[]string{"ZPOPMIN", "z"}, array("a", "1"), []string{"ZPOPMIN z"}, array("b", "c")},
+		{[]string{"ZPOPMAX", "z", "2"}, array("c", "3", "b", "2"), []string{"ZPOPMAX z 2"}, array("a")},
+		{[]string{"ZPOPMIN", "z", "10"}, array("a", "1", "b", "2", "c", "3"), []string{"ZPOPMIN z 10"}, array()},
+		{[]string{"ZPOPMIN", "z", "0"}, "*0\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZPOPMAX", "missing"}, "*0\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZPOPMIN", "z", "-1"}, "-ERR value is out of range, must be positive\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZPOPMIN", "z", "x"}, "-" + notIntegerError + "\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZPOPMIN", "str"}, "-" + wrongTypeError + "\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZPOPMAX", "z", "1", "2"}, "-ERR wrong number of arguments for 'zpopmax' command\r\n", []string{}, array("a", "b", "c")},
+		// ZMPOP pops from the first non-empty key and propagates as a pop
+		// on that key.
+		{[]string{"ZMPOP", "2", "missing", "z", "MIN"}, "*2\r\n$1\r\nz\r\n*1\r\n" + array("a", "1"), []string{"ZPOPMIN z 1"}, array("b", "c")},
+		{[]string{"ZMPOP", "1", "z", "max", "COUNT", "2"}, "*2\r\n$1\r\nz\r\n*2\r\n" + array("c", "3") + array("b", "2"), []string{"ZPOPMAX z 2"}, array("a")},
+		{[]string{"ZMPOP", "1", "z", "MIN", "COUNT", "5"}, "*2\r\n$1\r\nz\r\n*3\r\n" + array("a", "1") + array("b", "2") + array("c", "3"), []string{"ZPOPMIN z 3"}, array()},
+		{[]string{"ZMPOP", "1", "missing", "MIN"}, "*-1\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZMPOP", "0", "z", "MIN"}, "-ERR numkeys should be greater than 0\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZMPOP", "2", "z", "MIN"}, "-" + syntaxError + "\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZMPOP", "1", "z", "AVG"}, "-" + syntaxError + "\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZMPOP", "1", "z", "MIN", "COUNT", "0"}, "-ERR count should be greater than 0\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"ZMPOP", "1", "str", "MIN"}, "-" + wrongTypeError + "\r\n", []string{}, array("a", "b", "c")},
+		// The blocking variants that can pop straight away propagate as the
+		// plain pops.
+		{[]string{"BZPOPMIN", "missing", "z", "0"}, array("z", "a", "1"), []string{"ZPOPMIN z"}, array("b", "c")},
+		{[]string{"BZPOPMAX", "z", "0"}, array("z", "c", "3"), []string{"ZPOPMAX z"}, array("a", "b")},
+		{[]string{"BZMPOP", "0", "1", "z", "MAX", "COUNT", "2"}, "*2\r\n$1\r\nz\r\n*2\r\n" + array("c", "3") + array("b", "2"), []string{"ZPOPMAX z 2"}, array("a")},
+		{[]string{"BZPOPMIN", "z", "x"}, "-ERR timeout is not a float or out of range\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"BZPOPMIN", "z", "-1"}, "-ERR timeout is negative\r\n", []string{}, array("a", "b", "c")},
+		{[]string{"BZMPOP", "0", "1", "z", "MIN", "COUNT", "-1"}, "-ERR count should be greater than 0\r\n", []string{}, array("a", "b", "c")},
+	}
+
+	for _, tt := range tests {
+		ctx, propagated := newPropagationTestContext(t)
+		run(t, ctx, "ZADD", "z", "1", "a", "2", "b", "3", "c")
+		run(t, ctx, "SET", "str", "v")
+		if got := runPropagated(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+		if got := propagated(); !slices.Equal(got, tt.propagated) {
+			t.Errorf("%v: expected %q to be propagated; got %q", tt.cmd, tt.propagated, got)
+		}
+		if got := run(t, ctx, "ZRANGE", "z", "0", "-1"); got != tt.remaining {
+			t.Errorf("%v: expected %q left; got %q", tt.cmd, tt.remaining, got)
+		}
+	}
+}
+
+// TestBzpopBlocked checks that a blocked pop is served by the next write to
+// one of its keys, and propagated as the plain pop it makes.
+func TestBzpopBlocked(t *testing.T) {
+	tests := []struct {
+		cmd        []string
+		expected   string
+		propagated []string
+	}{
+		{[]string{"BZPOPMIN", "a", "z", "0"}, array("z", "x", "1"), []string{"ZADD z 1 x 2 y", "ZPOPMIN z"}},
+		{[]string{"BZPOPMAX", "z", "0"}, array("z", "y", "2"), []string{"ZADD z 1 x 2 y", "ZPOPMAX z"}},
+		{[]string{"BZMPOP", "0", "2", "a", "z", "MIN", "COUNT", "5"}, "*2\r\n$1\r\nz\r\n*2\r\n" + array("x", "1") + array("y", "2"),
+			[]string{"ZADD z 1 x 2 y", "ZPOPMIN z 2"}},
+	}
+
+	for _, tt := range tests {
+		ctx, propagated := newPropagationTestContext(t)
+		if got := runPropagated(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != "" {
+			t.Errorf("%v: expected to block; got %q", tt.cmd, got)
+		}
+		runPropagated(t, ctx, "ZADD", "z", "1", "x", "2", "y")
+		served := ctx.Blocking.HandleReadyKeys()
+		if len(served) != 1 || string(served[0].Reply) != tt.expected {
+			t.Errorf("%v: expected to be served %q; got %v", tt.cmd, tt.expected, served)
+		}
+		if got := propagated(); !slices.Equal(got, tt.propagated) {
+			t.Errorf("%v: expected %q to be propagated; got %q", tt.cmd, tt.propagated, got)
+		}
+	}
+}
+
+func TestBzpopTimeout(t *testing.T) {
+	for _, cmd := range [][]string{{"BZPOPMIN", "z", "0.01"}, {"BZMPOP", "0.01", "1", "z", "MAX"}} {
+		ctx := newTestContext()
+		read := newTestClient(t, ctx)
+		run(t, ctx, cmd[0], cmd[1:]...)
+		if got := expireBlocked(t, ctx, read); got != "*-1\r\n" {
+			t.Errorf("%v: expected a null array on timeout; got %q", cmd, got)
+		}
+		run(t, ctx, "ZADD", "z", "1", "x")
+		if served := ctx.Blocking.HandleReadyKeys(); len(served) != 0 {
+			t.Errorf("%v: expected no client left to serve after the timeout; got %v", cmd, served)
+		}
+	}
+}
+
+func TestZrandmember(t *testing.T) {
+	ctx := newTestContext()
+	run(t, ctx, "ZADD", "z", "1", "a", "2", "b", "3", "c")
+	run(t, ctx, "SET", "str", "v")
+	tests := []struct {
+		cmd      []string
+		expected string
+	}{
+		{[]string{"ZRANDMEMBER", "z", "5", "WITHSCORES"}, array("a", "1", "b", "2", "c", "3")},
+		{[]string{"ZRANDMEMBER", "z", "3"}, array("a", "b", "c")},
+		{[]string{"ZRANDMEMBER", "z", "0"}, "*0\r\n"},
+		{[]string{"ZRANDMEMBER", "missing"}, "$-1\r\n"},
+		{[]string{"ZRANDMEMBER", "missing", "3"}, "*0\r\n"},
+		{[]string{"ZRANDMEMBER", "z", "x"}, "-" + notIntegerError + "\r\n"},
+		{[]string{"ZRANDMEMBER", "z", "1", "SCORES"}, "-" + syntaxError + "\r\n"},
+		{[]string{"ZRANDMEMBER", "z", "-9223372036854775807", "WITHSCORES"}, "-ERR value is out of range\r\n"},
+		{[]string{"ZRANDMEMBER", "str"}, "-" + wrongTypeError + "\r\n"},
+		{[]string{"ZRANDMEMBER"}, "-ERR wrong number of arguments for 'zrandmember' command\r\n"},
+	}
+	for _, tt := range tests {
+		if got := run(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+	}
+
+	members := map[string]string{"a": "1", "b": "2", "c": "3"}
+	// A single member is returned as a bulk string.
+	if got := run(t, ctx, "ZRANDMEMBER", "z"); got != "$1\r\na\r\n" && got != "$1\r\nb\r\n" && got != "$1\r\nc\r\n" {
+		t.Errorf("Expected a member of z; got %q", got)
+	}
+	// A positive count picks distinct members, a negative one may repeat them.
+	for _, tt := range []struct {
+		count    string
+		length   int
+		distinct bool
+	}{{"2", 2, true}, {"-10", 10, false}} {
+		got := run(t, ctx, "ZRANDMEMBER", "z", tt.count, "WITHSCORES")
+		seen := map[string]bool{}
+		n := 0
+		for _, pair := range splitArrayReply(t, got, 2*tt.length) {
+			if members[pair[0]] != pair[1] || (tt.distinct && seen[pair[0]]) {
+				t.Errorf("ZRANDMEMBER z %s: expected members of z with their scores; got %q", tt.count, got)
+			}
+			seen[pair[0]] = true
+			n++
+		}
+		if n != tt.length {
+			t.Errorf("ZRANDMEMBER z %s: expected %d members; got %d", tt.count, tt.length, n)
+		}
+	}
+}
+
+// splitArrayReply decodes an array reply of length bulk strings into
+// member and score pairs.
+func splitArrayReply(t *testing.T, reply string, length int) [][2]string {
+	values := []string{}
+	lines := strings.Split(strings.TrimSuffix(reply, "\r\n"), "\r\n")
+	if len(lines) != 1+2*length || lines[0] != fmt.Sprintf("*%d", length) {
+		t.Fatalf("Expected an array of %d bulk strings; got %q", length, reply)
+	}
+	for i := 2; i < len(lines); i += 2 {
+		values = append(values, lines[i])
+	}
+	pairs := [][2]string{}
+	for i := 0; i+1 < len(values); i += 2 {
+		pairs = append(pairs, [2]string{values[i], values[i+1]})
+	}
+	return pairs
+}
@@ -0,0 +1,64 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zrandmember struct{}
+
+// Handle returns distinct members for a positive count and allows the same
+// member to be returned several times for a negative one.
+func (z *Zrandmember) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 1 || len(args) > 3 {
+		writeChan <- wrongNumberOfArgs("zrandmember")
+		return
+	}
+	withScores := false
+	if len(args) == 3 {
+		if strings.ToLower(args[2]) != "withscores" {
+			writeChan <- protocol.ToError(syntaxError)
+			return
+		}
+		withScores = true
+	}
+	zset, err := lookupSortedSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if len(args) == 1 {
+		if zset == nil {
+			writeChan <- protocol.NullBulkString()
+			return
+		}
+		writeChan <- protocol.ToBulkString(zset.RandomMembers(1)[0].Member)
+		return
+	}
+	count, err := strconv.Atoi(args[1])
+	if err != nil {
+		writeChan <- protocol.ToError(notIntegerError)
+		return
+	}
+	if err := entry.CheckRandomCount(count, withScores); err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if zset == nil || count == 0 {
+		writeChan <- protocol.ToArrayHeader(0)
+		return
+	}
+	if count > 0 {
+		writeChan <- sortedSetMembersToResp(zset.RandomMembers(count), withScores)
+		return
+	}
+	writeChan <- sortedSetMembersToResp(zset.RandomMembersWithRepetition(-count), withScores)
+}
+
+func (z *Zrandmember) CanPropogateCommand(args []string) bool {
+	return false
+}
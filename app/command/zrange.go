@@ -0,0 +1,136 @@
+package command
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Zrange implements ZRANGE and ZRANGESTORE, whose range type and direction
+// come from their BYSCORE, BYLEX and REV options, as well as the legacy
+// commands that fix both in their name.
+type Zrange struct {
+	name      string
+	store     bool
+	rangeType zrangeType
+	reverse   bool
+}
+
+func (z *Zrange) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	argStart := 0
+	if z.store {
+		argStart = 1
+	}
+	if len(args) < argStart+3 {
+		writeChan <- wrongNumberOfArgs(z.name)
+		return
+	}
+	key, minStr, maxStr := args[argStart], args[argStart+1], args[argStart+2]
+	rangeType, reverse := z.rangeType, z.reverse
+	withScores, hasLimit := false, false
+	offset, count := 0, -1
+	opts := args[argStart+3:]
+	for i := 0; i < len(opts); i++ {
+		opt := strings.ToLower(opts[i])
+		switch {
+		case opt == "withscores" && !z.store:
+			withScores = true
+		case opt == "limit" && i+2 < len(opts):
+			var err error
+			if offset, err = strconv.Atoi(opts[i+1]); err != nil {
+				writeChan <- protocol.ToError(notIntegerError)
+				return
+			}
+			if count, err = strconv.Atoi(opts[i+2]); err != nil {
+				writeChan <- protocol.ToError(notIntegerError)
+				return
+			}
+			hasLimit = true
+			i += 2
+		case opt == "byscore" && z.rangeType == ZRANGE_AUTO:
+			rangeType = ZRANGE_SCORE
+		case opt == "bylex" && z.rangeType == ZRANGE_AUTO:
+			rangeType = ZRANGE_LEX
+		case opt == "rev" && z.rangeType == ZRANGE_AUTO:
+			reverse = true
+		default:
+			writeChan <- protocol.ToError(syntaxError)
+			return
+		}
+	}
+	if rangeType == ZRANGE_AUTO {
+		rangeType = ZRANGE_RANK
+	}
+	if hasLimit && rangeType == ZRANGE_RANK {
+		writeChan <- protocol.ToError("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+		return
+	}
+	if withScores && rangeType == ZRANGE_LEX {
+		writeChan <- protocol.ToError("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+		return
+	}
+	if reverse && rangeType != ZRANGE_RANK {
+		minStr, maxStr = maxStr, minStr
+	}
+
+	zset, err := lookupSortedSet(ctx, key)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	start, end, err := rankRange(zset, rangeType, reverse, minStr, maxStr)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if offset < 0 {
+		start = end
+	}
+	var members []entry.SortedSetMember
+	if zset != nil && start < end {
+		members = sliceWithLimit(zset, start, end, offset, count, reverse)
+	}
+
+	if z.store {
+		storeSortedSet(ctx, args[0], members)
+		writeChan <- protocol.ToRespInt(len(members))
+		return
+	}
+	writeChan <- sortedSetMembersToResp(members, withScores)
+}
+
+// sliceWithLimit returns the elements with ranks [start, end), in descending
+// order when reverse is set, after skipping offset of them and keeping at most
+// count, or all of them when count is negative.
+func sliceWithLimit(zset *entry.SortedSet, start int, end int, offset int, count int, reverse bool) []entry.SortedSetMember {
+	start, end = max(start, 0), min(end, zset.Len())
+	if offset >= end-start {
+		return []entry.SortedSetMember{}
+	}
+	// The width left is compared against rather than added to, as offset
+	// and count may be as large as an int goes.
+	if reverse {
+		end -= offset
+		if count >= 0 && count < end-start {
+			start = end - count
+		}
+	} else {
+		start += offset
+		if count >= 0 && count < end-start {
+			end = start + count
+		}
+	}
+	members := zset.Slice(start, end)
+	if reverse {
+		slices.Reverse(members)
+	}
+	return members
+}
+
+func (z *Zrange) CanPropogateCommand(args []string) bool {
+	return z.store
+}
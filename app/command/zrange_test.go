@@ -0,0 +1,78 @@
+package command
+
+import "testing"
+
+func TestZrange(t *testing.T) {
+	const maxInt, minInt = "9223372036854775807", "-9223372036854775808"
+	tests := []struct {
+		cmd      []string
+		expected string
+		// then is run after cmd, and replies thenExpected.
+		then         []string
+		thenExpected string
+	}{
+		{[]string{"ZRANGE", "z", "0", "-1"}, array("a", "b", "c", "d", "e"), nil, ""},
+		{[]string{"ZRANGE", "z", "1", "2", "WITHSCORES"}, array("b", "2", "c", "3"), nil, ""},
+		{[]string{"ZRANGE", "z", "-2", "-1"}, array("d", "e"), nil, ""},
+		{[]string{"ZRANGE", "z", "5", "10"}, array(), nil, ""},
+		{[]string{"ZRANGE", "z", "0", "1", "REV"}, array("e", "d"), nil, ""},
+		{[]string{"ZRANGE", "missing", "0", "-1"}, array(), nil, ""},
+		{[]string{"ZRANGE", "z", "(1", "4", "BYSCORE"}, array("b", "c", "d"), nil, ""},
+		{[]string{"ZRANGE", "z", "4", "(2", "BYSCORE", "REV"}, array("d", "c"), nil, ""},
+		{[]string{"ZRANGE", "z", "-inf", "+inf", "BYSCORE", "LIMIT", "1", "2"}, array("b", "c"), nil, ""},
+		{[]string{"ZRANGE", "z", "-inf", "+inf", "BYSCORE", "LIMIT", "0", "-1"}, array("a", "b", "c", "d", "e"), nil, ""},
+		{[]string{"ZRANGE", "z", "-inf", "+inf", "BYSCORE", "LIMIT", "-1", "5"}, array(), nil, ""},
+		{[]string{"ZRANGE", "z", "-inf", "+inf", "BYSCORE", "LIMIT", "1", maxInt}, array("b", "c", "d", "e"), nil, ""},
+		{[]string{"ZRANGE", "z", "-inf", "+inf", "BYSCORE", "LIMIT", maxInt, maxInt}, array(), nil, ""},
+		{[]string{"ZRANGE", "z", "-inf", "+inf", "BYSCORE", "LIMIT", "4", minInt}, array("e"), nil, ""},
+		{[]string{"ZRANGE", "z", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", "1", maxInt}, array("d", "c", "b", "a"), nil, ""},
+		{[]string{"ZRANGE", "z", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", maxInt, "1"}, array(), nil, ""},
+		{[]string{"ZRANGE", "z", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", "4", minInt}, array("a"), nil, ""},
+		{[]string{"ZRANGE", "lex", "[b", "(d", "BYLEX"}, array("b", "c"), nil, ""},
+		{[]string{"ZRANGE", "lex", "+", "-", "BYLEX", "REV", "LIMIT", "1", "2"}, array("d", "c"), nil, ""},
+		{[]string{"ZRANGE", "lex", "-", "+", "BYLEX", "LIMIT", "3", maxInt}, array("d", "e"), nil, ""},
+		{[]string{"ZRANGE", "z", "0", "-1", "LIMIT", "0", "1"}, "-ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX\r\n", nil, ""},
+		{[]string{"ZRANGE", "lex", "-", "+", "BYLEX", "WITHSCORES"}, "-ERR syntax error, WITHSCORES not supported in combination with BYLEX\r\n", nil, ""},
+		{[]string{"ZRANGE", "z", "0", "-1", "LIMIT", "0"}, "-" + syntaxError + "\r\n", nil, ""},
+		{[]string{"ZRANGE", "z", "0", "x"}, "-" + notIntegerError + "\r\n", nil, ""},
+		{[]string{"ZRANGE", "z", "(x", "2", "BYSCORE"}, "-ERR min or max is not a float\r\n", nil, ""},
+		{[]string{"ZRANGE", "lex", "a", "[c", "BYLEX"}, "-ERR min or max not valid string range item\r\n", nil, ""},
+		{[]string{"ZRANGE", "str", "0", "-1"}, "-" + wrongTypeError + "\r\n", nil, ""},
+		{[]string{"ZREVRANGE", "z", "0", "1", "WITHSCORES"}, array("e", "5", "d", "4"), nil, ""},
+		{[]string{"ZRANGEBYSCORE", "z", "2", "4", "WITHSCORES"}, array("b", "2", "c", "3", "d", "4"), nil, ""},
+		{[]string{"ZRANGEBYSCORE", "z", "-inf", "+inf", "LIMIT", "3", maxInt}, array("d", "e"), nil, ""},
+		{[]string{"ZREVRANGEBYSCORE", "z", "4", "2", "LIMIT", "0", "2"}, array("d", "c"), nil, ""},
+		{[]string{"ZRANGEBYSCORE", "z", "1", "2", "BYLEX"}, "-" + syntaxError + "\r\n", nil, ""},
+		{[]string{"ZRANGEBYLEX", "lex", "-", "[c"}, array("a", "b", "c"), nil, ""},
+		{[]string{"ZREVRANGEBYLEX", "lex", "[c", "-"}, array("c", "b", "a"), nil, ""},
+		{[]string{"ZRANGESTORE", "dst", "z", "1", "3"}, ":3\r\n", []string{"ZRANGE", "dst", "0", "-1", "WITHSCORES"}, array("b", "2", "c", "3", "d", "4")},
+		{[]string{"ZRANGESTORE", "dst", "z", "+inf", "-inf", "BYSCORE", "REV", "LIMIT", "1", maxInt}, ":4\r\n", []string{"ZRANGE", "dst", "0", "-1"}, array("a", "b", "c", "d")},
+		{[]string{"ZRANGESTORE", "dst", "z", "10", "20", "BYSCORE"}, ":0\r\n", []string{"TYPE", "dst"}, "+none\r\n"},
+		{[]string{"ZRANGESTORE", "dst", "z", "0", "-1", "WITHSCORES"}, "-" + syntaxError + "\r\n", nil, ""},
+		{[]string{"ZCOUNT", "z", "(1", "3"}, ":2\r\n", nil, ""},
+		{[]string{"ZLEXCOUNT", "lex", "(a", "+"}, ":4\r\n", nil, ""},
+		{[]string{"ZREMRANGEBYRANK", "z", "0", "1"}, ":2\r\n", []string{"ZRANGE", "z", "0", "-1"}, array("c", "d", "e")},
+		{[]string{"ZREMRANGEBYRANK", "z", "-1", "-1"}, ":1\r\n", []string{"ZRANGE", "z", "0", "-1"}, array("a", "b", "c", "d")},
+		{[]string{"ZREMRANGEBYSCORE", "z", "(1", "3"}, ":2\r\n", []string{"ZRANGE", "z", "0", "-1"}, array("a", "d", "e")},
+		{[]string{"ZREMRANGEBYLEX", "lex", "[b", "(d"}, ":2\r\n", []string{"ZRANGE", "lex", "0", "-1"}, array("a", "d", "e")},
+		{[]string{"ZREMRANGEBYRANK", "z", "0", "-1"}, ":5\r\n", []string{"TYPE", "z"}, "+none\r\n"},
+		{[]string{"ZREMRANGEBYSCORE", "missing", "0", "1"}, ":0\r\n", nil, ""},
+	}
+
+	for _, tt := range tests {
+		ctx := newTestContext()
+		run(t, ctx, "ZADD", "z", "1", "a", "2", "b", "3", "c", "4", "d", "5", "e")
+		run(t, ctx, "ZADD", "lex", "0", "a", "0", "b", "0", "c", "0", "d", "0", "e")
+		run(t, ctx, "ZADD", "dst", "9", "old")
+		run(t, ctx, "SET", "str", "v")
+		if got := run(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+		if tt.then == nil {
+			continue
+		}
+		if got := run(t, ctx, tt.then[0], tt.then[1:]...); got != tt.thenExpected {
+			t.Errorf("%v then %v: expected %q; got %q", tt.cmd, tt.then, tt.thenExpected, got)
+		}
+	}
+}
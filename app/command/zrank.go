@@ -0,0 +1,62 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zrank struct {
+	reverse bool
+}
+
+func (z *Zrank) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	name := "zrank"
+	if z.reverse {
+		name = "zrevrank"
+	}
+	if len(args) != 2 && len(args) != 3 {
+		writeChan <- wrongNumberOfArgs(name)
+		return
+	}
+	withScore := false
+	if len(args) == 3 {
+		if strings.ToLower(args[2]) != "withscore" {
+			writeChan <- protocol.ToError(syntaxError)
+			return
+		}
+		withScore = true
+	}
+	zset, err := lookupSortedSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	var rank int
+	found := false
+	if zset != nil {
+		rank, found = zset.Rank(args[1], z.reverse)
+	}
+	if !found {
+		if withScore {
+			writeChan <- protocol.NullArray()
+		} else {
+			writeChan <- protocol.NullBulkString()
+		}
+		return
+	}
+	if !withScore {
+		writeChan <- protocol.ToRespInt(rank)
+		return
+	}
+	score, _ := zset.Score(args[1])
+	ret := protocol.ToArrayHeader(2)
+	ret = append(ret, protocol.ToRespInt(rank)...)
+	ret = append(ret, protocol.ToBulkDouble(score)...)
+	writeChan <- ret
+}
+
+func (z *Zrank) CanPropogateCommand(args []string) bool {
+	return false
+}
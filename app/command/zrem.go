@@ -0,0 +1,37 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zrem struct{}
+
+func (z *Zrem) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) < 2 {
+		writeChan <- wrongNumberOfArgs("zrem")
+		return
+	}
+	zset, err := lookupSortedSetWrite(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if zset == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	removed := 0
+	for _, member := range args[1:] {
+		if zset.Remove(member) {
+			removed++
+		}
+	}
+	deleteIfEmpty(ctx, args[0], zset.Len())
+	ctx.AddDirty(removed)
+	writeChan <- protocol.ToRespInt(removed)
+}
+
+func (z *Zrem) CanPropogateCommand(args []string) bool {
+	return true
+}
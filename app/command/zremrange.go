@@ -0,0 +1,41 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// Zremrange implements ZREMRANGEBYRANK, ZREMRANGEBYSCORE and ZREMRANGEBYLEX.
+type Zremrange struct {
+	name      string
+	rangeType zrangeType
+}
+
+func (z *Zremrange) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 3 {
+		writeChan <- wrongNumberOfArgs(z.name)
+		return
+	}
+	zset, err := lookupSortedSetWrite(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	start, end, err := rankRange(zset, z.rangeType, false, args[1], args[2])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if zset == nil {
+		writeChan <- protocol.ToRespInt(0)
+		return
+	}
+	removed := zset.RemoveRankRange(start, end)
+	deleteIfEmpty(ctx, args[0], zset.Len())
+	ctx.AddDirty(removed)
+	writeChan <- protocol.ToRespInt(removed)
+}
+
+func (z *Zremrange) CanPropogateCommand(args []string) bool {
+	return true
+}
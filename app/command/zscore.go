@@ -0,0 +1,34 @@
+package command
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type Zscore struct{}
+
+func (z *Zscore) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	if len(args) != 2 {
+		writeChan <- wrongNumberOfArgs("zscore")
+		return
+	}
+	zset, err := lookupSortedSet(ctx, args[0])
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+	if zset == nil {
+		writeChan <- protocol.NullBulkString()
+		return
+	}
+	score, ok := zset.Score(args[1])
+	if !ok {
+		writeChan <- protocol.NullBulkString()
+		return
+	}
+	writeChan <- protocol.ToBulkDouble(score)
+}
+
+func (z *Zscore) CanPropogateCommand(args []string) bool {
+	return false
+}
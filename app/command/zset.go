@@ -0,0 +1,274 @@
+package command
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+const notFloatError string = "ERR value is not a valid float"
+
+var errNotFloat = errors.New(notFloatError)
+
+// parseScore parses a score the way Redis' strtod-based parsing does,
+// accepting "inf", "+inf" and "-inf" but rejecting NaN and values that
+// overflow a double.
+func parseScore(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, errNotFloat
+	}
+	return f, nil
+}
+
+type zrangeType int
+
+const (
+	ZRANGE_AUTO zrangeType = iota
+	ZRANGE_RANK
+	ZRANGE_SCORE
+	ZRANGE_LEX
+)
+
+const scoreRangeError string = "ERR min or max is not a float"
+const lexRangeError string = "ERR min or max not valid string range item"
+
+func parseScoreBound(s string) (float64, bool, error) {
+	exclusive := false
+	if strings.HasPrefix(s, "(") {
+		exclusive = true
+		s = s[1:]
+	}
+	f, err := parseScore(s)
+	if err != nil {
+		return 0, false, errors.New(scoreRangeError)
+	}
+	return f, exclusive, nil
+}
+
+func parseScoreRange(minStr string, maxStr string) (entry.ScoreRange, error) {
+	var r entry.ScoreRange
+	var err error
+	if r.Min, r.MinExclusive, err = parseScoreBound(minStr); err != nil {
+		return r, err
+	}
+	if r.Max, r.MaxExclusive, err = parseScoreBound(maxStr); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+func parseLexBound(s string) (entry.LexBound, error) {
+	switch {
+	case s == "-":
+		return entry.LexBound{Inf: -1}, nil
+	case s == "+":
+		return entry.LexBound{Inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return entry.LexBound{Value: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return entry.LexBound{Value: s[1:], Exclusive: true}, nil
+	}
+	return entry.LexBound{}, errors.New(lexRangeError)
+}
+
+func parseLexRange(minStr string, maxStr string) (entry.LexRange, error) {
+	var r entry.LexRange
+	var err error
+	if r.Min, err = parseLexBound(minStr); err != nil {
+		return r, err
+	}
+	if r.Max, err = parseLexBound(maxStr); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// normaliseRankRange turns inclusive, possibly negative, start and stop
+// indexes into ranks [start, end) within a set of the given length.
+func normaliseRankRange(startStr string, stopStr string, length int) (int, int, error) {
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, errors.New(notIntegerError)
+	}
+	stop, err := strconv.Atoi(stopStr)
+	if err != nil {
+		return 0, 0, errors.New(notIntegerError)
+	}
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	start = max(start, 0)
+	stop = min(stop, length-1)
+	if start > stop {
+		return 0, 0, nil
+	}
+	return start, stop + 1, nil
+}
+
+// rankRange resolves the range arguments of a ZRANGE style command to the
+// ascending ranks [start, end) they select. For a reversed rank range the
+// indexes count from the highest score; for reversed score and lex ranges the
+// caller passes the arguments as min and max even though the user gave them
+// as max and min.
+func rankRange(zset *entry.SortedSet, t zrangeType, reverse bool, minStr string, maxStr string) (int, int, error) {
+	switch t {
+	case ZRANGE_SCORE:
+		r, err := parseScoreRange(minStr, maxStr)
+		if err != nil {
+			return 0, 0, err
+		}
+		if zset == nil {
+			return 0, 0, nil
+		}
+		start, end := zset.ScoreRankRange(r)
+		return start, end, nil
+	case ZRANGE_LEX:
+		r, err := parseLexRange(minStr, maxStr)
+		if err != nil {
+			return 0, 0, err
+		}
+		if zset == nil {
+			return 0, 0, nil
+		}
+		start, end := zset.LexRankRange(r)
+		return start, end, nil
+	}
+	length := 0
+	if zset != nil {
+		length = zset.Len()
+	}
+	start, end, err := normaliseRankRange(minStr, maxStr, length)
+	if err != nil || !reverse {
+		return start, end, err
+	}
+	return length - end, length - start, nil
+}
+
+// storeSortedSet replaces whatever is at key with a sorted set of members,
+// deleting the key instead when there are no members.
+func storeSortedSet(ctx *event.Context, key string, members []entry.SortedSetMember) {
+	if len(members) == 0 {
+		deleteKey(ctx, key)
+		return
+	}
+	z := newSortedSet(ctx)
+	for _, m := range members {
+		z.Add(m.Member, m.Score)
+	}
+	database(ctx)[key] = z
+	ctx.AddDirty(1)
+	signalKeyAsReady(ctx, key)
+}
+
+func sortedSetMembersToResp(members []entry.SortedSetMember, withScores bool) []byte {
+	if !withScores {
+		ret := protocol.ToArrayHeader(len(members))
+		for _, m := range members {
+			ret = append(ret, protocol.ToBulkString(m.Member)...)
+		}
+		return ret
+	}
+	ret := protocol.ToArrayHeader(2 * len(members))
+	for _, m := range members {
+		ret = append(ret, protocol.ToBulkString(m.Member)...)
+		ret = append(ret, protocol.ToBulkDouble(m.Score)...)
+	}
+	return ret
+}
+
+// parseTimeout parses the timeout of a blocking command, given in seconds
+// with an optional fraction. Zero means block forever.
+func parseTimeout(s string) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errors.New("ERR timeout is not a float or out of range")
+	}
+	if f < 0 {
+		return 0, errors.New("ERR timeout is negative")
+	}
+	return time.Duration(f * float64(time.Second)), nil
+}
+
+func popCommandName(fromMax bool) string {
+	if fromMax {
+		return "ZPOPMAX"
+	}
+	return "ZPOPMIN"
+}
+
+// zmpopArgs parses the "numkeys key [key ...] MIN|MAX [COUNT count]" tail
+// shared by ZMPOP and BZMPOP.
+func zmpopArgs(args []string) ([]string, bool, int, error) {
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, false, 0, errors.New(notIntegerError)
+	}
+	if numKeys <= 0 {
+		return nil, false, 0, errors.New("ERR numkeys should be greater than 0")
+	}
+	if numKeys+1 >= len(args) {
+		return nil, false, 0, errors.New(syntaxError)
+	}
+	keys, rest := args[1:1+numKeys], args[1+numKeys:]
+	var fromMax bool
+	switch strings.ToLower(rest[0]) {
+	case "min":
+	case "max":
+		fromMax = true
+	default:
+		return nil, false, 0, errors.New(syntaxError)
+	}
+	count := 1
+	rest = rest[1:]
+	if len(rest) > 0 {
+		if len(rest) != 2 || strings.ToLower(rest[0]) != "count" {
+			return nil, false, 0, errors.New(syntaxError)
+		}
+		count, err = strconv.Atoi(rest[1])
+		if err != nil || count <= 0 {
+			return nil, false, 0, errors.New("ERR count should be greater than 0")
+		}
+	}
+	return keys, fromMax, count, nil
+}
+
+// zmpopFirst pops up to count elements from the first non-empty sorted set
+// among keys, returning the key it popped from and the popped elements, or
+// nil elements if every set was empty.
+func zmpopFirst(ctx *event.Context, keys []string, fromMax bool, count int) (string, []entry.SortedSetMember, error) {
+	for _, key := range keys {
+		zset, err := lookupSortedSetWrite(ctx, key)
+		if err != nil {
+			return "", nil, err
+		}
+		if zset == nil || zset.Len() == 0 {
+			continue
+		}
+		popped := zset.Pop(count, fromMax)
+		deleteIfEmpty(ctx, key, zset.Len())
+		return key, popped, nil
+	}
+	return "", nil, nil
+}
+
+func zmpopResp(key string, popped []entry.SortedSetMember) []byte {
+	ret := protocol.ToArrayHeader(2)
+	ret = append(ret, protocol.ToBulkString(key)...)
+	ret = append(ret, protocol.ToArrayHeader(len(popped))...)
+	for _, m := range popped {
+		ret = append(ret, protocol.ToArrayHeader(2)...)
+		ret = append(ret, protocol.ToBulkString(m.Member)...)
+		ret = append(ret, protocol.ToBulkDouble(m.Score)...)
+	}
+	return ret
+}
@@ -0,0 +1,287 @@
+package command
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+type zsetOpType int
+
+const (
+	ZSET_OP_UNION zsetOpType = iota
+	ZSET_OP_INTER
+	ZSET_OP_DIFF
+)
+
+type zsetAggregate int
+
+const (
+	ZSET_AGGREGATE_SUM zsetAggregate = iota
+	ZSET_AGGREGATE_MIN
+	ZSET_AGGREGATE_MAX
+)
+
+// Zsetop implements ZUNION, ZINTER, ZDIFF and their STORE variants. Plain sets
+// are accepted as inputs, every member having an implicit score of 1.
+type Zsetop struct {
+	name  string
+	op    zsetOpType
+	store bool
+}
+
+// zsetOpInput is one input key: a sorted set, a plain set, or neither when
+// the key does not exist.
+type zsetOpInput struct {
+	zset   *entry.SortedSet
+	set    *entry.Set
+	weight float64
+}
+
+func (in *zsetOpInput) len() int {
+	switch {
+	case in.zset != nil:
+		return in.zset.Len()
+	case in.set != nil:
+		return in.set.Len()
+	}
+	return 0
+}
+
+func (in *zsetOpInput) score(member string) (float64, bool) {
+	switch {
+	case in.zset != nil:
+		return in.zset.Score(member)
+	case in.set != nil:
+		return 1, in.set.Contains(member)
+	}
+	return 0, false
+}
+
+func (in *zsetOpInput) members() []entry.SortedSetMember {
+	switch {
+	case in.zset != nil:
+		return in.zset.Slice(0, in.zset.Len())
+	case in.set != nil:
+		ret := []entry.SortedSetMember{}
+		for _, member := range in.set.Members() {
+			ret = append(ret, entry.SortedSetMember{Member: member, Score: 1})
+		}
+		return ret
+	}
+	return []entry.SortedSetMember{}
+}
+
+func (z *Zsetop) Handle(args []string, ctx *event.Context, writeChan chan []byte) {
+	argStart := 0
+	if z.store {
+		argStart = 1
+	}
+	if len(args) < argStart+2 {
+		writeChan <- wrongNumberOfArgs(z.name)
+		return
+	}
+	numKeys, err := strconv.Atoi(args[argStart])
+	if err != nil {
+		writeChan <- protocol.ToError(notIntegerError)
+		return
+	}
+	if numKeys <= 0 {
+		writeChan <- protocol.ToError(fmt.Sprintf("ERR at least 1 input key is needed for '%s' command", z.name))
+		return
+	}
+	keys := args[argStart+1:]
+	if numKeys > len(keys) {
+		writeChan <- protocol.ToError(syntaxError)
+		return
+	}
+	keys, opts := keys[:numKeys], keys[numKeys:]
+	weights := slices.Repeat([]float64{1}, numKeys)
+	aggregate := ZSET_AGGREGATE_SUM
+	withScores := false
+	for i := 0; i < len(opts); i++ {
+		opt := strings.ToLower(opts[i])
+		switch {
+		case opt == "weights" && z.op != ZSET_OP_DIFF && i+numKeys < len(opts):
+			for j := range numKeys {
+				w, err := parseScore(opts[i+1+j])
+				if err != nil {
+					writeChan <- protocol.ToError("ERR weight value is not a float")
+					return
+				}
+				weights[j] = w
+			}
+			i += numKeys
+		case opt == "aggregate" && z.op != ZSET_OP_DIFF && i+1 < len(opts):
+			switch strings.ToLower(opts[i+1]) {
+			case "sum":
+				aggregate = ZSET_AGGREGATE_SUM
+			case "min":
+				aggregate = ZSET_AGGREGATE_MIN
+			case "max":
+				aggregate = ZSET_AGGREGATE_MAX
+			default:
+				writeChan <- protocol.ToError(syntaxError)
+				return
+			}
+			i++
+		case opt == "withscores" && !z.store:
+			withScores = true
+		default:
+			writeChan <- protocol.ToError(syntaxError)
+			return
+		}
+	}
+	inputs, err := lookupZsetOpInputs(ctx, keys, weights)
+	if err != nil {
+		writeChan <- protocol.ToError(err.Error())
+		return
+	}
+
+	var result []entry.SortedSetMember
+	switch z.op {
+	case ZSET_OP_UNION:
+		result = zsetUnion(inputs, aggregate)
+	case ZSET_OP_INTER:
+		result = zsetInter(inputs, aggregate, 0)
+	case ZSET_OP_DIFF:
+		result = zsetDiff(inputs)
+	}
+	slices.SortFunc(result, func(a, b entry.SortedSetMember) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(a.Member, b.Member))
+	})
+
+	if z.store {
+		storeSortedSet(ctx, args[0], result)
+		writeChan <- protocol.ToRespInt(len(result))
+		return
+	}
+	writeChan <- sortedSetMembersToResp(result, withScores)
+}
+
+func (z *Zsetop) CanPropogateCommand(args []string) bool {
+	return z.store
+}
+
+func lookupZsetOpInputs(ctx *event.Context, keys []string, weights []float64) ([]*zsetOpInput, error) {
+	inputs := make([]*zsetOpInput, len(keys))
+	for i, key := range keys {
+		in := &zsetOpInput{weight: weights[i]}
+		e, _ := lookupKey(ctx, key)
+		switch e := e.(type) {
+		case nil:
+		case *entry.SortedSet:
+			in.zset = e
+		case *entry.Set:
+			in.set = e
+		default:
+			return nil, errors.New(wrongTypeError)
+		}
+		inputs[i] = in
+	}
+	return inputs, nil
+}
+
+// weightedScore multiplies a score by its input's weight, treating the NaN of
+// 0 * inf as 0 like Redis does.
+func weightedScore(score float64, weight float64) float64 {
+	v := score * weight
+	if math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
+
+func aggregateScores(a float64, b float64, aggregate zsetAggregate) float64 {
+	switch aggregate {
+	case ZSET_AGGREGATE_MIN:
+		return min(a, b)
+	case ZSET_AGGREGATE_MAX:
+		return max(a, b)
+	}
+	v := a + b
+	if math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
+
+func zsetUnion(inputs []*zsetOpInput, aggregate zsetAggregate) []entry.SortedSetMember {
+	scores := make(map[string]float64)
+	order := []string{}
+	for _, in := range inputs {
+		for _, m := range in.members() {
+			score := weightedScore(m.Score, in.weight)
+			current, ok := scores[m.Member]
+			if !ok {
+				order = append(order, m.Member)
+				scores[m.Member] = score
+				continue
+			}
+			scores[m.Member] = aggregateScores(current, score, aggregate)
+		}
+	}
+	ret := make([]entry.SortedSetMember, len(order))
+	for i, member := range order {
+		ret[i] = entry.SortedSetMember{Member: member, Score: scores[member]}
+	}
+	return ret
+}
+
+// zsetInter walks the smallest input and probes the others. A positive limit
+// stops the walk once that many members have been found.
+func zsetInter(inputs []*zsetOpInput, aggregate zsetAggregate, limit int) []entry.SortedSetMember {
+	sorted := slices.Clone(inputs)
+	slices.SortStableFunc(sorted, func(a, b *zsetOpInput) int {
+		return a.len() - b.len()
+	})
+	ret := []entry.SortedSetMember{}
+	if sorted[0].len() == 0 {
+		return ret
+	}
+	for _, m := range sorted[0].members() {
+		score := weightedScore(m.Score, sorted[0].weight)
+		inAll := true
+		for _, other := range sorted[1:] {
+			otherScore, ok := other.score(m.Member)
+			if !ok {
+				inAll = false
+				break
+			}
+			score = aggregateScores(score, weightedScore(otherScore, other.weight), aggregate)
+		}
+		if !inAll {
+			continue
+		}
+		ret = append(ret, entry.SortedSetMember{Member: m.Member, Score: score})
+		if limit > 0 && len(ret) == limit {
+			break
+		}
+	}
+	return ret
+}
+
+func zsetDiff(inputs []*zsetOpInput) []entry.SortedSetMember {
+	ret := []entry.SortedSetMember{}
+	for _, m := range inputs[0].members() {
+		found := false
+		for _, other := range inputs[1:] {
+			if _, ok := other.score(m.Member); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
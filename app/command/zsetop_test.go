@@ -0,0 +1,75 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+func array(strs ...string) string {
+	return string(protocol.ToArrayBulkStrings(strs))
+}
+
+func TestZsetop(t *testing.T) {
+	tests := []struct {
+		cmd      []string
+		expected string
+		// stored is what ZRANGE dst 0 -1 WITHSCORES returns after a STORE
+		// variant.
+		stored string
+	}{
+		{[]string{"ZUNION", "2", "z1", "z2", "WITHSCORES"}, array("a", "1", "b", "12", "c", "23", "d", "30"), ""},
+		{[]string{"ZINTER", "2", "z1", "z2", "WITHSCORES"}, array("b", "12", "c", "23"), ""},
+		{[]string{"ZINTER", "2", "z1", "z2"}, array("b", "c"), ""},
+		{[]string{"ZDIFF", "2", "z1", "z2", "WITHSCORES"}, array("a", "1"), ""},
+		{[]string{"ZDIFF", "1", "missing"}, array(), ""},
+		{[]string{"ZUNION", "2", "z1", "z2", "WEIGHTS", "2", "0.5", "WITHSCORES"}, array("a", "2", "b", "9", "d", "15", "c", "16"), ""},
+		{[]string{"ZINTER", "2", "z1", "z2", "AGGREGATE", "MAX", "WITHSCORES"}, array("b", "10", "c", "20"), ""},
+		{[]string{"ZINTER", "2", "z1", "z2", "AGGREGATE", "min", "WITHSCORES"}, array("b", "2", "c", "3"), ""},
+		{[]string{"ZUNION", "2", "z1", "z2", "WEIGHTS", "1", "-1", "AGGREGATE", "MIN", "WITHSCORES"}, array("d", "-30", "c", "-20", "b", "-10", "a", "1"), ""},
+		// Plain sets count as sorted sets whose members all score 1.
+		{[]string{"ZINTER", "2", "z1", "s", "WITHSCORES"}, array("c", "4"), ""},
+		{[]string{"ZUNION", "2", "s", "missing", "WITHSCORES"}, array("c", "1", "d", "1", "e", "1"), ""},
+		{[]string{"ZDIFF", "2", "s", "z1"}, array("d", "e"), ""},
+		{[]string{"ZINTERSTORE", "dst", "2", "z1", "z2"}, ":2\r\n", array("b", "12", "c", "23")},
+		{[]string{"ZUNIONSTORE", "dst", "2", "z1", "s", "WEIGHTS", "1", "3", "AGGREGATE", "MAX"}, ":5\r\n", array("a", "1", "b", "2", "c", "3", "d", "3", "e", "3")},
+		{[]string{"ZDIFFSTORE", "dst", "2", "z2", "s"}, ":1\r\n", array("b", "10")},
+		{[]string{"ZDIFFSTORE", "dst", "2", "z1", "z1"}, ":0\r\n", array()},
+		{[]string{"ZUNION", "0", "z1"}, "-ERR at least 1 input key is needed for 'zunion' command\r\n", ""},
+		{[]string{"ZUNION", "3", "z1", "z2"}, "-" + syntaxError + "\r\n", ""},
+		{[]string{"ZINTER", "2", "z1", "z2", "WEIGHTS", "1"}, "-" + syntaxError + "\r\n", ""},
+		{[]string{"ZINTER", "2", "z1", "z2", "WEIGHTS", "1", "x"}, "-ERR weight value is not a float\r\n", ""},
+		{[]string{"ZINTER", "1", "z1", "AGGREGATE", "avg"}, "-" + syntaxError + "\r\n", ""},
+		{[]string{"ZDIFF", "2", "z1", "z2", "WEIGHTS", "1", "1"}, "-" + syntaxError + "\r\n", ""},
+		{[]string{"ZUNIONSTORE", "dst", "1", "z1", "WITHSCORES"}, "-" + syntaxError + "\r\n", ""},
+		{[]string{"ZINTER", "2", "z1", "str"}, "-" + wrongTypeError + "\r\n", ""},
+		{[]string{"ZINTERCARD", "2", "z1", "z2"}, ":2\r\n", ""},
+		{[]string{"ZINTERCARD", "2", "z1", "s", "LIMIT", "0"}, ":1\r\n", ""},
+		{[]string{"ZINTERCARD", "2", "z1", "z2", "LIMIT", "1"}, ":1\r\n", ""},
+		{[]string{"ZINTERCARD", "2", "z1", "z2", "LIMIT", "-1"}, "-ERR LIMIT can't be negative\r\n", ""},
+		{[]string{"ZINTERCARD", "0", "z1"}, "-ERR numkeys should be greater than 0\r\n", ""},
+		{[]string{"ZINTERCARD", "3", "z1", "z2"}, "-ERR Number of keys can't be greater than number of args\r\n", ""},
+		{[]string{"SINTERCARD", "2", "s", "s2", "LIMIT", "1"}, ":1\r\n", ""},
+		{[]string{"SINTERCARD", "2", "s", "s2", "LIMIT"}, "-" + syntaxError + "\r\n", ""},
+		{[]string{"SINTERCARD", "1", "s", "LIMIT", "x"}, "-" + notIntegerError + "\r\n", ""},
+	}
+
+	for _, tt := range tests {
+		ctx := newTestContext()
+		run(t, ctx, "ZADD", "z1", "1", "a", "2", "b", "3", "c")
+		run(t, ctx, "ZADD", "z2", "10", "b", "20", "c", "30", "d")
+		run(t, ctx, "SADD", "s", "c", "d", "e")
+		run(t, ctx, "SADD", "s2", "d", "e", "f")
+		run(t, ctx, "SET", "str", "v")
+		run(t, ctx, "ZADD", "dst", "100", "old")
+		if got := run(t, ctx, tt.cmd[0], tt.cmd[1:]...); got != tt.expected {
+			t.Errorf("%v: expected %q; got %q", tt.cmd, tt.expected, got)
+		}
+		if tt.stored == "" {
+			continue
+		}
+		if got := run(t, ctx, "ZRANGE", "dst", "0", "-1", "WITHSCORES"); got != tt.stored {
+			t.Errorf("%v: expected to store %q; got %q", tt.cmd, tt.stored, got)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package entry
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// snapshots counts the snapshots ever taken, and activeSnapshots those still
+// being read.
+var snapshots, activeSnapshots atomic.Int64
+
+// TakeSnapshot starts a snapshot that shares every collection in the
+// keyspace until the collection is next written to, when Writable copies it.
+// It returns the function to call once the snapshot is no longer read.
+func TakeSnapshot() (release func()) {
+	snapshots.Add(1)
+	activeSnapshots.Add(1)
+	var once sync.Once
+	return func() { once.Do(func() { activeSnapshots.Add(-1) }) }
+}
+
+// cow is embedded in the collections to tell whether a snapshot may share
+// them: epoch is the number of snapshots taken before they were made.
+type cow struct {
+	epoch int64
+}
+
+func newCow() cow {
+	return cow{epoch: snapshots.Load()}
+}
+
+func (c *cow) shared() bool {
+	return activeSnapshots.Load() > 0 && c.epoch < snapshots.Load()
+}
+
+// Writable returns e, or a copy of it for the keyspace to keep in its place
+// if a snapshot still being read may share it, so that the snapshot keeps
+// seeing e as it was when taken.
+func Writable(e Entry) Entry {
+	switch v := e.(type) {
+	case *Set:
+		if v.shared() {
+			return v.Clone()
+		}
+	case *SortedSet:
+		if v.shared() {
+			return v.Clone()
+		}
+	case *List:
+		if v.shared() {
+			return v.Clone()
+		}
+	case *Hash:
+		if v.shared() {
+			return v.Clone()
+		}
+	case *Stream:
+		if v.shared() {
+			return v.Clone()
+		}
+	}
+	return e
+}
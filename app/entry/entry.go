@@ -6,6 +6,32 @@ type Entry interface {
 	Type() string
 }
 
+// Expiring is an entry that can be given an expiry time.
+type Expiring interface {
+	ExpiryTime() time.Time
+	SetExpiryTime(t time.Time)
+}
+
+// IsExpired reports whether e has an expiry time that is before now.
+func IsExpired(e Entry, now time.Time) bool {
+	x, ok := e.(Expiring)
+	return ok && !x.ExpiryTime().IsZero() && x.ExpiryTime().Before(now)
+}
+
+// expiry is when a key expires, or the zero time if it doesn't. The
+// collection types embed it to be Expiring.
+type expiry struct {
+	expiryTime time.Time
+}
+
+func (e *expiry) ExpiryTime() time.Time {
+	return e.expiryTime
+}
+
+func (e *expiry) SetExpiryTime(t time.Time) {
+	e.expiryTime = t
+}
+
 type RedisString struct {
 	value      string
 	expiryTime time.Time
@@ -26,6 +52,10 @@ func (r *RedisString) ExpiryTime() time.Time {
 	return r.expiryTime
 }
 
+func (r *RedisString) SetExpiryTime(t time.Time) {
+	r.expiryTime = t
+}
+
 func (r *RedisString) Type() string {
 	return "string"
 }
@@ -0,0 +1,58 @@
+package entry
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Hash maps fields to values, each field with an optional expiry time. It
+// only holds what an RDB file loads into it for now.
+type Hash struct {
+	cow
+	expiry
+	fields  map[string]string
+	expires map[string]time.Time
+}
+
+func NewHash() *Hash {
+	return &Hash{cow: newCow(), fields: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (h *Hash) Type() string {
+	return "hash"
+}
+
+func (h *Hash) Len() int {
+	return len(h.fields)
+}
+
+// Set sets field to value, expiring at expiryTime unless it is zero.
+func (h *Hash) Set(field string, value string, expiryTime time.Time) {
+	h.fields[field] = value
+	if expiryTime.IsZero() {
+		delete(h.expires, field)
+	} else {
+		h.expires[field] = expiryTime
+	}
+}
+
+func (h *Hash) Get(field string) (string, bool) {
+	value, ok := h.fields[field]
+	return value, ok
+}
+
+// ExpiryTime returns when field expires, or the zero time if it doesn't.
+func (h *Hash) ExpiryTime(field string) time.Time {
+	return h.expires[field]
+}
+
+// Fields returns the fields of h in lexicographical order.
+func (h *Hash) Fields() []string {
+	return slices.Sorted(maps.Keys(h.fields))
+}
+
+// Clone returns a copy of h sharing nothing with it.
+func (h *Hash) Clone() *Hash {
+	return &Hash{cow: newCow(), expiry: h.expiry, fields: maps.Clone(h.fields), expires: maps.Clone(h.expires)}
+}
@@ -0,0 +1,33 @@
+package entry
+
+import "slices"
+
+// List is a sequence of elements. It only holds what an RDB file loads into
+// it for now.
+type List struct {
+	cow
+	expiry
+	elements []string
+}
+
+func NewList(elements []string) *List {
+	return &List{cow: newCow(), elements: elements}
+}
+
+func (l *List) Type() string {
+	return "list"
+}
+
+func (l *List) Len() int {
+	return len(l.elements)
+}
+
+// Elements returns the elements of l from head to tail.
+func (l *List) Elements() []string {
+	return slices.Clone(l.elements)
+}
+
+// Clone returns a copy of l sharing nothing with it.
+func (l *List) Clone() *List {
+	return &List{cow: newCow(), expiry: l.expiry, elements: slices.Clone(l.elements)}
+}
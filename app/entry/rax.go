@@ -0,0 +1,210 @@
+package entry
+
+import (
+	"bytes"
+	"slices"
+	"sort"
+)
+
+// rax is a radix tree mapping fixed-length keys to stream nodes, the index
+// Redis keeps stream nodes in. Every node holds the bytes its keys have in
+// common below its parent, so a chain of single children is collapsed into
+// one node. Since all keys are the same length no key is a prefix of another
+// and values only ever sit on leaves.
+type rax struct {
+	root  *raxNode
+	keys  int
+	nodes int
+}
+
+type raxNode struct {
+	prefix   []byte
+	children []*raxNode // ordered by the first byte of their prefix
+	value    *streamNode
+}
+
+func newRax() *rax {
+	return &rax{root: &raxNode{}, nodes: 1}
+}
+
+// child returns the index of the child of n starting with b, or where it
+// would be inserted if there is none.
+func (n *raxNode) child(b byte) (int, bool) {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].prefix[0] >= b })
+	return i, i < len(n.children) && n.children[i].prefix[0] == b
+}
+
+func commonPrefixLen(a []byte, b []byte) int {
+	l := 0
+	for l < len(a) && l < len(b) && a[l] == b[l] {
+		l++
+	}
+	return l
+}
+
+// insert stores value at key, replacing any value already there.
+func (t *rax) insert(key []byte, value *streamNode) {
+	n := t.root
+	for len(key) > 0 {
+		i, found := n.child(key[0])
+		if !found {
+			n.children = slices.Insert(n.children, i, &raxNode{prefix: slices.Clone(key), value: value})
+			t.nodes++
+			t.keys++
+			return
+		}
+		c := n.children[i]
+		l := commonPrefixLen(c.prefix, key)
+		if l < len(c.prefix) {
+			split := &raxNode{prefix: c.prefix[:l:l], children: []*raxNode{c}}
+			c.prefix = c.prefix[l:]
+			n.children[i] = split
+			t.nodes++
+			c = split
+		}
+		n = c
+		key = key[l:]
+	}
+	if n.value == nil {
+		t.keys++
+	}
+	n.value = value
+}
+
+// find returns the value at key, or nil if there is none.
+func (t *rax) find(key []byte) *streamNode {
+	n := t.root
+	for len(key) > 0 {
+		i, found := n.child(key[0])
+		if !found || !bytes.HasPrefix(key, n.children[i].prefix) {
+			return nil
+		}
+		n = n.children[i]
+		key = key[len(n.prefix):]
+	}
+	return n.value
+}
+
+// remove deletes the value at key, merging the nodes left with a single
+// child back into it, and reports whether there was one.
+func (t *rax) remove(key []byte) bool {
+	return t.removeFrom(t.root, key)
+}
+
+func (t *rax) removeFrom(n *raxNode, key []byte) bool {
+	if len(key) == 0 {
+		if n.value == nil {
+			return false
+		}
+		n.value = nil
+		t.keys--
+		return true
+	}
+	i, found := n.child(key[0])
+	if !found || !bytes.HasPrefix(key, n.children[i].prefix) {
+		return false
+	}
+	c := n.children[i]
+	if !t.removeFrom(c, key[len(c.prefix):]) {
+		return false
+	}
+	switch {
+	case c.value == nil && len(c.children) == 0:
+		n.children = slices.Delete(n.children, i, i+1)
+		t.nodes--
+	case c.value == nil && len(c.children) == 1:
+		grandchild := c.children[0]
+		grandchild.prefix = append(slices.Clone(c.prefix), grandchild.prefix...)
+		n.children[i] = grandchild
+		t.nodes--
+	}
+	return true
+}
+
+// ascend calls fn with the values at keys greater than or equal to from, in
+// order, until fn returns false.
+func (t *rax) ascend(from []byte, fn func(*streamNode) bool) {
+	t.root.ascend(from, true, fn)
+}
+
+// ascend walks the subtree of n, which is still bounded by from when the path
+// to n matches the start of it.
+func (n *raxNode) ascend(from []byte, bounded bool, fn func(*streamNode) bool) bool {
+	if n.value != nil && !fn(n.value) {
+		return false
+	}
+	start := 0
+	if bounded && len(from) > 0 {
+		start, _ = n.child(from[0])
+	}
+	for _, c := range n.children[start:] {
+		childBounded, rest := false, from
+		if bounded {
+			l := min(len(c.prefix), len(from))
+			switch bytes.Compare(c.prefix[:l], from[:l]) {
+			case -1:
+				continue
+			case 0:
+				childBounded, rest = true, from[l:]
+			}
+		}
+		if !c.ascend(rest, childBounded, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// descend calls fn with the values at keys less than or equal to from, in
+// reverse order, until fn returns false.
+func (t *rax) descend(from []byte, fn func(*streamNode) bool) {
+	t.root.descend(from, true, fn)
+}
+
+func (n *raxNode) descend(from []byte, bounded bool, fn func(*streamNode) bool) bool {
+	end := len(n.children)
+	if bounded && len(from) > 0 {
+		i, found := n.child(from[0])
+		if found {
+			i++
+		}
+		end = i
+	}
+	for j := end - 1; j >= 0; j-- {
+		c := n.children[j]
+		childBounded, rest := false, from
+		if bounded {
+			l := min(len(c.prefix), len(from))
+			switch bytes.Compare(c.prefix[:l], from[:l]) {
+			case 1:
+				continue
+			case 0:
+				childBounded, rest = true, from[l:]
+			}
+		}
+		if !c.descend(rest, childBounded, fn) {
+			return false
+		}
+	}
+	return n.value == nil || fn(n.value)
+}
+
+// first returns the value at the smallest key, or nil if the tree is empty.
+func (t *rax) first() *streamNode {
+	var first *streamNode
+	t.root.ascend(nil, false, func(n *streamNode) bool {
+		first = n
+		return false
+	})
+	return first
+}
+
+// last returns the value at the largest key, or nil if the tree is empty.
+func (t *rax) last() *streamNode {
+	var last *streamNode
+	t.root.descend(nil, false, func(n *streamNode) bool {
+		last = n
+		return false
+	})
+	return last
+}
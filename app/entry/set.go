@@ -0,0 +1,319 @@
+package entry
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+type setEncoding int
+
+const (
+	SET_ENCODING_INTSET setEncoding = iota
+	SET_ENCODING_HASHTABLE
+)
+
+func (e setEncoding) String() string {
+	return [...]string{"intset", "hashtable"}[e]
+}
+
+const DEFAULT_SET_MAX_INTSET_ENTRIES int = 512
+
+// Set keeps its members in a sorted slice of integers while every member is a
+// canonical integer and there are at most maxIntsetEntries of them, and in a
+// hash set otherwise. Once converted it never goes back to the intset.
+type Set struct {
+	cow
+	expiry
+	encoding         setEncoding
+	intset           []int64
+	members          mapset.Set[string]
+	maxIntsetEntries int
+}
+
+func NewSet(maxIntsetEntries int) *Set {
+	return &Set{
+		cow:              newCow(),
+		encoding:         SET_ENCODING_INTSET,
+		intset:           []int64{},
+		maxIntsetEntries: maxIntsetEntries,
+	}
+}
+
+func (s *Set) Type() string {
+	return "set"
+}
+
+func (s *Set) Encoding() string {
+	return s.encoding.String()
+}
+
+func (s *Set) Len() int {
+	if s.encoding == SET_ENCODING_INTSET {
+		return len(s.intset)
+	}
+	return s.members.Cardinality()
+}
+
+// Clone returns a copy of s sharing nothing with it.
+func (s *Set) Clone() *Set {
+	c := &Set{cow: newCow(), expiry: s.expiry, encoding: s.encoding, maxIntsetEntries: s.maxIntsetEntries}
+	if s.encoding == SET_ENCODING_INTSET {
+		c.intset = slices.Clone(s.intset)
+	} else {
+		c.members = s.members.Clone()
+	}
+	return c
+}
+
+// Add inserts member and reports whether it was not already present.
+func (s *Set) Add(member string) bool {
+	if s.encoding == SET_ENCODING_INTSET {
+		v, ok := parseSetInt(member)
+		if !ok {
+			s.convertToHashtable()
+			return s.members.Add(member)
+		}
+		idx, found := slices.BinarySearch(s.intset, v)
+		if found {
+			return false
+		}
+		s.intset = slices.Insert(s.intset, idx, v)
+		if len(s.intset) > s.maxIntsetEntries {
+			s.convertToHashtable()
+		}
+		return true
+	}
+	return s.members.Add(member)
+}
+
+// Remove deletes member and reports whether it was present.
+func (s *Set) Remove(member string) bool {
+	if s.encoding == SET_ENCODING_INTSET {
+		v, ok := parseSetInt(member)
+		if !ok {
+			return false
+		}
+		idx, found := slices.BinarySearch(s.intset, v)
+		if !found {
+			return false
+		}
+		s.intset = slices.Delete(s.intset, idx, idx+1)
+		return true
+	}
+	if !s.members.Contains(member) {
+		return false
+	}
+	s.members.Remove(member)
+	return true
+}
+
+func (s *Set) Contains(member string) bool {
+	if s.encoding == SET_ENCODING_INTSET {
+		v, ok := parseSetInt(member)
+		if !ok {
+			return false
+		}
+		_, found := slices.BinarySearch(s.intset, v)
+		return found
+	}
+	return s.members.Contains(member)
+}
+
+// Members returns every member; intset encoded sets are returned in ascending
+// order.
+func (s *Set) Members() []string {
+	if s.encoding == SET_ENCODING_INTSET {
+		ret := make([]string, len(s.intset))
+		for i, v := range s.intset {
+			ret[i] = strconv.FormatInt(v, 10)
+		}
+		return ret
+	}
+	return s.members.ToSlice()
+}
+
+// RandomMember returns a member chosen at random. The hash table is sampled
+// by where a map iteration starts, which like Redis's dictGetRandomKey
+// favours some members slightly over others.
+func (s *Set) RandomMember() (string, bool) {
+	if s.Len() == 0 {
+		return "", false
+	}
+	if s.encoding == SET_ENCODING_INTSET {
+		return strconv.FormatInt(s.intset[rand.Intn(len(s.intset))], 10), true
+	}
+	var member string
+	s.members.Each(func(m string) bool {
+		member = m
+		return true
+	})
+	return member, true
+}
+
+// RandomMembers returns up to count distinct members chosen at random: every
+// member once count reaches the size of the set, and otherwise count of them
+// picked by index from the intset or by reservoir sampling from the hash
+// table, so that only count members are ever copied.
+func (s *Set) RandomMembers(count int) []string {
+	if count >= s.Len() {
+		return s.Members()
+	}
+	if s.encoding == SET_ENCODING_INTSET {
+		ret := make([]string, count)
+		for i, j := range randomIndexes(len(s.intset), count) {
+			ret[i] = strconv.FormatInt(s.intset[j], 10)
+		}
+		return ret
+	}
+	ret := make([]string, 0, count)
+	seen := 0
+	s.members.Each(func(m string) bool {
+		if seen < count {
+			ret = append(ret, m)
+		} else if j := rand.Intn(seen + 1); j < count {
+			ret[j] = m
+		}
+		seen++
+		return false
+	})
+	rand.Shuffle(len(ret), func(i, j int) {
+		ret[i], ret[j] = ret[j], ret[i]
+	})
+	return ret
+}
+
+// RANDOM_PREALLOC_MAX caps how many random members are allocated for up
+// front, so that a huge count only takes memory as its members are picked.
+const RANDOM_PREALLOC_MAX int = 1024
+
+// randomIndexes returns count distinct indexes below n, 0 < count < n, in
+// random order. It runs the first count steps of a Fisher-Yates shuffle of
+// 0 to n-1, keeping only the positions swapped so far.
+func randomIndexes(n int, count int) []int {
+	swapped := make(map[int]int, count)
+	at := func(i int) int {
+		if v, ok := swapped[i]; ok {
+			return v
+		}
+		return i
+	}
+	ret := make([]int, count)
+	for i := range count {
+		j := i + rand.Intn(n-i)
+		ret[i] = at(j)
+		swapped[j] = at(i)
+	}
+	return ret
+}
+
+// RandomMembersWithRepetition returns exactly count members chosen at random,
+// possibly returning the same member more than once.
+func (s *Set) RandomMembersWithRepetition(count int) []string {
+	if s.Len() == 0 || count <= 0 {
+		return []string{}
+	}
+	ret := make([]string, 0, min(count, RANDOM_PREALLOC_MAX))
+	for range count {
+		member, _ := s.RandomMember()
+		ret = append(ret, member)
+	}
+	return ret
+}
+
+func (s *Set) convertToHashtable() {
+	if s.encoding == SET_ENCODING_HASHTABLE {
+		return
+	}
+	s.members = mapset.NewThreadUnsafeSetWithSize[string](len(s.intset))
+	for _, v := range s.intset {
+		s.members.Add(strconv.FormatInt(v, 10))
+	}
+	s.intset = nil
+	s.encoding = SET_ENCODING_HASHTABLE
+}
+
+// parseSetInt only accepts the canonical form of an integer, so that the
+// member read back from the intset is byte for byte the one that was added.
+func parseSetInt(member string) (int64, bool) {
+	v, err := strconv.ParseInt(member, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if strconv.FormatInt(v, 10) != member {
+		return 0, false
+	}
+	return v, true
+}
+
+// SetIntersection returns the members common to every set, walking the
+// smallest set and probing the others. A nil set counts as empty. A positive
+// limit stops the walk once that many members have been found.
+func SetIntersection(sets []*Set, limit int) []string {
+	if len(sets) == 0 || slices.Contains(sets, nil) {
+		return []string{}
+	}
+	sorted := slices.Clone(sets)
+	slices.SortFunc(sorted, func(a, b *Set) int {
+		return a.Len() - b.Len()
+	})
+	ret := []string{}
+	for _, member := range sorted[0].Members() {
+		inAll := true
+		for _, other := range sorted[1:] {
+			if !other.Contains(member) {
+				inAll = false
+				break
+			}
+		}
+		if !inAll {
+			continue
+		}
+		ret = append(ret, member)
+		if limit > 0 && len(ret) == limit {
+			break
+		}
+	}
+	return ret
+}
+
+// SetUnion returns the members found in any of the sets, skipping nil sets.
+func SetUnion(sets []*Set) []string {
+	seen := mapset.NewThreadUnsafeSet[string]()
+	ret := []string{}
+	for _, s := range sets {
+		if s == nil {
+			continue
+		}
+		for _, member := range s.Members() {
+			if seen.Add(member) {
+				ret = append(ret, member)
+			}
+		}
+	}
+	return ret
+}
+
+// SetDifference returns the members of the first set that are in none of the
+// others, skipping nil sets.
+func SetDifference(sets []*Set) []string {
+	ret := []string{}
+	if len(sets) == 0 || sets[0] == nil {
+		return ret
+	}
+	for _, member := range sets[0].Members() {
+		found := false
+		for _, other := range sets[1:] {
+			if other != nil && other.Contains(member) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ret = append(ret, member)
+		}
+	}
+	return ret
+}
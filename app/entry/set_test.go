@@ -0,0 +1,117 @@
+package entry
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestSetEncoding(t *testing.T) {
+	tests := []struct {
+		members          []string
+		maxIntsetEntries int
+		expectedEncoding string
+	}{
+		{[]string{"3", "1", "2"}, 512, "intset"},
+		{[]string{"1", "-20", "abc"}, 512, "hashtable"},
+		{[]string{"1", "2", "3"}, 2, "hashtable"},
+		{[]string{"007"}, 512, "hashtable"},
+		{[]string{"+1"}, 512, "hashtable"},
+	}
+
+	for _, tt := range tests {
+		s := NewSet(tt.maxIntsetEntries)
+		for _, m := range tt.members {
+			s.Add(m)
+		}
+		if s.Encoding() != tt.expectedEncoding {
+			t.Errorf("Expected encoding %s for %v; got %s", tt.expectedEncoding, tt.members, s.Encoding())
+		}
+		got := s.Members()
+		slices.Sort(got)
+		expected := slices.Clone(tt.members)
+		slices.Sort(expected)
+		if !slices.Equal(got, expected) {
+			t.Errorf("Expected members %v; got %v", expected, got)
+		}
+	}
+}
+
+func TestSetAddRemove(t *testing.T) {
+	s := NewSet(DEFAULT_SET_MAX_INTSET_ENTRIES)
+	if !s.Add("10") || s.Add("10") {
+		t.Errorf("Expected only the first add of 10 to report an insertion")
+	}
+	if s.Remove("x") || !s.Remove("10") {
+		t.Errorf("Expected only removing 10 to report a deletion")
+	}
+	if s.Len() != 0 {
+		t.Errorf("Expected empty set; got len %d", s.Len())
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	newSet := func(members ...string) *Set {
+		s := NewSet(DEFAULT_SET_MAX_INTSET_ENTRIES)
+		for _, m := range members {
+			s.Add(m)
+		}
+		return s
+	}
+	a := newSet("1", "2", "3", "x")
+	b := newSet("2", "3", "y")
+	tests := []struct {
+		name     string
+		got      []string
+		expected []string
+	}{
+		{"intersection", SetIntersection([]*Set{a, b}, 0), []string{"2", "3"}},
+		{"intersection with missing", SetIntersection([]*Set{a, nil}, 0), []string{}},
+		{"intersection with limit", SetIntersection([]*Set{a, newSet("2", "3")}, 1), []string{"2"}},
+		{"union", SetUnion([]*Set{a, nil, b}), []string{"1", "2", "3", "x", "y"}},
+		{"difference", SetDifference([]*Set{a, b, nil}), []string{"1", "x"}},
+		{"difference of missing", SetDifference([]*Set{nil, a}), []string{}},
+	}
+
+	for _, tt := range tests {
+		slices.Sort(tt.got)
+		if !slices.Equal(tt.got, tt.expected) {
+			t.Errorf("%s: expected %v; got %v", tt.name, tt.expected, tt.got)
+		}
+	}
+}
+
+func TestSetRandomMembers(t *testing.T) {
+	for _, members := range [][]string{{"1", "2", "3", "4", "5"}, {"a", "b", "c", "d", "e"}} {
+		s := NewSet(DEFAULT_SET_MAX_INTSET_ENTRIES)
+		for _, m := range members {
+			s.Add(m)
+		}
+		for count := range 7 {
+			got := s.RandomMembers(count)
+			sorted := slices.Sorted(slices.Values(got))
+			if len(got) != min(count, 5) || len(slices.Compact(sorted)) != len(got) {
+				t.Errorf("%s: expected %d distinct members; got %v", s.Encoding(), min(count, 5), got)
+			}
+			for _, m := range got {
+				if !s.Contains(m) {
+					t.Errorf("%s: expected members of the set; got %q", s.Encoding(), m)
+				}
+			}
+		}
+		if m, ok := s.RandomMember(); !ok || !s.Contains(m) {
+			t.Errorf("%s: expected a member of the set; got %q", s.Encoding(), m)
+		}
+	}
+}
+
+func TestSetRandomMembersWithRepetition(t *testing.T) {
+	s := NewSet(DEFAULT_SET_MAX_INTSET_ENTRIES)
+	s.Add("a")
+	if got := s.RandomMembersWithRepetition(3); !slices.Equal(got, []string{"a", "a", "a"}) {
+		t.Errorf("Expected a three times; got %v", got)
+	}
+	if got := s.RandomMembersWithRepetition(math.MinInt64); len(got) != 0 {
+		t.Errorf("Expected no members for a negative count; got %v", got)
+	}
+}
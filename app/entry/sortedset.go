@@ -0,0 +1,353 @@
+package entry
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+type sortedSetEncoding int
+
+const (
+	SORTED_SET_ENCODING_LISTPACK sortedSetEncoding = iota
+	SORTED_SET_ENCODING_SKIPLIST
+)
+
+func (e sortedSetEncoding) String() string {
+	return [...]string{"listpack", "skiplist"}[e]
+}
+
+const (
+	DEFAULT_ZSET_MAX_LISTPACK_ENTRIES int = 128
+	DEFAULT_ZSET_MAX_LISTPACK_VALUE   int = 64
+)
+
+type SortedSetMember struct {
+	Member string
+	Score  float64
+}
+
+func compareSortedSetMembers(a, b SortedSetMember) int {
+	if c := cmp.Compare(a.Score, b.Score); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.Member, b.Member)
+}
+
+// SortedSet keeps small sets as a flat slice ordered by score then member,
+// the equivalent of a Redis listpack. Once it grows past maxListpackEntries
+// elements or is given a member longer than maxListpackValue bytes it is
+// converted to a skiplist for ordered access plus a dict for score lookups,
+// and never converted back.
+type SortedSet struct {
+	cow
+	expiry
+	encoding           sortedSetEncoding
+	listpack           []SortedSetMember
+	zsl                *skiplist
+	dict               map[string]float64
+	maxListpackEntries int
+	maxListpackValue   int
+}
+
+func NewSortedSet(maxListpackEntries int, maxListpackValue int) *SortedSet {
+	return &SortedSet{
+		cow:                newCow(),
+		encoding:           SORTED_SET_ENCODING_LISTPACK,
+		listpack:           []SortedSetMember{},
+		maxListpackEntries: maxListpackEntries,
+		maxListpackValue:   maxListpackValue,
+	}
+}
+
+func (z *SortedSet) Type() string {
+	return "zset"
+}
+
+func (z *SortedSet) Encoding() string {
+	return z.encoding.String()
+}
+
+func (z *SortedSet) Len() int {
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		return len(z.listpack)
+	}
+	return z.zsl.length
+}
+
+// Clone returns a copy of z sharing nothing with it.
+func (z *SortedSet) Clone() *SortedSet {
+	c := NewSortedSet(z.maxListpackEntries, z.maxListpackValue)
+	c.expiry = z.expiry
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		c.listpack = slices.Clone(z.listpack)
+		return c
+	}
+	c.listpack = z.Slice(0, z.Len())
+	c.convertToSkiplist()
+	return c
+}
+
+func (z *SortedSet) Score(member string) (float64, bool) {
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		for _, m := range z.listpack {
+			if m.Member == member {
+				return m.Score, true
+			}
+		}
+		return 0, false
+	}
+	score, ok := z.dict[member]
+	return score, ok
+}
+
+// Add sets the score of member, inserting it if needed, and reports whether it
+// was newly inserted.
+func (z *SortedSet) Add(member string, score float64) bool {
+	current, exists := z.Score(member)
+	if exists {
+		if current != score {
+			z.remove(member, current)
+			z.insert(member, score)
+		}
+		return false
+	}
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK &&
+		(len(z.listpack)+1 > z.maxListpackEntries || len(member) > z.maxListpackValue) {
+		z.convertToSkiplist()
+	}
+	z.insert(member, score)
+	return true
+}
+
+// Remove deletes member and reports whether it was present.
+func (z *SortedSet) Remove(member string) bool {
+	score, ok := z.Score(member)
+	if !ok {
+		return false
+	}
+	z.remove(member, score)
+	return true
+}
+
+// Rank returns the 0-based position of member, counted from the highest
+// score when reverse is set.
+func (z *SortedSet) Rank(member string, reverse bool) (int, bool) {
+	score, ok := z.Score(member)
+	if !ok {
+		return 0, false
+	}
+	var rank int
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		rank, _ = slices.BinarySearchFunc(z.listpack, SortedSetMember{Member: member, Score: score}, compareSortedSetMembers)
+	} else {
+		rank = z.zsl.rank(member, score) - 1
+	}
+	if reverse {
+		rank = z.Len() - 1 - rank
+	}
+	return rank, true
+}
+
+// ScoreRange is an interval of scores whose ends may each be exclusive.
+type ScoreRange struct {
+	Min          float64
+	Max          float64
+	MinExclusive bool
+	MaxExclusive bool
+}
+
+func (r ScoreRange) belowMin(m SortedSetMember) bool {
+	return m.Score < r.Min || (r.MinExclusive && m.Score == r.Min)
+}
+
+func (r ScoreRange) withinMax(m SortedSetMember) bool {
+	return m.Score < r.Max || (!r.MaxExclusive && m.Score == r.Max)
+}
+
+// LexBound is one end of a LexRange. Inf is -1 for "-", 1 for "+" and 0 when
+// the bound is the string Value.
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int
+}
+
+// LexRange is an interval of members, only meaningful when every element of
+// the set has the same score.
+type LexRange struct {
+	Min LexBound
+	Max LexBound
+}
+
+func (r LexRange) belowMin(m SortedSetMember) bool {
+	if r.Min.Inf != 0 {
+		return r.Min.Inf > 0
+	}
+	return m.Member < r.Min.Value || (r.Min.Exclusive && m.Member == r.Min.Value)
+}
+
+func (r LexRange) withinMax(m SortedSetMember) bool {
+	if r.Max.Inf != 0 {
+		return r.Max.Inf > 0
+	}
+	return m.Member < r.Max.Value || (!r.Max.Exclusive && m.Member == r.Max.Value)
+}
+
+// ScoreRankRange returns the ranks [start, end) of the elements within r.
+func (z *SortedSet) ScoreRankRange(r ScoreRange) (int, int) {
+	start := z.countWhile(r.belowMin)
+	return start, max(start, z.countWhile(r.withinMax))
+}
+
+// LexRankRange returns the ranks [start, end) of the elements within r.
+func (z *SortedSet) LexRankRange(r LexRange) (int, int) {
+	start := z.countWhile(r.belowMin)
+	return start, max(start, z.countWhile(r.withinMax))
+}
+
+// Slice returns the elements with ranks [start, end) in ascending order.
+func (z *SortedSet) Slice(start int, end int) []SortedSetMember {
+	start, end = max(start, 0), min(end, z.Len())
+	if start >= end {
+		return []SortedSetMember{}
+	}
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		return slices.Clone(z.listpack[start:end])
+	}
+	ret := make([]SortedSetMember, 0, end-start)
+	for x := z.zsl.byRank(start + 1); x != nil && len(ret) < end-start; x = x.levels[0].forward {
+		ret = append(ret, SortedSetMember{Member: x.member, Score: x.score})
+	}
+	return ret
+}
+
+// RemoveRankRange deletes the elements with ranks [start, end) and returns
+// how many were deleted.
+func (z *SortedSet) RemoveRankRange(start int, end int) int {
+	removed := z.Slice(start, end)
+	for _, m := range removed {
+		z.remove(m.Member, m.Score)
+	}
+	return len(removed)
+}
+
+// Pop removes and returns up to count elements from the low end of the set,
+// or from the high end when fromMax is set, in the order they were popped.
+func (z *SortedSet) Pop(count int, fromMax bool) []SortedSetMember {
+	var popped []SortedSetMember
+	if fromMax {
+		popped = z.Slice(z.Len()-count, z.Len())
+		slices.Reverse(popped)
+	} else {
+		popped = z.Slice(0, count)
+	}
+	for _, m := range popped {
+		z.remove(m.Member, m.Score)
+	}
+	return popped
+}
+
+var ErrRandomCountOutOfRange = errors.New("ERR value is out of range")
+
+// CheckRandomCount checks a count of random members against the range Redis
+// takes, -LONG_MAX to LONG_MAX so that a negative one can be negated, and
+// half that when each member is returned with its score.
+func CheckRandomCount(count int, withScores bool) error {
+	limit := math.MaxInt64
+	if withScores {
+		limit /= 2
+	}
+	if count < -limit || count > limit {
+		return ErrRandomCountOutOfRange
+	}
+	return nil
+}
+
+// RandomMembers returns up to count distinct elements chosen at random:
+// every element once count reaches the size of the set, and otherwise count
+// of them picked by rank.
+func (z *SortedSet) RandomMembers(count int) []SortedSetMember {
+	if count >= z.Len() {
+		return z.Slice(0, z.Len())
+	}
+	ret := make([]SortedSetMember, count)
+	for i, rank := range randomIndexes(z.Len(), count) {
+		ret[i] = z.byRank(rank)
+	}
+	return ret
+}
+
+// RandomMembersWithRepetition returns exactly count elements chosen at
+// random, possibly returning the same element more than once.
+func (z *SortedSet) RandomMembersWithRepetition(count int) []SortedSetMember {
+	if z.Len() == 0 || count <= 0 {
+		return []SortedSetMember{}
+	}
+	ret := make([]SortedSetMember, 0, min(count, RANDOM_PREALLOC_MAX))
+	for range count {
+		ret = append(ret, z.byRank(rand.Intn(z.Len())))
+	}
+	return ret
+}
+
+// byRank returns the element with the 0-based rank, which must be in range.
+func (z *SortedSet) byRank(rank int) SortedSetMember {
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		return z.listpack[rank]
+	}
+	x := z.zsl.byRank(rank + 1)
+	return SortedSetMember{Member: x.member, Score: x.score}
+}
+
+func (z *SortedSet) insert(member string, score float64) {
+	m := SortedSetMember{Member: member, Score: score}
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		idx, _ := slices.BinarySearchFunc(z.listpack, m, compareSortedSetMembers)
+		z.listpack = slices.Insert(z.listpack, idx, m)
+		return
+	}
+	z.zsl.insert(member, score)
+	z.dict[member] = score
+}
+
+func (z *SortedSet) remove(member string, score float64) {
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		idx, found := slices.BinarySearchFunc(z.listpack, SortedSetMember{Member: member, Score: score}, compareSortedSetMembers)
+		if found {
+			z.listpack = slices.Delete(z.listpack, idx, idx+1)
+		}
+		return
+	}
+	z.zsl.delete(member, score)
+	delete(z.dict, member)
+}
+
+// countWhile returns how many leading elements, in ascending order, satisfy
+// pred. pred must hold for a prefix of the set and fail for the rest, which
+// lets both encodings answer in O(log n).
+func (z *SortedSet) countWhile(pred func(SortedSetMember) bool) int {
+	if z.encoding == SORTED_SET_ENCODING_LISTPACK {
+		return sort.Search(len(z.listpack), func(i int) bool {
+			return !pred(z.listpack[i])
+		})
+	}
+	return z.zsl.countWhile(pred)
+}
+
+func (z *SortedSet) convertToSkiplist() {
+	if z.encoding == SORTED_SET_ENCODING_SKIPLIST {
+		return
+	}
+	z.zsl = newSkiplist()
+	z.dict = make(map[string]float64, len(z.listpack))
+	for _, m := range z.listpack {
+		z.zsl.insert(m.Member, m.Score)
+		z.dict[m.Member] = m.Score
+	}
+	z.listpack = nil
+	z.encoding = SORTED_SET_ENCODING_SKIPLIST
+}
@@ -0,0 +1,160 @@
+package entry
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSortedSetRank(t *testing.T) {
+	tests := []struct {
+		maxListpackEntries int
+		expectedEncoding   string
+	}{
+		{DEFAULT_ZSET_MAX_LISTPACK_ENTRIES, "listpack"},
+		{16, "skiplist"},
+	}
+
+	for _, tt := range tests {
+		z := NewSortedSet(tt.maxListpackEntries, DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+		expected := []SortedSetMember{}
+		for i := range 100 {
+			m := SortedSetMember{Member: fmt.Sprintf("m%d", i), Score: float64(rand.Intn(20))}
+			z.Add(m.Member, m.Score)
+			expected = append(expected, m)
+		}
+		for i := 0; i < 100; i += 3 {
+			member := fmt.Sprintf("m%d", i)
+			z.Remove(member)
+			expected = slices.DeleteFunc(expected, func(m SortedSetMember) bool {
+				return m.Member == member
+			})
+		}
+		z.Add("m1", 100)
+		expected = slices.DeleteFunc(expected, func(m SortedSetMember) bool {
+			return m.Member == "m1"
+		})
+		expected = append(expected, SortedSetMember{Member: "m1", Score: 100})
+		slices.SortFunc(expected, compareSortedSetMembers)
+
+		if z.Encoding() != tt.expectedEncoding {
+			t.Errorf("Expected encoding %s; got %s", tt.expectedEncoding, z.Encoding())
+		}
+		if z.Len() != len(expected) {
+			t.Errorf("Expected len %d; got %d", len(expected), z.Len())
+		}
+		for i, m := range expected {
+			rank, ok := z.Rank(m.Member, false)
+			if !ok || rank != i {
+				t.Errorf("Expected rank %d for %s; got %d", i, m.Member, rank)
+			}
+			revRank, _ := z.Rank(m.Member, true)
+			if revRank != len(expected)-1-i {
+				t.Errorf("Expected reverse rank %d for %s; got %d", len(expected)-1-i, m.Member, revRank)
+			}
+		}
+	}
+}
+
+func TestSortedSetRanges(t *testing.T) {
+	for _, maxListpackEntries := range []int{DEFAULT_ZSET_MAX_LISTPACK_ENTRIES, 2} {
+		z := NewSortedSet(maxListpackEntries, DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+		for i, member := range []string{"a", "b", "c", "d", "e"} {
+			z.Add(member, float64(i+1))
+		}
+		tests := []struct {
+			name          string
+			rankRange     func(z *SortedSet) (int, int)
+			expectedStart int
+			expectedEnd   int
+		}{
+			{"inclusive scores", func(z *SortedSet) (int, int) {
+				return z.ScoreRankRange(ScoreRange{Min: 2, Max: 4})
+			}, 1, 4},
+			{"exclusive scores", func(z *SortedSet) (int, int) {
+				return z.ScoreRankRange(ScoreRange{Min: 2, Max: 4, MinExclusive: true, MaxExclusive: true})
+			}, 2, 3},
+			{"empty scores", func(z *SortedSet) (int, int) {
+				return z.ScoreRankRange(ScoreRange{Min: 10, Max: 1})
+			}, 5, 5},
+			{"lex from b", func(z *SortedSet) (int, int) {
+				return z.LexRankRange(LexRange{Min: LexBound{Value: "b"}, Max: LexBound{Inf: 1}})
+			}, 1, 5},
+		}
+		for _, tt := range tests {
+			if start, end := tt.rankRange(z); start != tt.expectedStart || end != tt.expectedEnd {
+				t.Errorf("%s (%s): expected [%d, %d); got [%d, %d)", tt.name, z.Encoding(),
+					tt.expectedStart, tt.expectedEnd, start, end)
+			}
+		}
+
+		got := []string{}
+		for _, m := range z.Slice(1, 4) {
+			got = append(got, m.Member)
+		}
+		if !slices.Equal(got, []string{"b", "c", "d"}) {
+			t.Errorf("Expected slice [b c d] (%s); got %v", z.Encoding(), got)
+		}
+		if removed := z.RemoveRankRange(0, 2); removed != 2 || z.Len() != 3 {
+			t.Errorf("Expected to remove 2 leaving 3 (%s); removed %d leaving %d", z.Encoding(), removed, z.Len())
+		}
+	}
+}
+
+func TestSortedSetRandomMembersWithRepetition(t *testing.T) {
+	z := NewSortedSet(DEFAULT_ZSET_MAX_LISTPACK_ENTRIES, DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+	z.Add("a", 1)
+	if got := z.RandomMembersWithRepetition(2); !slices.Equal(got, []SortedSetMember{{"a", 1}, {"a", 1}}) {
+		t.Errorf("Expected a twice; got %v", got)
+	}
+	if got := z.RandomMembersWithRepetition(math.MinInt64); len(got) != 0 {
+		t.Errorf("Expected no members for a negative count; got %v", got)
+	}
+}
+
+func TestSortedSetRandomMembers(t *testing.T) {
+	for _, maxListpackEntries := range []int{DEFAULT_ZSET_MAX_LISTPACK_ENTRIES, 1} {
+		z := NewSortedSet(maxListpackEntries, DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+		for i, m := range []string{"a", "b", "c", "d", "e"} {
+			z.Add(m, float64(i))
+		}
+		for count := range 7 {
+			got := z.RandomMembers(count)
+			seen := map[string]bool{}
+			for _, m := range got {
+				if score, ok := z.Score(m.Member); !ok || score != m.Score || seen[m.Member] {
+					t.Errorf("%s: expected distinct members of the set; got %v", z.Encoding(), got)
+				}
+				seen[m.Member] = true
+			}
+			if len(got) != min(count, 5) {
+				t.Errorf("%s: expected %d members; got %v", z.Encoding(), min(count, 5), got)
+			}
+		}
+	}
+}
+
+func TestCheckRandomCount(t *testing.T) {
+	tests := []struct {
+		count      int
+		withScores bool
+		valid      bool
+	}{
+		{-1, false, true},
+		{-math.MaxInt64, false, true},
+		{math.MaxInt64, false, true},
+		{math.MinInt64, false, false},
+		{-math.MaxInt64 / 2, true, true},
+		{math.MaxInt64 / 2, true, true},
+		{-math.MaxInt64/2 - 1, true, false},
+		{math.MaxInt64/2 + 1, true, false},
+		{math.MinInt64, true, false},
+	}
+	for _, tt := range tests {
+		if err := CheckRandomCount(tt.count, tt.withScores); (err == nil) != tt.valid {
+			t.Errorf("Expected count %d with scores %v to be valid: %v; got %v", tt.count, tt.withScores, tt.valid, err)
+		}
+	}
+}
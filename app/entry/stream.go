@@ -1,37 +1,89 @@
 package entry
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"math"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/app/protocol"
-	"github.com/google/btree"
 )
 
-type streamID struct {
+type StreamID struct {
 	millisecondsTime int
 	sequenceNumber   int
 }
 
-func (id *streamID) String() string {
+func (id *StreamID) String() string {
 	return fmt.Sprintf("%d-%d", id.millisecondsTime, id.sequenceNumber)
 }
 
-func (id *streamID) isZero() bool {
+func (id *StreamID) MillisecondsTime() int {
+	return id.millisecondsTime
+}
+
+func (id *StreamID) SequenceNumber() int {
+	return id.sequenceNumber
+}
+
+func (id *StreamID) isZero() bool {
 	return id.millisecondsTime == 0 && id.sequenceNumber == 0
 }
 
+// next returns the smallest ID greater than id, or nil if there is none.
+func (id *StreamID) next() *StreamID {
+	if id.sequenceNumber < math.MaxInt {
+		return NewStreamID(id.millisecondsTime, id.sequenceNumber+1)
+	}
+	if id.millisecondsTime < math.MaxInt {
+		return NewStreamID(id.millisecondsTime+1, 0)
+	}
+	return nil
+}
+
+// prev returns the largest ID smaller than id, or nil if there is none.
+func (id *StreamID) prev() *StreamID {
+	if id.sequenceNumber > 0 {
+		return NewStreamID(id.millisecondsTime, id.sequenceNumber-1)
+	}
+	if id.millisecondsTime > 0 {
+		return NewStreamID(id.millisecondsTime-1, math.MaxInt)
+	}
+	return nil
+}
+
+func (id *StreamID) compare(other *StreamID) int {
+	if id.millisecondsTime != other.millisecondsTime {
+		return cmp.Compare(id.millisecondsTime, other.millisecondsTime)
+	}
+	return cmp.Compare(id.sequenceNumber, other.sequenceNumber)
+}
+
+// Stream keeps its entries in macro nodes of at most nodeMaxBytes bytes and
+// nodeMaxEntries entries, indexed by a radix tree on the ID of their first
+// entry, and the Redis 7 metadata alongside them: topID is the last ID ever
+// handed out, bottomID the recorded first entry ID (zero while the stream is
+// empty), entriesAdded counts every entry ever added and maxDeletedID is the
+// largest ID removed by XDEL. groups holds its consumer groups by name.
 type Stream struct {
-	data                          *btree.BTree
-	dataLock                      sync.RWMutex
-	bottomID                      *streamID
-	topID                         *streamID
-	highSequenceNumberPerTime     map[int]int
-	highSequenceNumberPerTimeLock sync.RWMutex
+	cow
+	expiry
+	index          *rax
+	length         int
+	nodeMaxBytes   int
+	nodeMaxEntries int
+	dataLock       sync.RWMutex
+	bottomID       *StreamID
+	topID          *StreamID
+	entriesAdded   int
+	maxDeletedID   *StreamID
+	groups         map[string]*StreamGroup
 }
 
 func (s *Stream) Type() string {
@@ -43,65 +95,488 @@ type KeyValue struct {
 	Value string
 }
 
+// StreamItem is an entry decoded from the node it is stored in.
 type StreamItem struct {
-	id     *streamID
+	id     *StreamID
 	fields []*KeyValue // We need fields to be deterministic for testing
-	lock   sync.RWMutex
 }
 
-func newStreamItem(id *streamID) *StreamItem {
-	return &StreamItem{
-		id:     id,
-		fields: []*KeyValue{},
+func NewStream(nodeMaxBytes int, nodeMaxEntries int) *Stream {
+	return &Stream{
+		cow:            newCow(),
+		index:          newRax(),
+		nodeMaxBytes:   nodeMaxBytes,
+		nodeMaxEntries: nodeMaxEntries,
+		topID:          &StreamID{millisecondsTime: 0, sequenceNumber: 0},
+		bottomID:       &StreamID{millisecondsTime: 0, sequenceNumber: 0},
+		maxDeletedID:   &StreamID{millisecondsTime: 0, sequenceNumber: 0},
+		groups:         make(map[string]*StreamGroup),
 	}
 }
 
-func (si *StreamItem) Less(than btree.Item) bool {
-	thanAsStream := than.(*StreamItem)
-	if si.id.millisecondsTime != thanAsStream.id.millisecondsTime {
-		return si.id.millisecondsTime < thanAsStream.id.millisecondsTime
+// Add appends an entry to the last node, starting a new node when that one
+// is full.
+func (s *Stream) Add(idStr string, fields []*KeyValue) (*StreamID, error) {
+	id, err := s.validateID(idStr)
+	if err != nil {
+		return nil, err
+	}
+	s.dataLock.Lock()
+	defer s.dataLock.Unlock()
+	node := s.index.last()
+	var encoded []byte
+	if node != nil {
+		encoded = node.encode(id, fields)
+	}
+	if node == nil || !node.fits(len(encoded), s.nodeMaxBytes, s.nodeMaxEntries) {
+		node = newStreamNode(id, fields)
+		s.index.insert(node.key(), node)
+		encoded = node.encode(id, fields)
+	}
+	node.append(encoded)
+	s.length++
+	if s.bottomID.isZero() {
+		s.bottomID = id
 	}
-	return si.id.sequenceNumber < thanAsStream.id.sequenceNumber
+	s.topID = id
+	s.entriesAdded++
+	return id, nil
 }
 
-func (si *StreamItem) AddField(field string, value string) *StreamItem {
-	si.lock.Lock()
-	defer si.lock.Unlock()
-	si.fields = append(si.fields, &KeyValue{Key: field, Value: value})
-	return si
+func (s *Stream) Len() int {
+	s.dataLock.RLock()
+	defer s.dataLock.RUnlock()
+	return s.length
 }
 
-func NewStream() *Stream {
-	return &Stream{
-		data:                      btree.New(32),
-		topID:                     &streamID{millisecondsTime: 0, sequenceNumber: 0},
-		bottomID:                  &streamID{millisecondsTime: 0, sequenceNumber: 0},
-		highSequenceNumberPerTime: make(map[int]int),
+// RadixTreeKeys returns the number of nodes the entries are stored in.
+func (s *Stream) RadixTreeKeys() int {
+	return s.index.keys
+}
+
+// RadixTreeNodes returns the number of nodes of the radix tree indexing them.
+func (s *Stream) RadixTreeNodes() int {
+	return s.index.nodes
+}
+
+// Nodes calls fn with the master ID, the master field names and the entries,
+// deleted ones included, of every node in order until fn returns false.
+func (s *Stream) Nodes(fn func(master *StreamID, masterFields []string, items []StreamNodeItem) bool) {
+	s.dataLock.RLock()
+	defer s.dataLock.RUnlock()
+	s.index.ascend(streamIDKey(NewStreamID(0, 0)), func(n *streamNode) bool {
+		master := n.master
+		return fn(&master, slices.Clone(n.masterFields), n.items())
+	})
+}
+
+// LoadNode adds a node read back from a snapshot: master is the ID its
+// entries are stored relative to, masterFields the field names of its master
+// entry and items its entries in order, deleted ones included. They must all
+// come after the entries already in the stream. A node without live entries
+// is dropped, as it would have been when its last entry was deleted.
+func (s *Stream) LoadNode(master *StreamID, masterFields []string, items []StreamNodeItem) error {
+	s.dataLock.Lock()
+	defer s.dataLock.Unlock()
+	if len(items) == 0 {
+		return fmt.Errorf("stream node %s has no entries", master)
+	}
+	if last := s.index.last(); last != nil {
+		if entries := last.entries(); master.compare(entries[len(entries)-1].id) <= 0 {
+			return fmt.Errorf("stream node %s is out of order", master)
+		}
 	}
+	n := &streamNode{master: *master, masterFields: masterFields}
+	prev := master
+	for i, item := range items {
+		if c := item.ID.compare(prev); c < 0 || (c == 0 && i > 0) {
+			return fmt.Errorf("stream entry %s is out of order", item.ID)
+		}
+		prev = item.ID
+		offset := len(n.data)
+		n.append(n.encode(item.ID, item.Fields))
+		if item.Deleted {
+			n.markDeleted(streamNodeEntry{offset: offset})
+		}
+	}
+	if n.live == 0 {
+		return nil
+	}
+	s.index.insert(n.key(), n)
+	s.length += n.live
+	if s.bottomID.isZero() {
+		s.updateBottomID()
+	}
+	if prev.compare(s.topID) > 0 {
+		s.topID = prev
+	}
+	return nil
 }
 
-func (s *Stream) Add(idStr string, field string, value string) (*streamID, error) {
-	id, err := s.validateID(idStr)
-	if err != nil {
-		return nil, err
+// Clone returns a copy of the stream and its groups that can be read while
+// the original keeps changing.
+func (s *Stream) Clone() *Stream {
+	s.dataLock.RLock()
+	defer s.dataLock.RUnlock()
+	c := NewStream(s.nodeMaxBytes, s.nodeMaxEntries)
+	s.index.ascend(streamIDKey(NewStreamID(0, 0)), func(n *streamNode) bool {
+		copied := *n
+		copied.data = slices.Clone(n.data)
+		c.index.insert(copied.key(), &copied)
+		return true
+	})
+	c.expiry = s.expiry
+	c.length = s.length
+	c.bottomID = s.bottomID
+	c.topID = s.topID
+	c.entriesAdded = s.entriesAdded
+	c.maxDeletedID = s.maxDeletedID
+	for name, g := range s.groups {
+		c.groups[name] = g.clone()
+	}
+	return c
+}
+
+// ascendEntries calls fn with the live entries with IDs from start on, in
+// order, until fn returns false.
+func (s *Stream) ascendEntries(start *StreamID, fn func(*streamNode, streamNodeEntry) bool) {
+	from := streamIDKey(start)
+	s.index.descend(from, func(n *streamNode) bool {
+		from = n.key()
+		return false
+	})
+	s.index.ascend(from, func(n *streamNode) bool {
+		for _, e := range n.entries() {
+			if e.deleted || e.id.compare(start) < 0 {
+				continue
+			}
+			if !fn(n, e) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// descendEntries calls fn with the live entries with IDs up to end, in
+// reverse order, until fn returns false.
+func (s *Stream) descendEntries(end *StreamID, fn func(*streamNode, streamNodeEntry) bool) {
+	s.index.descend(streamIDKey(end), func(n *streamNode) bool {
+		entries := n.entries()
+		for i := len(entries) - 1; i >= 0; i-- {
+			if entries[i].deleted || entries[i].id.compare(end) > 0 {
+				continue
+			}
+			if !fn(n, entries[i]) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// findEntry returns the node holding the live entry with the given ID, and
+// the entry.
+func (s *Stream) findEntry(id *StreamID) (*streamNode, streamNodeEntry, bool) {
+	var node *streamNode
+	s.index.descend(streamIDKey(id), func(n *streamNode) bool {
+		node = n
+		return false
+	})
+	if node == nil {
+		return nil, streamNodeEntry{}, false
+	}
+	e, ok := node.find(id)
+	return node, e, ok
+}
+
+// deleteEntry flags e as deleted, dropping its node once it has no entries
+// left.
+func (s *Stream) deleteEntry(n *streamNode, e streamNodeEntry) {
+	n.markDeleted(e)
+	s.length--
+	if n.live == 0 {
+		s.index.remove(n.key())
+	}
+}
+
+type StreamTrimStrategy int
+
+const (
+	STREAM_TRIM_NONE StreamTrimStrategy = iota
+	STREAM_TRIM_MAXLEN
+	STREAM_TRIM_MINID
+)
+
+// StreamRefPolicy says what deleting an entry does about the consumer groups
+// that still refer to it.
+type StreamRefPolicy int
+
+const (
+	// STREAM_REF_KEEPREF deletes the entry and leaves its pending entries
+	// dangling.
+	STREAM_REF_KEEPREF StreamRefPolicy = iota
+	// STREAM_REF_DELREF deletes the entry along with its pending entries in
+	// every group.
+	STREAM_REF_DELREF
+	// STREAM_REF_ACKED only deletes the entry once every group has read and
+	// acknowledged it.
+	STREAM_REF_ACKED
+)
+
+func (p StreamRefPolicy) String() string {
+	return [...]string{"KEEPREF", "DELREF", "ACKED"}[p]
+}
+
+// StreamTrim describes how to trim a stream: down to MaxLen entries, or
+// removing every entry with an ID below MinID. An approximate trim only
+// removes whole nodes. Limit, when positive, caps the number of entries
+// removed, and Policy decides what happens to entries groups still refer to.
+type StreamTrim struct {
+	Strategy StreamTrimStrategy
+	Approx   bool
+	MaxLen   int
+	MinID    *StreamID
+	Limit    int
+	Policy   StreamRefPolicy
+}
+
+// trimNodes removes whole nodes from the start of the stream, as Redis does
+// for an approximate trim. It stops at the first node that holds an entry to
+// keep, that would take it past the limit or, under the ACKED policy, that
+// holds an entry groups still refer to.
+func (s *Stream) trimNodes(t StreamTrim) int {
+	removed := 0
+	nodes := []*streamNode{}
+	s.index.ascend(nil, func(n *streamNode) bool {
+		if t.Limit > 0 && removed+n.live > t.Limit {
+			return false
+		}
+		entries := n.entries()
+		if t.Strategy == STREAM_TRIM_MAXLEN && s.length-removed-n.live < t.MaxLen {
+			return false
+		}
+		if t.Strategy == STREAM_TRIM_MINID && entries[len(entries)-1].id.compare(t.MinID) >= 0 {
+			return false
+		}
+		if t.Policy == STREAM_REF_ACKED && slices.ContainsFunc(entries, func(e streamNodeEntry) bool {
+			return !e.deleted && s.isReferenced(e.id)
+		}) {
+			return false
+		}
+		nodes = append(nodes, n)
+		removed += n.live
+		return true
+	})
+	for _, n := range nodes {
+		if t.Policy == STREAM_REF_DELREF {
+			for _, e := range n.entries() {
+				if !e.deleted {
+					s.dropReferences(e.id)
+				}
+			}
+		}
+		s.index.remove(n.key())
+		s.length -= n.live
+	}
+	return removed
+}
+
+// Trim removes entries from the start of the stream as described by t and
+// returns how many were removed. Under the ACKED policy entries that are
+// still referenced are skipped rather than stopping the trim.
+func (s *Stream) Trim(t StreamTrim) int {
+	if t.Strategy == STREAM_TRIM_NONE {
+		return 0
 	}
 	s.dataLock.Lock()
-	new := newStreamItem(id).AddField(field, value)
-	if s.bottomID.isZero() {
-		s.bottomID = id
+	defer s.dataLock.Unlock()
+	if t.Approx {
+		removed := s.trimNodes(t)
+		s.updateBottomID()
+		return removed
 	}
-	s.topID = id
-	s.data.ReplaceOrInsert(new)
-	s.highSequenceNumberPerTimeLock.Lock()
-	s.highSequenceNumberPerTime[id.millisecondsTime] = id.sequenceNumber
-	s.highSequenceNumberPerTimeLock.Unlock()
-	s.dataLock.Unlock()
+	type nodeEntry struct {
+		node  *streamNode
+		entry streamNodeEntry
+	}
+	removed := []nodeEntry{}
+	s.ascendEntries(NewStreamID(0, 0), func(n *streamNode, e streamNodeEntry) bool {
+		id := e.id
+		if t.Limit > 0 && len(removed) >= t.Limit {
+			return false
+		}
+		if t.Strategy == STREAM_TRIM_MAXLEN && s.length-len(removed) <= t.MaxLen {
+			return false
+		}
+		if t.Strategy == STREAM_TRIM_MINID && id.compare(t.MinID) >= 0 {
+			return false
+		}
+		if t.Policy != STREAM_REF_ACKED || !s.isReferenced(id) {
+			removed = append(removed, nodeEntry{n, e})
+		}
+		return true
+	})
+	for _, r := range removed {
+		s.deleteEntry(r.node, r.entry)
+		if t.Policy == STREAM_REF_DELREF {
+			s.dropReferences(r.entry.id)
+		}
+	}
+	s.updateBottomID()
+	return len(removed)
+}
+
+// DeleteWithPolicy deletes the entries with the given IDs as policy allows.
+// For each ID it returns -1 if there was no such entry, 1 if it was deleted
+// and 2 if the ACKED policy kept it.
+func (s *Stream) DeleteWithPolicy(ids []*StreamID, policy StreamRefPolicy) []int {
+	ret := make([]int, len(ids))
+	for i, id := range ids {
+		switch {
+		case s.lookup(id) == nil:
+			ret[i] = -1
+		case policy == STREAM_REF_ACKED && s.isReferenced(id):
+			ret[i] = 2
+		default:
+			s.Delete([]*StreamID{id})
+			if policy == STREAM_REF_DELREF {
+				s.dropReferences(id)
+			}
+			ret[i] = 1
+		}
+	}
+	return ret
+}
+
+// FirstID returns the ID of the first entry, or nil if the stream is empty.
+func (s *Stream) FirstID() *StreamID {
+	s.dataLock.RLock()
+	defer s.dataLock.RUnlock()
+	if s.length == 0 {
+		return nil
+	}
+	return s.bottomID
+}
+
+// lookup returns the entry with the given ID, or nil if there is none.
+func (s *Stream) lookup(id *StreamID) *StreamItem {
+	s.dataLock.RLock()
+	defer s.dataLock.RUnlock()
+	if n, e, ok := s.findEntry(id); ok {
+		return n.item(e)
+	}
+	return nil
+}
+
+func (s *Stream) LastID() *StreamID {
+	return s.topID
+}
+
+func (s *Stream) EntriesAdded() int {
+	return s.entriesAdded
+}
+
+func (s *Stream) MaxDeletedID() *StreamID {
+	return s.maxDeletedID
+}
+
+// Delete removes the entries with the given IDs and returns how many existed.
+func (s *Stream) Delete(ids []*StreamID) int {
+	s.dataLock.Lock()
+	defer s.dataLock.Unlock()
+	deleted := 0
+	for _, id := range ids {
+		n, e, ok := s.findEntry(id)
+		if !ok {
+			continue
+		}
+		s.deleteEntry(n, e)
+		deleted++
+		if id.compare(s.maxDeletedID) > 0 {
+			s.maxDeletedID = id
+		}
+	}
+	if deleted > 0 {
+		s.updateBottomID()
+	}
+	return deleted
+}
+
+const (
+	setIDSmallerThanTopItemStr      string = "ERR The ID specified in XSETID is smaller than the target stream top item"
+	setIDEntriesAddedTooSmallStr    string = "ERR The entries_added specified in XSETID is smaller than the target stream length"
+	setIDSmallerThanMaxDeletedIDStr string = "ERR The ID specified in XSETID is smaller than the provided max_deleted_entry_id"
+)
+
+// SetID moves the last ID of the stream to lastID, which may not be below the
+// last entry. A negative entriesAdded and a nil maxDeletedID leave those
+// fields unchanged.
+func (s *Stream) SetID(lastID *StreamID, entriesAdded int, maxDeletedID *StreamID) error {
+	s.dataLock.Lock()
+	defer s.dataLock.Unlock()
+	if lastEntryID := s.lastEntryID(); lastEntryID != nil && lastID.compare(lastEntryID) < 0 {
+		return errors.New(setIDSmallerThanTopItemStr)
+	}
+	if entriesAdded >= 0 && entriesAdded < s.length {
+		return errors.New(setIDEntriesAddedTooSmallStr)
+	}
+	if maxDeletedID != nil && lastID.compare(maxDeletedID) < 0 {
+		return errors.New(setIDSmallerThanMaxDeletedIDStr)
+	}
+	s.topID = lastID
+	if entriesAdded >= 0 {
+		s.entriesAdded = entriesAdded
+	}
+	if maxDeletedID != nil {
+		s.maxDeletedID = maxDeletedID
+	}
+	return nil
+}
+
+func (s *Stream) updateBottomID() {
+	s.bottomID = NewStreamID(0, 0)
+	s.ascendEntries(NewStreamID(0, 0), func(n *streamNode, e streamNodeEntry) bool {
+		s.bottomID = e.id
+		return false
+	})
+}
+
+// lastEntryID returns the ID of the last entry, or nil if the stream is
+// empty. Unlike LastID it ignores IDs handed out to entries since deleted.
+func (s *Stream) lastEntryID() *StreamID {
+	var id *StreamID
+	s.descendEntries(NewStreamID(math.MaxInt, math.MaxInt), func(n *streamNode, e streamNodeEntry) bool {
+		id = e.id
+		return false
+	})
+	return id
+}
+
+const invalidStreamIDStr string = "ERR Invalid stream ID specified as stream command argument"
 
-	return new.id, nil
+// ParseStreamID parses an ID given as an argument other than the one of
+// XADD, where the sequence number may be left out and defaults to 0.
+func ParseStreamID(idStr string) (*StreamID, error) {
+	if match := fullIDRe.FindStringSubmatch(idStr); match != nil {
+		millisecondsTime, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, errors.New(invalidStreamIDStr)
+		}
+		sequenceNumber, err := strconv.Atoi(match[2])
+		if err != nil {
+			return nil, errors.New(invalidStreamIDStr)
+		}
+		return NewStreamID(millisecondsTime, sequenceNumber), nil
+	}
+	if millisecondsTime, err := strconv.Atoi(idStr); err == nil && millisecondsTime >= 0 {
+		return NewStreamID(millisecondsTime, 0), nil
+	}
+	return nil, errors.New(invalidStreamIDStr)
 }
 
-func NewStreamID(m int, sn int) *streamID {
-	return &streamID{
+func NewStreamID(m int, sn int) *StreamID {
+	return &StreamID{
 		millisecondsTime: m,
 		sequenceNumber:   sn,
 	}
@@ -113,13 +588,23 @@ func (s StreamRangeData) Encoded() []byte {
 	ret := []byte{}
 	ret = append(ret, []byte(fmt.Sprintf("*%d\r\n", len(s)))...)
 	for _, item := range s {
-		ret = append(ret, []byte("*2\r\n")...)
-		ret = append(ret, protocol.ToBulkString(item.id.String())...)
-		ret = append(ret, []byte(fmt.Sprintf("*%d\r\n", 2*len(item.fields)))...)
-		for _, kv := range item.fields {
-			ret = append(ret, protocol.ToBulkString(kv.Key)...)
-			ret = append(ret, protocol.ToBulkString(kv.Value)...)
-		}
+		ret = append(ret, item.Encoded()...)
+	}
+	return ret
+}
+
+// Encoded returns the entry as its ID and its fields, which are nil for an
+// entry that was deleted while still pending in a group.
+func (si *StreamItem) Encoded() []byte {
+	ret := []byte("*2\r\n")
+	ret = append(ret, protocol.ToBulkString(si.id.String())...)
+	if si.fields == nil {
+		return append(ret, protocol.NullArray()...)
+	}
+	ret = append(ret, []byte(fmt.Sprintf("*%d\r\n", 2*len(si.fields)))...)
+	for _, kv := range si.fields {
+		ret = append(ret, protocol.ToBulkString(kv.Key)...)
+		ret = append(ret, protocol.ToBulkString(kv.Value)...)
 	}
 	return ret
 }
@@ -130,10 +615,11 @@ var partialIDRe = regexp.MustCompile(`^(\d+)-\*$`)
 const invalidIDFormatStr string = "ERR id not in format <millisecondsTime>-<sequenceNumber|*> | *"
 const invalidIDNotGreaterThanTopItem string = "ERR The ID specified in XADD is equal or smaller than the target stream top item"
 const invalidIDNotGreaterThanZero string = "ERR The ID specified in XADD must be greater than 0-0"
+const streamIDsExhaustedStr string = "ERR The stream has exhausted the last possible ID, unable to add more items"
 
-func (s *Stream) validateID(id string) (*streamID, error) {
+func (s *Stream) validateID(id string) (*StreamID, error) {
 	if id == "*" {
-		return s.generateID(), nil
+		return s.generateID()
 	}
 	if match := partialIDRe.FindStringSubmatch(id); match != nil {
 		return s.validatePartialID(match[1])
@@ -144,19 +630,26 @@ func (s *Stream) validateID(id string) (*streamID, error) {
 	return nil, errors.New(invalidIDFormatStr)
 }
 
-func (s *Stream) generateID() *streamID {
+// generateID returns the ID of the current time, or the one after the top
+// ID while that is ahead of the clock, as after XSETID into the future or
+// the clock stepping back. Like Redis, the time moves on by 1ms once the
+// sequence numbers of the top ID's time are used up.
+func (s *Stream) generateID() (*StreamID, error) {
 	millisecondsTime := int(time.Now().UnixMilli())
-	if millisecondsTime == s.topID.millisecondsTime {
-		return NewStreamID(millisecondsTime, s.topID.sequenceNumber+1)
+	if millisecondsTime > s.topID.millisecondsTime {
+		return NewStreamID(millisecondsTime, 0), nil
 	}
-	sequenceNumber := 0
-	if millisecondsTime == 0 { // In edge case it is zero
-		sequenceNumber = 1
+	top := s.topID
+	switch {
+	case top.sequenceNumber < math.MaxInt:
+		return NewStreamID(top.millisecondsTime, top.sequenceNumber+1), nil
+	case top.millisecondsTime < math.MaxInt:
+		return NewStreamID(top.millisecondsTime+1, 0), nil
 	}
-	return NewStreamID(millisecondsTime, sequenceNumber)
+	return nil, errors.New(streamIDsExhaustedStr)
 }
 
-func (s *Stream) validatePartialID(millisecondsTimeStr string) (*streamID, error) {
+func (s *Stream) validatePartialID(millisecondsTimeStr string) (*StreamID, error) {
 	millisecondsTime, err := strconv.Atoi(millisecondsTimeStr)
 	if err != nil {
 		return nil, errors.New(invalidIDFormatStr)
@@ -168,6 +661,9 @@ func (s *Stream) validatePartialID(millisecondsTimeStr string) (*streamID, error
 		return nil, errors.New(invalidIDNotGreaterThanTopItem)
 	}
 	if millisecondsTime == s.topID.millisecondsTime {
+		if s.topID.sequenceNumber == math.MaxInt {
+			return nil, errors.New(invalidIDNotGreaterThanTopItem)
+		}
 		return NewStreamID(millisecondsTime, s.topID.sequenceNumber+1), nil
 	}
 	sequenceNumber := 0
@@ -177,7 +673,7 @@ func (s *Stream) validatePartialID(millisecondsTimeStr string) (*streamID, error
 	return NewStreamID(millisecondsTime, sequenceNumber), nil
 }
 
-func (s *Stream) validateFullID(millisecondsTimeStr string, sequenceNumberStr string) (*streamID, error) {
+func (s *Stream) validateFullID(millisecondsTimeStr string, sequenceNumberStr string) (*StreamID, error) {
 	millisecondsTime, sequenceNumber, err := validateIDFormat(millisecondsTimeStr, sequenceNumberStr)
 	if err != nil {
 		return nil, err
@@ -207,66 +703,109 @@ func validateIDFormat(millisecondsTimeStr string, sequenceNumberStr string) (int
 	return millisecondsTime, sequenceNumber, nil
 }
 
-func (s *Stream) GetDataFromRange(startStr string, endStr string) (StreamRangeData, error) {
-	if s.data.Len() == 0 {
-		return StreamRangeData{}, nil
-	}
-	startID, err := s.createStartStreamID(startStr)
+const (
+	invalidStartIDStr string = "ERR invalid start ID for the interval"
+	invalidEndIDStr   string = "ERR invalid end ID for the interval"
+)
+
+// GetDataFromRange returns up to count entries (every one of them when count
+// is negative) in the interval given by startStr and endStr, walking it from
+// the end when reverse is set.
+func (s *Stream) GetDataFromRange(startStr string, endStr string, count int, reverse bool) (StreamRangeData, error) {
+	startID, endID, err := ParseStreamInterval(startStr, endStr)
 	if err != nil {
 		return nil, err
 	}
-	endID, err := s.createEndStreamID(endStr)
+	return s.Range(startID, endID, count, reverse), nil
+}
+
+// ParseStreamInterval parses the bounds of an interval of IDs, both inclusive
+// unless prefixed with "(". "-" and "+" stand for the smallest and largest
+// possible IDs, and an ID without a sequence number covers the whole
+// millisecond.
+func ParseStreamInterval(startStr string, endStr string) (*StreamID, *StreamID, error) {
+	startID, err := parseRangeID(startStr, false)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	var result []*StreamItem
-	s.dataLock.Lock()
-	defer s.dataLock.Unlock()
-	s.data.AscendGreaterOrEqual(newStreamItem(startID), func(item btree.Item) bool {
-		if newStreamItem(endID).Less(item) {
-			return false
+	endID, err := parseRangeID(endStr, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.HasPrefix(startStr, "(") {
+		if startID = startID.next(); startID == nil {
+			return nil, nil, errors.New(invalidStartIDStr)
 		}
-		result = append(result, item.(*StreamItem))
-		return true
-	})
-	return result, nil
+	}
+	if strings.HasPrefix(endStr, "(") {
+		if endID = endID.prev(); endID == nil {
+			return nil, nil, errors.New(invalidEndIDStr)
+		}
+	}
+	return startID, endID, nil
 }
 
-func (s *Stream) createStartStreamID(startStr string) (*streamID, error) {
-	if startStr == "-" {
-		return s.bottomID, nil
+// Range returns up to count entries with IDs in [start, end], or every one of
+// them when count is negative, in descending order when reverse is set.
+func (s *Stream) Range(start *StreamID, end *StreamID, count int, reverse bool) StreamRangeData {
+	result := StreamRangeData{}
+	if count == 0 || start.compare(end) > 0 {
+		return result
 	}
-	match := fullIDRe.FindStringSubmatch(startStr)
-	if match != nil {
-		millisecondsTime, sequenceNum, err := validateIDFormat(match[1], match[2])
-		if err != nil {
-			return nil, err
+	s.dataLock.RLock()
+	defer s.dataLock.RUnlock()
+	inRange := func(n *streamNode, e streamNodeEntry) bool {
+		if e.id.compare(start) < 0 || e.id.compare(end) > 0 {
+			return false
 		}
-		return NewStreamID(millisecondsTime, sequenceNum), nil
+		result = append(result, n.item(e))
+		return count < 0 || len(result) < count
 	}
-	if ms, err := strconv.Atoi(startStr); err == nil {
-		return NewStreamID(ms, 0), nil
+	if reverse {
+		s.descendEntries(end, inRange)
+	} else {
+		s.ascendEntries(start, inRange)
 	}
-	return nil, errors.New(invalidIDFormatStr)
+	return result
 }
 
-func (s *Stream) createEndStreamID(endStr string) (*streamID, error) {
-	if endStr == "+" {
-		return s.topID, nil
+// EntriesAfter returns up to count entries with IDs greater than id, or every
+// one of them when count is negative.
+func (s *Stream) EntriesAfter(id *StreamID, count int) StreamRangeData {
+	start := id.next()
+	if start == nil {
+		return StreamRangeData{}
 	}
-	match := fullIDRe.FindStringSubmatch(endStr)
-	if match != nil {
-		millisecondsTime, sequenceNum, err := validateIDFormat(match[1], match[2])
-		if err != nil {
-			return nil, err
-		}
-		return NewStreamID(millisecondsTime, sequenceNum), nil
+	return s.Range(start, NewStreamID(math.MaxInt, math.MaxInt), count, false)
+}
+
+// FirstEntry returns the first entry of the stream, or nothing if it is
+// empty.
+func (s *Stream) FirstEntry() StreamRangeData {
+	return s.Range(NewStreamID(0, 0), NewStreamID(math.MaxInt, math.MaxInt), 1, false)
+}
+
+// LastEntry returns the last entry of the stream, or nothing if it is empty.
+func (s *Stream) LastEntry() StreamRangeData {
+	return s.Range(NewStreamID(0, 0), NewStreamID(math.MaxInt, math.MaxInt), 1, true)
+}
+
+// parseRangeID parses one end of an XRANGE interval, ignoring a leading "(".
+func parseRangeID(idStr string, isEnd bool) (*StreamID, error) {
+	exclusive := strings.HasPrefix(idStr, "(")
+	idStr = strings.TrimPrefix(idStr, "(")
+	if !exclusive && idStr == "-" {
+		return NewStreamID(0, 0), nil
 	}
-	if ms, err := strconv.Atoi(endStr); err == nil {
-		if sn, ok := s.highSequenceNumberPerTime[ms]; ok {
-			return NewStreamID(ms, sn), nil
-		}
-		return NewStreamID(ms, 0), nil
+	if !exclusive && idStr == "+" {
+		return NewStreamID(math.MaxInt, math.MaxInt), nil
 	}
-	return nil, errors.New(invalidIDFormatStr)
+	id, err := ParseStreamID(idStr)
+	if err != nil {
+		return nil, err
+	}
+	if isEnd && !strings.Contains(idStr, "-") {
+		id.sequenceNumber = math.MaxInt
+	}
+	return id, nil
 }
@@ -0,0 +1,318 @@
+package entry
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+)
+
+func newTestStream(t *testing.T, ids ...string) *Stream {
+	s := NewStream(DEFAULT_STREAM_NODE_MAX_BYTES, DEFAULT_STREAM_NODE_MAX_ENTRIES)
+	for _, id := range ids {
+		if _, err := s.Add(id, []*KeyValue{{Key: "f", Value: id}}); err != nil {
+			t.Fatalf("Error adding %s: %s", id, err)
+		}
+	}
+	return s
+}
+
+func TestStreamTrim(t *testing.T) {
+	ids := []string{"1-1", "2-1", "3-1", "4-1", "5-1"}
+	tests := []struct {
+		name            string
+		trim            StreamTrim
+		expectedRemoved int
+		expectedFirstID string
+	}{
+		{"maxlen", StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 2}, 3, "4-1"},
+		{"approximate within a node", StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 2, Approx: true, Limit: 1}, 0, "1-1"},
+		{"minid", StreamTrim{Strategy: STREAM_TRIM_MINID, MinID: NewStreamID(3, 0)}, 2, "3-1"},
+		{"none", StreamTrim{Strategy: STREAM_TRIM_NONE}, 0, "1-1"},
+	}
+
+	for _, tt := range tests {
+		s := newTestStream(t, ids...)
+		removed := s.Trim(tt.trim)
+		if removed != tt.expectedRemoved {
+			t.Errorf("%s: expected %d removed; got %d", tt.name, tt.expectedRemoved, removed)
+		}
+		items, err := s.GetDataFromRange("-", "+", -1, false)
+		if err != nil {
+			t.Fatalf("%s: error getting range: %s", tt.name, err)
+		}
+		if len(items) != len(ids)-tt.expectedRemoved || items[0].id.String() != tt.expectedFirstID {
+			t.Errorf("%s: expected %d entries from %s; got %d", tt.name, len(ids)-tt.expectedRemoved, tt.expectedFirstID, len(items))
+		}
+	}
+}
+
+func TestStreamTrimApprox(t *testing.T) {
+	tests := []struct {
+		name            string
+		setup           func(s *Stream)
+		trim            StreamTrim
+		expectedRemoved int
+		expectedFirstID string
+	}{
+		{"maxlen", nil, StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 4}, 3, "4-1"},
+		{"maxlen within the first node", nil, StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 5}, 0, "1-1"},
+		{"maxlen 0", nil, StreamTrim{Strategy: STREAM_TRIM_MAXLEN}, 7, ""},
+		{"maxlen 0 with limit", nil, StreamTrim{Strategy: STREAM_TRIM_MAXLEN, Limit: 5}, 3, "4-1"},
+		{"minid", nil, StreamTrim{Strategy: STREAM_TRIM_MINID, MinID: NewStreamID(5, 1)}, 3, "4-1"},
+		{"minid past two nodes", nil, StreamTrim{Strategy: STREAM_TRIM_MINID, MinID: NewStreamID(7, 1)}, 6, "7-1"},
+		{"deleted entries", func(s *Stream) {
+			s.Delete([]*StreamID{NewStreamID(1, 1)})
+		}, StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 4}, 2, "4-1"},
+		{"acked with unread entries", func(s *Stream) {
+			s.CreateGroup("g", NewStreamID(0, 0), 0)
+		}, StreamTrim{Strategy: STREAM_TRIM_MAXLEN, Policy: STREAM_REF_ACKED}, 0, "1-1"},
+		{"acked up to the last node", func(s *Stream) {
+			s.CreateGroup("g", NewStreamID(6, 1), 6)
+		}, StreamTrim{Strategy: STREAM_TRIM_MAXLEN, Policy: STREAM_REF_ACKED}, 6, "7-1"},
+	}
+
+	for _, tt := range tests {
+		// Nodes of 3 entries: 1-1 to 3-1, 4-1 to 6-1 and 7-1.
+		s := NewStream(0, 3)
+		for i := range 7 {
+			s.Add(fmt.Sprintf("%d-1", i+1), []*KeyValue{{Key: "f", Value: "v"}})
+		}
+		if tt.setup != nil {
+			tt.setup(s)
+		}
+		length := s.Len()
+		tt.trim.Approx = true
+		if removed := s.Trim(tt.trim); removed != tt.expectedRemoved || s.Len() != length-removed {
+			t.Errorf("%s: expected %d removed; got %d leaving %d", tt.name, tt.expectedRemoved, removed, s.Len())
+		}
+		firstID := ""
+		if id := s.FirstID(); id != nil {
+			firstID = id.String()
+		}
+		if firstID != tt.expectedFirstID {
+			t.Errorf("%s: expected the first entry %q; got %q", tt.name, tt.expectedFirstID, firstID)
+		}
+	}
+}
+
+func TestStreamAddFields(t *testing.T) {
+	s := NewStream(DEFAULT_STREAM_NODE_MAX_BYTES, DEFAULT_STREAM_NODE_MAX_ENTRIES)
+	fields := []*KeyValue{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}
+	if _, err := s.Add("1-1", fields); err != nil {
+		t.Fatalf("Error adding entry: %s", err)
+	}
+	items, _ := s.GetDataFromRange("-", "+", -1, false)
+	if len(items) != 1 || len(items[0].fields) != 2 || items[0].fields[1].Value != "2" {
+		t.Errorf("Expected one entry with both fields; got %v", items)
+	}
+}
+
+func TestStreamDelete(t *testing.T) {
+	s := newTestStream(t, "1-1", "2-1", "3-1")
+	removed := s.Delete([]*StreamID{NewStreamID(1, 1), NewStreamID(9, 9)})
+	if removed != 1 {
+		t.Errorf("Expected 1 removed; got %d", removed)
+	}
+	if s.FirstID().String() != "2-1" || s.MaxDeletedID().String() != "1-1" || s.EntriesAdded() != 3 {
+		t.Errorf("Expected first 2-1, max deleted 1-1 and 3 added; got %s, %s and %d",
+			s.FirstID(), s.MaxDeletedID(), s.EntriesAdded())
+	}
+	s.Delete([]*StreamID{NewStreamID(2, 1), NewStreamID(3, 1)})
+	if s.Len() != 0 || s.FirstID() != nil || s.LastID().String() != "3-1" {
+		t.Errorf("Expected empty stream keeping last ID 3-1; got len %d, first %s, last %s",
+			s.Len(), s.FirstID(), s.LastID())
+	}
+}
+
+func TestStreamSetID(t *testing.T) {
+	tests := []struct {
+		name          string
+		lastID        *StreamID
+		entriesAdded  int
+		maxDeletedID  *StreamID
+		expectedError bool
+	}{
+		{"smaller than top item", NewStreamID(1, 0), -1, nil, true},
+		{"entries added below length", NewStreamID(5, 0), 1, nil, true},
+		{"max deleted above last", NewStreamID(5, 0), -1, NewStreamID(6, 0), true},
+		{"valid", NewStreamID(5, 0), 10, NewStreamID(4, 0), false},
+	}
+
+	for _, tt := range tests {
+		s := newTestStream(t, "1-1", "2-1")
+		err := s.SetID(tt.lastID, tt.entriesAdded, tt.maxDeletedID)
+		if (err != nil) != tt.expectedError {
+			t.Errorf("%s: expected error %v; got %v", tt.name, tt.expectedError, err)
+		}
+	}
+	s := newTestStream(t, "1-1")
+	if err := s.SetID(NewStreamID(5, 0), 10, nil); err != nil {
+		t.Fatalf("Error setting ID: %s", err)
+	}
+	if _, err := s.Add("5-0", []*KeyValue{{Key: "a", Value: "1"}}); err == nil {
+		t.Errorf("Expected adding an ID at the last ID to fail")
+	}
+	if s.EntriesAdded() != 10 {
+		t.Errorf("Expected 10 entries added; got %d", s.EntriesAdded())
+	}
+}
+
+func TestStreamGetDataFromRange(t *testing.T) {
+	s := newTestStream(t, "1-1", "1-2", "2-0", "3-5")
+	tests := []struct {
+		start       string
+		end         string
+		count       int
+		reverse     bool
+		expectedIDs []string
+	}{
+		{"-", "+", -1, false, []string{"1-1", "1-2", "2-0", "3-5"}},
+		{"-", "+", 2, false, []string{"1-1", "1-2"}},
+		{"(1-2", "+", -1, false, []string{"2-0", "3-5"}},
+		{"1", "1", -1, false, []string{"1-1", "1-2"}},
+		{"-", "(3-5", -1, false, []string{"1-1", "1-2", "2-0"}},
+		{"-", "+", 3, true, []string{"3-5", "2-0", "1-2"}},
+		{"(1-1", "(3-5", -1, true, []string{"2-0", "1-2"}},
+		{"-", "+", 0, false, []string{}},
+		{"3", "1", -1, false, []string{}},
+	}
+
+	for _, tt := range tests {
+		items, err := s.GetDataFromRange(tt.start, tt.end, tt.count, tt.reverse)
+		if err != nil {
+			t.Fatalf("%s %s: error getting range: %s", tt.start, tt.end, err)
+		}
+		ids := []string{}
+		for _, item := range items {
+			ids = append(ids, item.id.String())
+		}
+		if !slices.Equal(ids, tt.expectedIDs) {
+			t.Errorf("%s %s count %d reverse %v: expected %v; got %v", tt.start, tt.end, tt.count, tt.reverse, tt.expectedIDs, ids)
+		}
+	}
+
+	for _, bounds := range [][2]string{{"(-", "+"}, {"-", "(0-0"}, {"bad", "+"}} {
+		if _, err := s.GetDataFromRange(bounds[0], bounds[1], -1, false); err == nil {
+			t.Errorf("%s %s: expected an error", bounds[0], bounds[1])
+		}
+	}
+}
+
+func TestStreamEntriesAfter(t *testing.T) {
+	s := newTestStream(t, "1-1", "2-1", "3-1")
+	if items := s.EntriesAfter(NewStreamID(1, 1), -1); len(items) != 2 || items[0].id.String() != "2-1" {
+		t.Errorf("Expected 2 entries from 2-1; got %v", items)
+	}
+	if items := s.EntriesAfter(NewStreamID(0, 0), 1); len(items) != 1 || items[0].id.String() != "1-1" {
+		t.Errorf("Expected only 1-1; got %v", items)
+	}
+	if items := s.EntriesAfter(NewStreamID(3, 1), -1); len(items) != 0 {
+		t.Errorf("Expected no entries; got %v", items)
+	}
+	if items := s.LastEntry(); len(items) != 1 || items[0].id.String() != "3-1" {
+		t.Errorf("Expected last entry 3-1; got %v", items)
+	}
+	if items := NewStream(DEFAULT_STREAM_NODE_MAX_BYTES, DEFAULT_STREAM_NODE_MAX_ENTRIES).LastEntry(); len(items) != 0 {
+		t.Errorf("Expected no last entry in an empty stream; got %v", items)
+	}
+}
+
+func TestStreamNodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		nodeMaxBytes   int
+		nodeMaxEntries int
+		expectedKeys   int
+	}{
+		{"one entry per node", 0, 1, 300},
+		{"entry limit", 0, 7, 43},
+		{"byte limit", 64, 0, 65},
+		{"defaults", DEFAULT_STREAM_NODE_MAX_BYTES, DEFAULT_STREAM_NODE_MAX_ENTRIES, 3},
+	}
+
+	for _, tt := range tests {
+		s := NewStream(tt.nodeMaxBytes, tt.nodeMaxEntries)
+		ids := []string{}
+		for i := range 300 {
+			id := fmt.Sprintf("%d-%d", 1+i/3*257, i%3)
+			ids = append(ids, id)
+			fields := []*KeyValue{{Key: "f", Value: id}}
+			if i%10 == 0 {
+				fields = append(fields, &KeyValue{Key: "extra", Value: "x"})
+			}
+			if _, err := s.Add(id, fields); err != nil {
+				t.Fatalf("%s: error adding %s: %s", tt.name, id, err)
+			}
+		}
+		if s.RadixTreeKeys() != tt.expectedKeys {
+			t.Errorf("%s: expected %d nodes; got %d", tt.name, tt.expectedKeys, s.RadixTreeKeys())
+		}
+
+		items := s.Range(NewStreamID(0, 0), NewStreamID(math.MaxInt, math.MaxInt), -1, true)
+		if len(items) != len(ids) || items[0].id.String() != ids[len(ids)-1] || items[len(items)-1].fields[0].Value != ids[0] {
+			t.Fatalf("%s: expected all %d entries in reverse; got %d", tt.name, len(ids), len(items))
+		}
+		if item := s.lookup(NewStreamID(1+10*257, 0)); item == nil || len(item.fields) != 2 {
+			t.Errorf("%s: expected 30th entry with 2 fields; got %v", tt.name, item)
+		}
+
+		deleted := []*StreamID{}
+		for i := 0; i < len(ids); i += 2 {
+			id, _ := ParseStreamID(ids[i])
+			deleted = append(deleted, id)
+		}
+		if n := s.Delete(deleted); n != 150 {
+			t.Errorf("%s: expected 150 deleted; got %d", tt.name, n)
+		}
+		items, _ = s.GetDataFromRange("(1-1", "(772-2", -1, false)
+		got := []string{}
+		for _, item := range items {
+			got = append(got, item.id.String())
+		}
+		if expected := []string{"258-0", "258-2", "515-1", "772-0"}; !slices.Equal(got, expected) {
+			t.Errorf("%s: expected %v; got %v", tt.name, expected, got)
+		}
+		s.Trim(StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 10})
+		if s.Len() != 10 || s.FirstID().String() != ids[281] {
+			t.Errorf("%s: expected 10 entries from %s; got %d from %s", tt.name, ids[281], s.Len(), s.FirstID())
+		}
+		s.Trim(StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 0})
+		if s.RadixTreeKeys() != 0 || s.RadixTreeNodes() != 1 || s.FirstID() != nil {
+			t.Errorf("%s: expected an empty radix tree; got %d keys in %d nodes", tt.name, s.RadixTreeKeys(), s.RadixTreeNodes())
+		}
+	}
+}
+
+func TestStreamLoadNode(t *testing.T) {
+	s := newTestStream(t, "1-1", "1-2", "2-1")
+	s.Delete([]*StreamID{NewStreamID(1, 1)})
+	loaded := NewStream(DEFAULT_STREAM_NODE_MAX_BYTES, DEFAULT_STREAM_NODE_MAX_ENTRIES)
+	s.Nodes(func(master *StreamID, masterFields []string, items []StreamNodeItem) bool {
+		if err := loaded.LoadNode(master, masterFields, items); err != nil {
+			t.Fatalf("Error loading node %s: %s", master, err)
+		}
+		return true
+	})
+	if loaded.Len() != 2 || loaded.FirstID().String() != "1-2" || loaded.LastID().String() != "2-1" {
+		t.Errorf("Expected 2 entries from 1-2 to 2-1; got %d from %s to %s", loaded.Len(), loaded.FirstID(), loaded.LastID())
+	}
+	if item := loaded.lookup(NewStreamID(1, 1)); item != nil {
+		t.Errorf("Expected the deleted entry to stay deleted; got %v", item)
+	}
+
+	items := []StreamNodeItem{{ID: NewStreamID(2, 1), Fields: []*KeyValue{{Key: "f", Value: "v"}}}}
+	if err := loaded.LoadNode(NewStreamID(2, 1), []string{"f"}, items); err == nil {
+		t.Errorf("Expected a node overlapping the last one to fail")
+	}
+	items = append(items, StreamNodeItem{ID: NewStreamID(3, 0)}, StreamNodeItem{ID: NewStreamID(2, 5)})
+	if err := loaded.LoadNode(NewStreamID(2, 2), []string{"f"}, items); err == nil {
+		t.Errorf("Expected entries out of order to fail")
+	}
+
+	clone := loaded.Clone()
+	loaded.Delete([]*StreamID{NewStreamID(1, 2)})
+	if clone.Len() != 2 || clone.lookup(NewStreamID(1, 2)) == nil {
+		t.Errorf("Expected the clone to keep the entry deleted from the original")
+	}
+}
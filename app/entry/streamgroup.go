@@ -0,0 +1,543 @@
+package entry
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/google/btree"
+)
+
+// STREAM_ENTRIES_READ_UNKNOWN is the entries-read counter of a group whose
+// position in the stream can not be told from its last delivered ID.
+const STREAM_ENTRIES_READ_UNKNOWN int = -1
+
+// StreamPendingEntry is an entry delivered to a consumer of a group and not
+// acknowledged yet. The same value sits in the pending entries list of the
+// group and in the one of the consumer that owns it.
+type StreamPendingEntry struct {
+	ID            *StreamID
+	Consumer      *StreamConsumer
+	DeliveryTime  int64
+	DeliveryCount int
+}
+
+func (p *StreamPendingEntry) Less(than btree.Item) bool {
+	return p.ID.compare(than.(*StreamPendingEntry).ID) < 0
+}
+
+// StreamConsumer is a member of a group. SeenTime is the last time it tried
+// to read or claim, ActiveTime the last time it actually got an entry, or -1
+// if it never did; both are unix times in milliseconds.
+type StreamConsumer struct {
+	Name       string
+	SeenTime   int64
+	ActiveTime int64
+	pending    *btree.BTree
+}
+
+func (c *StreamConsumer) PendingLen() int {
+	return c.pending.Len()
+}
+
+// StreamGroup is a consumer group: the last ID delivered to any of its
+// consumers, how many entries of the stream that covers, and the entries
+// delivered but not acknowledged.
+type StreamGroup struct {
+	Name        string
+	lastID      *StreamID
+	entriesRead int
+	pending     *btree.BTree
+	consumers   map[string]*StreamConsumer
+}
+
+func (g *StreamGroup) LastID() *StreamID {
+	return g.lastID
+}
+
+func (g *StreamGroup) EntriesRead() int {
+	return g.entriesRead
+}
+
+func (g *StreamGroup) PendingLen() int {
+	return g.pending.Len()
+}
+
+// SetID moves the group to lastID, as XGROUP SETID does.
+func (g *StreamGroup) SetID(lastID *StreamID, entriesRead int) {
+	g.lastID = lastID
+	g.entriesRead = entriesRead
+}
+
+func (g *StreamGroup) Consumer(name string) *StreamConsumer {
+	return g.consumers[name]
+}
+
+// Consumers returns the consumers of the group sorted by name.
+func (g *StreamGroup) Consumers() []*StreamConsumer {
+	ret := make([]*StreamConsumer, 0, len(g.consumers))
+	for _, c := range g.consumers {
+		ret = append(ret, c)
+	}
+	slices.SortFunc(ret, func(a, b *StreamConsumer) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return ret
+}
+
+// CreateConsumer returns the consumer called name, creating it if needed,
+// and reports whether it was created.
+func (g *StreamGroup) CreateConsumer(name string, now int64) (*StreamConsumer, bool) {
+	if c, ok := g.consumers[name]; ok {
+		return c, false
+	}
+	c := &StreamConsumer{
+		Name:       name,
+		SeenTime:   now,
+		ActiveTime: -1,
+		pending:    btree.New(32),
+	}
+	g.consumers[name] = c
+	return c, true
+}
+
+// DeleteConsumer removes the consumer called name along with its pending
+// entries and returns how many of them it had.
+func (g *StreamGroup) DeleteConsumer(name string) int {
+	c, ok := g.consumers[name]
+	if !ok {
+		return 0
+	}
+	pending := c.pending.Len()
+	c.pending.Ascend(func(item btree.Item) bool {
+		g.pending.Delete(item)
+		return true
+	})
+	delete(g.consumers, name)
+	return pending
+}
+
+// Ack removes ids from the pending entries list and returns how many of them
+// were pending.
+func (g *StreamGroup) Ack(ids []*StreamID) int {
+	acked := 0
+	for _, id := range ids {
+		item := g.pending.Delete(&StreamPendingEntry{ID: id})
+		if item == nil {
+			continue
+		}
+		item.(*StreamPendingEntry).Consumer.pending.Delete(item)
+		acked++
+	}
+	return acked
+}
+
+// assign makes c the owner of the pending entry for id, creating the entry if
+// there is none, and returns it.
+func (g *StreamGroup) assign(id *StreamID, c *StreamConsumer) *StreamPendingEntry {
+	var p *StreamPendingEntry
+	if item := g.pending.Get(&StreamPendingEntry{ID: id}); item != nil {
+		p = item.(*StreamPendingEntry)
+		if p.Consumer != c {
+			p.Consumer.pending.Delete(p)
+		}
+	} else {
+		p = &StreamPendingEntry{ID: id}
+		g.pending.ReplaceOrInsert(p)
+	}
+	p.Consumer = c
+	c.pending.ReplaceOrInsert(p)
+	return p
+}
+
+// RestorePending adds the pending entry for id, read back from a snapshot,
+// and reports whether id was not pending yet. No consumer owns it until
+// RestoreConsumer hands it to one.
+func (g *StreamGroup) RestorePending(id *StreamID, deliveryTime int64, deliveryCount int) bool {
+	if g.pending.Has(&StreamPendingEntry{ID: id}) {
+		return false
+	}
+	g.pending.ReplaceOrInsert(&StreamPendingEntry{ID: id, DeliveryTime: deliveryTime, DeliveryCount: deliveryCount})
+	return true
+}
+
+// RestoreConsumer adds a consumer read back from a snapshot, handing it the
+// pending entries for ids, which have to be restored and not owned yet.
+func (g *StreamGroup) RestoreConsumer(name string, seenTime int64, activeTime int64, ids []*StreamID) error {
+	c, created := g.CreateConsumer(name, seenTime)
+	if !created {
+		return fmt.Errorf("duplicate consumer %q", name)
+	}
+	c.ActiveTime = activeTime
+	for _, id := range ids {
+		item := g.pending.Get(&StreamPendingEntry{ID: id})
+		if item == nil {
+			return fmt.Errorf("entry %s pending for consumer %q isn't pending in the group", id, name)
+		}
+		p := item.(*StreamPendingEntry)
+		if p.Consumer != nil {
+			return fmt.Errorf("entry %s is pending for both %q and %q", id, p.Consumer.Name, name)
+		}
+		p.Consumer = c
+		c.pending.ReplaceOrInsert(p)
+	}
+	return nil
+}
+
+// clone returns a copy of g with copies of its consumers and pending
+// entries.
+func (g *StreamGroup) clone() *StreamGroup {
+	c := &StreamGroup{
+		Name:        g.Name,
+		lastID:      g.lastID,
+		entriesRead: g.entriesRead,
+		pending:     btree.New(32),
+		consumers:   make(map[string]*StreamConsumer, len(g.consumers)),
+	}
+	for name, consumer := range g.consumers {
+		copied := *consumer
+		copied.pending = btree.New(32)
+		c.consumers[name] = &copied
+	}
+	g.pending.Ascend(func(item btree.Item) bool {
+		p := *item.(*StreamPendingEntry)
+		c.pending.ReplaceOrInsert(&p)
+		if p.Consumer != nil {
+			p.Consumer = c.consumers[p.Consumer.Name]
+			p.Consumer.pending.ReplaceOrInsert(&p)
+		}
+		return true
+	})
+	return c
+}
+
+func (s *Stream) Group(name string) *StreamGroup {
+	return s.groups[name]
+}
+
+// Groups returns the groups of the stream sorted by name.
+func (s *Stream) Groups() []*StreamGroup {
+	ret := make([]*StreamGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		ret = append(ret, g)
+	}
+	slices.SortFunc(ret, func(a, b *StreamGroup) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return ret
+}
+
+// CreateGroup adds a group that has read up to lastID and reports whether
+// there was no group called name yet.
+func (s *Stream) CreateGroup(name string, lastID *StreamID, entriesRead int) bool {
+	if _, ok := s.groups[name]; ok {
+		return false
+	}
+	s.groups[name] = &StreamGroup{
+		Name:        name,
+		lastID:      lastID,
+		entriesRead: entriesRead,
+		pending:     btree.New(32),
+		consumers:   make(map[string]*StreamConsumer),
+	}
+	return true
+}
+
+func (s *Stream) DestroyGroup(name string) bool {
+	if _, ok := s.groups[name]; !ok {
+		return false
+	}
+	delete(s.groups, name)
+	return true
+}
+
+// ReadGroup delivers to c up to count entries (all of them when count is
+// negative) that the group has not delivered yet, moving the group past
+// them. Unless noack is set they are added to the pending entries lists,
+// which are returned as well.
+func (s *Stream) ReadGroup(g *StreamGroup, c *StreamConsumer, count int, noack bool, now int64) (StreamRangeData, []*StreamPendingEntry) {
+	c.SeenTime = now
+	items := s.EntriesAfter(g.lastID, count)
+	delivered := []*StreamPendingEntry{}
+	for _, item := range items {
+		s.advanceGroup(g, item.id)
+		if noack {
+			continue
+		}
+		p := g.assign(item.id, c)
+		p.DeliveryTime = now
+		p.DeliveryCount = 1
+		delivered = append(delivered, p)
+	}
+	if len(items) > 0 {
+		c.ActiveTime = now
+	}
+	return items, delivered
+}
+
+// ReadHistory returns up to count of the entries pending for c with IDs
+// greater than after, counting them as delivered once more. Entries that
+// were deleted from the stream are returned without fields.
+func (s *Stream) ReadHistory(g *StreamGroup, c *StreamConsumer, after *StreamID, count int, now int64) (StreamRangeData, []*StreamPendingEntry) {
+	c.SeenTime = now
+	items := StreamRangeData{}
+	delivered := []*StreamPendingEntry{}
+	start := after.next()
+	if start == nil {
+		return items, delivered
+	}
+	c.pending.AscendGreaterOrEqual(&StreamPendingEntry{ID: start}, func(item btree.Item) bool {
+		p := item.(*StreamPendingEntry)
+		if entry := s.lookup(p.ID); entry != nil {
+			p.DeliveryTime = now
+			p.DeliveryCount++
+			items = append(items, entry)
+			delivered = append(delivered, p)
+		} else {
+			items = append(items, &StreamItem{id: p.ID})
+		}
+		return count < 0 || len(items) < count
+	})
+	return items, delivered
+}
+
+// StreamClaim holds the options of XCLAIM. An entry is only claimed once it
+// has been idle for at least MinIdle milliseconds, and its delivery time is
+// then set to DeliveryTime. A negative RetryCount increments the delivery
+// count unless JustID is set. Force creates the pending entry for entries
+// nobody had been delivered, and a LastID past the last ID of the group moves
+// the group forward.
+type StreamClaim struct {
+	MinIdle      int64
+	DeliveryTime int64
+	RetryCount   int
+	Force        bool
+	JustID       bool
+	LastID       *StreamID
+}
+
+// Claim hands the pending entries for ids over to c and returns the ones it
+// now owns. Pending entries whose stream entry was deleted are dropped
+// instead, and their IDs returned separately.
+func (s *Stream) Claim(g *StreamGroup, c *StreamConsumer, ids []*StreamID, claim StreamClaim, now int64) ([]*StreamPendingEntry, []*StreamID) {
+	c.SeenTime = now
+	if claim.LastID != nil && claim.LastID.compare(g.lastID) > 0 {
+		g.lastID = claim.LastID
+	}
+	claimed := []*StreamPendingEntry{}
+	deleted := []*StreamID{}
+	for _, id := range ids {
+		item := g.pending.Get(&StreamPendingEntry{ID: id})
+		if item == nil {
+			if claim.Force && s.lookup(id) != nil {
+				claimed = append(claimed, g.claim(id, c, claim))
+			}
+			continue
+		}
+		if now-item.(*StreamPendingEntry).DeliveryTime < claim.MinIdle {
+			continue
+		}
+		if s.lookup(id) == nil {
+			g.Ack([]*StreamID{id})
+			deleted = append(deleted, id)
+			continue
+		}
+		claimed = append(claimed, g.claim(id, c, claim))
+	}
+	if len(claimed) > 0 {
+		c.ActiveTime = now
+	}
+	return claimed, deleted
+}
+
+// AutoClaim claims for c up to count pending entries, starting at start,
+// that have been idle for at least claim.MinIdle, looking at no more than ten
+// times count of them. Like Claim it drops the pending entries of deleted
+// stream entries. It also returns the ID to resume from, zero once the whole
+// pending entries list has been scanned.
+func (s *Stream) AutoClaim(g *StreamGroup, c *StreamConsumer, start *StreamID, count int, claim StreamClaim, now int64) ([]*StreamPendingEntry, []*StreamID, *StreamID) {
+	c.SeenTime = now
+	attempts := count * 10
+	candidates := []*StreamPendingEntry{}
+	next := NewStreamID(0, 0)
+	g.pending.AscendGreaterOrEqual(&StreamPendingEntry{ID: start}, func(item btree.Item) bool {
+		if attempts == 0 || len(candidates) == count {
+			next = item.(*StreamPendingEntry).ID
+			return false
+		}
+		attempts--
+		p := item.(*StreamPendingEntry)
+		if now-p.DeliveryTime >= claim.MinIdle {
+			candidates = append(candidates, p)
+		}
+		return true
+	})
+	claimed := []*StreamPendingEntry{}
+	deleted := []*StreamID{}
+	for _, p := range candidates {
+		if s.lookup(p.ID) == nil {
+			g.Ack([]*StreamID{p.ID})
+			deleted = append(deleted, p.ID)
+			continue
+		}
+		claimed = append(claimed, g.claim(p.ID, c, claim))
+	}
+	if len(claimed) > 0 {
+		c.ActiveTime = now
+	}
+	return claimed, deleted, next
+}
+
+func (g *StreamGroup) claim(id *StreamID, c *StreamConsumer, claim StreamClaim) *StreamPendingEntry {
+	p := g.assign(id, c)
+	p.DeliveryTime = claim.DeliveryTime
+	if claim.RetryCount >= 0 {
+		p.DeliveryCount = claim.RetryCount
+	} else if !claim.JustID {
+		p.DeliveryCount++
+	}
+	return p
+}
+
+// PendingRange returns up to count pending entries with IDs in [start, end]
+// that have been idle for at least minIdle milliseconds, only taking those of
+// c unless it is nil.
+func (g *StreamGroup) PendingRange(c *StreamConsumer, start *StreamID, end *StreamID, count int, minIdle int64, now int64) []*StreamPendingEntry {
+	ret := []*StreamPendingEntry{}
+	if count <= 0 || start.compare(end) > 0 {
+		return ret
+	}
+	pending := g.pending
+	if c != nil {
+		pending = c.pending
+	}
+	pending.AscendGreaterOrEqual(&StreamPendingEntry{ID: start}, func(item btree.Item) bool {
+		p := item.(*StreamPendingEntry)
+		if p.ID.compare(end) > 0 {
+			return false
+		}
+		if now-p.DeliveryTime >= minIdle {
+			ret = append(ret, p)
+		}
+		return len(ret) < count
+	})
+	return ret
+}
+
+// Pending returns the pending entries of c, or of the whole group when c is
+// nil, in ID order.
+func (g *StreamGroup) Pending(c *StreamConsumer) []*StreamPendingEntry {
+	pending := g.pending
+	if c != nil {
+		pending = c.pending
+	}
+	ret := make([]*StreamPendingEntry, 0, pending.Len())
+	pending.Ascend(func(item btree.Item) bool {
+		ret = append(ret, item.(*StreamPendingEntry))
+		return true
+	})
+	return ret
+}
+
+// PendingBounds returns the smallest and largest pending IDs, or nils when
+// nothing is pending.
+func (g *StreamGroup) PendingBounds() (*StreamID, *StreamID) {
+	if g.pending.Len() == 0 {
+		return nil, nil
+	}
+	return g.pending.Min().(*StreamPendingEntry).ID, g.pending.Max().(*StreamPendingEntry).ID
+}
+
+// Entries returns the entries for ids that are still in the stream.
+func (s *Stream) Entries(ids []*StreamID) StreamRangeData {
+	ret := StreamRangeData{}
+	for _, id := range ids {
+		if entry := s.lookup(id); entry != nil {
+			ret = append(ret, entry)
+		}
+	}
+	return ret
+}
+
+// isReferenced reports whether a group has yet to deliver the entry for id or
+// has it pending.
+func (s *Stream) isReferenced(id *StreamID) bool {
+	for _, g := range s.groups {
+		if id.compare(g.lastID) > 0 || g.pending.Has(&StreamPendingEntry{ID: id}) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropReferences removes the pending entries for id from every group.
+func (s *Stream) dropReferences(id *StreamID) {
+	for _, g := range s.groups {
+		g.Ack([]*StreamID{id})
+	}
+}
+
+// advanceGroup moves g past id, keeping its entries-read counter exact while
+// it can and estimating it otherwise.
+func (s *Stream) advanceGroup(g *StreamGroup, id *StreamID) {
+	if g.entriesRead != STREAM_ENTRIES_READ_UNKNOWN && !s.hasTombstonesFrom(id) {
+		g.entriesRead++
+	} else if s.entriesAdded > 0 {
+		g.entriesRead = s.EstimateEntriesRead(id)
+	}
+	g.lastID = id
+}
+
+// Lag returns how many entries of the stream the group has yet to read, and
+// false when deletions make that impossible to tell.
+func (s *Stream) Lag(g *StreamGroup) (int, bool) {
+	if s.entriesAdded == 0 {
+		return 0, true
+	}
+	if g.entriesRead != STREAM_ENTRIES_READ_UNKNOWN && !s.hasTombstonesFrom(g.lastID) {
+		return s.entriesAdded - g.entriesRead, true
+	}
+	entriesRead := s.EstimateEntriesRead(g.lastID)
+	if entriesRead == STREAM_ENTRIES_READ_UNKNOWN {
+		return 0, false
+	}
+	return s.entriesAdded - entriesRead, true
+}
+
+// hasTombstonesFrom reports whether an entry with an ID at or after id may
+// have been deleted.
+func (s *Stream) hasTombstonesFrom(id *StreamID) bool {
+	if s.Len() == 0 || s.maxDeletedID.isZero() {
+		return false
+	}
+	return id.compare(s.maxDeletedID) <= 0
+}
+
+// EstimateEntriesRead returns how many entries had been added to the stream
+// up to and including id, or STREAM_ENTRIES_READ_UNKNOWN when deletions make
+// that impossible to tell.
+func (s *Stream) EstimateEntriesRead(id *StreamID) int {
+	if s.entriesAdded == 0 {
+		return 0
+	}
+	length := s.Len()
+	if length == 0 && id.compare(s.topID) <= 0 {
+		return s.entriesAdded
+	}
+	cmpLast := id.compare(s.topID)
+	if cmpLast == 0 {
+		return s.entriesAdded
+	} else if cmpLast > 0 {
+		return STREAM_ENTRIES_READ_UNKNOWN
+	}
+	firstID := s.FirstID()
+	if s.maxDeletedID.isZero() || s.maxDeletedID.compare(firstID) < 0 {
+		switch cmpFirst := id.compare(firstID); {
+		case cmpFirst < 0:
+			return s.entriesAdded - length
+		case cmpFirst == 0:
+			return s.entriesAdded - length + 1
+		}
+	}
+	return STREAM_ENTRIES_READ_UNKNOWN
+}
@@ -0,0 +1,292 @@
+package entry
+
+import (
+	"testing"
+)
+
+func TestStreamReadGroup(t *testing.T) {
+	s := newTestStream(t, "1-1", "2-1", "3-1")
+	s.CreateGroup("g", NewStreamID(0, 0), 0)
+	g := s.Group("g")
+	alice, _ := g.CreateConsumer("alice", 0)
+	bob, _ := g.CreateConsumer("bob", 0)
+
+	items, delivered := s.ReadGroup(g, alice, 2, false, 10)
+	if len(items) != 2 || len(delivered) != 2 || g.LastID().String() != "2-1" || g.EntriesRead() != 2 {
+		t.Errorf("Expected 2 entries read up to 2-1; got %d, last ID %s, entries read %d", len(items), g.LastID(), g.EntriesRead())
+	}
+	items, delivered = s.ReadGroup(g, bob, -1, true, 20)
+	if len(items) != 1 || len(delivered) != 0 || g.PendingLen() != 2 {
+		t.Errorf("Expected 1 entry read without acknowledgement; got %d with %d pending", len(items), g.PendingLen())
+	}
+	if bob.ActiveTime != 20 || alice.PendingLen() != 2 || bob.PendingLen() != 0 {
+		t.Errorf("Expected bob active at 20 and alice owning 2 entries; got %d and %d", bob.ActiveTime, alice.PendingLen())
+	}
+
+	history, delivered := s.ReadHistory(g, alice, NewStreamID(1, 1), -1, 30)
+	if len(history) != 1 || delivered[0].DeliveryCount != 2 || delivered[0].DeliveryTime != 30 {
+		t.Errorf("Expected 2-1 delivered a second time at 30; got %v", delivered)
+	}
+
+	if acked := g.Ack([]*StreamID{NewStreamID(1, 1), NewStreamID(3, 1)}); acked != 1 {
+		t.Errorf("Expected 1 acknowledged; got %d", acked)
+	}
+	if g.PendingLen() != 1 || alice.PendingLen() != 1 {
+		t.Errorf("Expected 1 pending entry left; got %d", g.PendingLen())
+	}
+}
+
+func TestStreamClaim(t *testing.T) {
+	s := newTestStream(t, "1-1", "2-1")
+	s.CreateGroup("g", NewStreamID(0, 0), 0)
+	g := s.Group("g")
+	alice, _ := g.CreateConsumer("alice", 0)
+	bob, _ := g.CreateConsumer("bob", 0)
+	s.ReadGroup(g, alice, 1, false, 100)
+
+	tests := []struct {
+		name            string
+		id              *StreamID
+		claim           StreamClaim
+		now             int64
+		expectedClaimed int
+		expectedCount   int
+	}{
+		{"not idle long enough", NewStreamID(1, 1), StreamClaim{MinIdle: 50, DeliveryTime: 120, RetryCount: -1}, 120, 0, 1},
+		{"idle", NewStreamID(1, 1), StreamClaim{MinIdle: 50, DeliveryTime: 200, RetryCount: -1}, 200, 1, 2},
+		{"just id", NewStreamID(1, 1), StreamClaim{DeliveryTime: 210, RetryCount: -1, JustID: true}, 210, 1, 2},
+		{"not pending", NewStreamID(2, 1), StreamClaim{DeliveryTime: 220, RetryCount: -1}, 220, 0, 0},
+		{"force", NewStreamID(2, 1), StreamClaim{DeliveryTime: 230, RetryCount: 5, Force: true}, 230, 1, 5},
+		{"force missing entry", NewStreamID(9, 9), StreamClaim{DeliveryTime: 240, RetryCount: -1, Force: true}, 240, 0, 0},
+	}
+
+	for _, tt := range tests {
+		claimed, _ := s.Claim(g, bob, []*StreamID{tt.id}, tt.claim, tt.now)
+		if len(claimed) != tt.expectedClaimed {
+			t.Fatalf("%s: expected %d claimed; got %d", tt.name, tt.expectedClaimed, len(claimed))
+		}
+		if len(claimed) > 0 && (claimed[0].DeliveryCount != tt.expectedCount || claimed[0].Consumer != bob) {
+			t.Errorf("%s: expected bob owning it with count %d; got %s with %d", tt.name, tt.expectedCount, claimed[0].Consumer.Name, claimed[0].DeliveryCount)
+		}
+	}
+	if alice.PendingLen() != 0 || bob.PendingLen() != 2 {
+		t.Errorf("Expected bob owning both pending entries; got alice %d and bob %d", alice.PendingLen(), bob.PendingLen())
+	}
+	if pending := g.DeleteConsumer("bob"); pending != 2 || g.PendingLen() != 0 {
+		t.Errorf("Expected bob deleted with 2 pending entries; got %d with %d left", pending, g.PendingLen())
+	}
+}
+
+func TestStreamEstimateEntriesRead(t *testing.T) {
+	s := newTestStream(t, "1-1", "2-1", "3-1", "4-1")
+	tests := []struct {
+		name     string
+		id       *StreamID
+		expected int
+	}{
+		{"last entry", NewStreamID(4, 1), 4},
+		{"future ID", NewStreamID(5, 0), STREAM_ENTRIES_READ_UNKNOWN},
+		{"first entry", NewStreamID(1, 1), 1},
+		{"before first entry", NewStreamID(0, 1), 0},
+		{"middle entry", NewStreamID(2, 1), STREAM_ENTRIES_READ_UNKNOWN},
+	}
+
+	for _, tt := range tests {
+		if got := s.EstimateEntriesRead(tt.id); got != tt.expected {
+			t.Errorf("%s: expected %d; got %d", tt.name, tt.expected, got)
+		}
+	}
+
+	s.Delete([]*StreamID{NewStreamID(1, 1)})
+	if got := s.EstimateEntriesRead(NewStreamID(2, 1)); got != 2 {
+		t.Errorf("Expected the new first entry to be the 2nd added; got %d", got)
+	}
+}
+
+func TestStreamAutoClaim(t *testing.T) {
+	s := newTestStream(t, "1-1", "2-1", "3-1", "4-1")
+	s.CreateGroup("g", NewStreamID(0, 0), 0)
+	g := s.Group("g")
+	alice, _ := g.CreateConsumer("alice", 0)
+	bob, _ := g.CreateConsumer("bob", 0)
+	s.ReadGroup(g, alice, -1, false, 100)
+	s.Delete([]*StreamID{NewStreamID(2, 1)})
+
+	claim := StreamClaim{DeliveryTime: 200, RetryCount: -1}
+	claimed, deleted, next := s.AutoClaim(g, bob, NewStreamID(0, 0), 2, claim, 200)
+	if len(claimed) != 1 || len(deleted) != 1 || deleted[0].String() != "2-1" || next.String() != "3-1" {
+		t.Errorf("Expected 1-1 claimed, 2-1 deleted and 3-1 next; got %d, %v and %s", len(claimed), deleted, next)
+	}
+	claimed, deleted, next = s.AutoClaim(g, bob, next, 10, claim, 200)
+	if len(claimed) != 2 || len(deleted) != 0 || !next.isZero() {
+		t.Errorf("Expected the rest claimed and the scan finished; got %d, %v and %s", len(claimed), deleted, next)
+	}
+	if g.PendingLen() != 3 || bob.PendingLen() != 3 {
+		t.Errorf("Expected bob owning all 3 pending entries; got %d of %d", bob.PendingLen(), g.PendingLen())
+	}
+
+	claimed, _, _ = s.AutoClaim(g, alice, NewStreamID(0, 0), 10, StreamClaim{MinIdle: 50, DeliveryTime: 220, RetryCount: -1}, 220)
+	if len(claimed) != 0 {
+		t.Errorf("Expected nothing idle long enough; got %d", len(claimed))
+	}
+}
+
+func TestStreamPendingRange(t *testing.T) {
+	s := newTestStream(t, "1-1", "2-1", "3-1")
+	s.CreateGroup("g", NewStreamID(0, 0), 0)
+	g := s.Group("g")
+	alice, _ := g.CreateConsumer("alice", 0)
+	bob, _ := g.CreateConsumer("bob", 0)
+	s.ReadGroup(g, alice, 2, false, 100)
+	s.ReadGroup(g, bob, 1, false, 150)
+
+	tests := []struct {
+		name     string
+		consumer *StreamConsumer
+		count    int
+		minIdle  int64
+		expected int
+	}{
+		{"all", nil, 10, 0, 3},
+		{"count", nil, 2, 0, 2},
+		{"consumer", bob, 10, 0, 1},
+		{"idle", nil, 10, 60, 2},
+		{"zero count", nil, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		got := g.PendingRange(tt.consumer, NewStreamID(0, 0), NewStreamID(9, 9), tt.count, tt.minIdle, 200)
+		if len(got) != tt.expected {
+			t.Errorf("%s: expected %d; got %d", tt.name, tt.expected, len(got))
+		}
+	}
+	first, last := g.PendingBounds()
+	if first.String() != "1-1" || last.String() != "3-1" {
+		t.Errorf("Expected pending bounds 1-1 and 3-1; got %s and %s", first, last)
+	}
+}
+
+func TestStreamLag(t *testing.T) {
+	s := newTestStream(t, "1-1", "2-1", "3-1")
+	s.CreateGroup("read", NewStreamID(0, 0), 0)
+	s.CreateGroup("unknown", NewStreamID(1, 1), STREAM_ENTRIES_READ_UNKNOWN)
+	s.CreateGroup("caught up", NewStreamID(3, 1), STREAM_ENTRIES_READ_UNKNOWN)
+	c, _ := s.Group("read").CreateConsumer("c", 0)
+	s.ReadGroup(s.Group("read"), c, 1, true, 0)
+
+	tests := []struct {
+		group       string
+		expectedLag int
+		expectedOk  bool
+	}{
+		{"read", 2, true},
+		{"unknown", 2, true},
+		{"caught up", 0, true},
+	}
+	for _, tt := range tests {
+		lag, ok := s.Lag(s.Group(tt.group))
+		if lag != tt.expectedLag || ok != tt.expectedOk {
+			t.Errorf("%s: expected lag %d (%v); got %d (%v)", tt.group, tt.expectedLag, tt.expectedOk, lag, ok)
+		}
+	}
+
+	s.Delete([]*StreamID{NewStreamID(2, 1)})
+	if _, ok := s.Lag(s.Group("read")); ok {
+		t.Errorf("Expected the lag to be unknown after a deletion ahead of the group")
+	}
+}
+
+func TestStreamDeleteWithPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          StreamRefPolicy
+		expected        []int
+		expectedPending int
+	}{
+		{"keepref", STREAM_REF_KEEPREF, []int{1, 1, 1, -1}, 1},
+		{"delref", STREAM_REF_DELREF, []int{1, 1, 1, -1}, 0},
+		{"acked", STREAM_REF_ACKED, []int{1, 2, 2, -1}, 1},
+	}
+
+	for _, tt := range tests {
+		s := newTestStream(t, "1-1", "2-1", "3-1")
+		s.CreateGroup("g", NewStreamID(0, 0), 0)
+		g := s.Group("g")
+		c, _ := g.CreateConsumer("c", 0)
+		s.ReadGroup(g, c, 2, false, 0)
+		g.Ack([]*StreamID{NewStreamID(1, 1)})
+
+		ids := []*StreamID{NewStreamID(1, 1), NewStreamID(2, 1), NewStreamID(3, 1), NewStreamID(9, 9)}
+		got := s.DeleteWithPolicy(ids, tt.policy)
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("%s: expected %v; got %v", tt.name, tt.expected, got)
+				break
+			}
+		}
+		if g.PendingLen() != tt.expectedPending {
+			t.Errorf("%s: expected %d pending; got %d", tt.name, tt.expectedPending, g.PendingLen())
+		}
+	}
+}
+
+func TestStreamTrimWithPolicy(t *testing.T) {
+	tests := []struct {
+		name            string
+		trim            StreamTrim
+		expectedRemoved int
+		expectedFirst   string
+		expectedPending int
+	}{
+		{"keepref", StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 1}, 3, "4-1", 1},
+		{"delref", StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 1, Policy: STREAM_REF_DELREF}, 3, "4-1", 0},
+		{"acked", StreamTrim{Strategy: STREAM_TRIM_MAXLEN, MaxLen: 1, Policy: STREAM_REF_ACKED}, 1, "2-1", 1},
+		{"acked minid", StreamTrim{Strategy: STREAM_TRIM_MINID, MinID: NewStreamID(4, 0), Policy: STREAM_REF_ACKED}, 1, "2-1", 1},
+	}
+
+	for _, tt := range tests {
+		s := newTestStream(t, "1-1", "2-1", "3-1", "4-1")
+		s.CreateGroup("g", NewStreamID(0, 0), 0)
+		g := s.Group("g")
+		c, _ := g.CreateConsumer("c", 0)
+		s.ReadGroup(g, c, 2, false, 0)
+		g.Ack([]*StreamID{NewStreamID(1, 1)})
+
+		if removed := s.Trim(tt.trim); removed != tt.expectedRemoved {
+			t.Errorf("%s: expected %d removed; got %d", tt.name, tt.expectedRemoved, removed)
+		}
+		if first := s.FirstID(); first.String() != tt.expectedFirst {
+			t.Errorf("%s: expected first ID %s; got %s", tt.name, tt.expectedFirst, first)
+		}
+		if g.PendingLen() != tt.expectedPending {
+			t.Errorf("%s: expected %d pending; got %d", tt.name, tt.expectedPending, g.PendingLen())
+		}
+	}
+}
+
+func TestStreamRestoreGroup(t *testing.T) {
+	s := newTestStream(t, "1-1", "1-2")
+	s.CreateGroup("g", NewStreamID(1, 2), 2)
+	g := s.Group("g")
+	if !g.RestorePending(NewStreamID(1, 1), 100, 2) || !g.RestorePending(NewStreamID(1, 2), 200, 1) {
+		t.Fatalf("Expected the pending entries to be restored")
+	}
+	if g.RestorePending(NewStreamID(1, 1), 100, 2) {
+		t.Errorf("Expected a duplicate pending entry to be refused")
+	}
+	if err := g.RestoreConsumer("a", 300, 200, []*StreamID{NewStreamID(1, 1), NewStreamID(1, 2)}); err != nil {
+		t.Fatalf("Error restoring consumer: %s", err)
+	}
+	if err := g.RestoreConsumer("b", 300, 200, []*StreamID{NewStreamID(1, 2)}); err == nil {
+		t.Errorf("Expected an entry already pending for a to fail")
+	}
+	if err := g.RestoreConsumer("c", 300, 200, []*StreamID{NewStreamID(9, 9)}); err == nil {
+		t.Errorf("Expected an entry that isn't pending to fail")
+	}
+
+	clone := s.Clone().Group("g")
+	g.Ack([]*StreamID{NewStreamID(1, 1)})
+	if a := clone.Consumer("a"); clone.PendingLen() != 2 || a.PendingLen() != 2 || a.ActiveTime != 200 {
+		t.Errorf("Expected the clone to keep both pending entries of a; got %d", clone.PendingLen())
+	}
+}
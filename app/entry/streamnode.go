@@ -0,0 +1,204 @@
+package entry
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+const (
+	DEFAULT_STREAM_NODE_MAX_BYTES   int = 4096
+	DEFAULT_STREAM_NODE_MAX_ENTRIES int = 100
+)
+
+const (
+	streamItemFlagDeleted    byte = 1 << 0
+	streamItemFlagSameFields byte = 1 << 1
+)
+
+// streamNode is a macro node holding a run of consecutive entries, laid out
+// the way Redis lays out a stream listpack. The first entry added becomes the
+// master entry: the IDs of every entry are stored as deltas from its ID, and
+// an entry with the same field names as it stores only its values. Each entry
+// is encoded as
+//
+//	flags ms-delta seq-delta [field-count field...] value...
+//
+// with varint numbers and length-prefixed strings. Deleting an entry only
+// flags it, and the node is dropped once none of its entries are left.
+type streamNode struct {
+	master       StreamID
+	masterFields []string
+	data         []byte
+	count        int // entries in data, deleted ones included
+	live         int
+}
+
+// streamNodeEntry is an entry decoded from a node without its fields.
+// offset is where the entry starts in the node and fieldsAt where its
+// fields do.
+type streamNodeEntry struct {
+	id       *StreamID
+	deleted  bool
+	offset   int
+	fieldsAt int
+}
+
+// StreamNodeItem is an entry as stored in a node, which may have been
+// deleted but not removed from it yet.
+type StreamNodeItem struct {
+	ID      *StreamID
+	Deleted bool
+	Fields  []*KeyValue
+}
+
+func newStreamNode(id *StreamID, fields []*KeyValue) *streamNode {
+	masterFields := make([]string, len(fields))
+	for i, kv := range fields {
+		masterFields[i] = kv.Key
+	}
+	return &streamNode{master: *id, masterFields: masterFields}
+}
+
+// streamIDKey encodes id big-endian, so that keys sort the way IDs do.
+func streamIDKey(id *StreamID) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(id.millisecondsTime))
+	binary.BigEndian.PutUint64(key[8:], uint64(id.sequenceNumber))
+	return key
+}
+
+func (n *streamNode) key() []byte {
+	return streamIDKey(&n.master)
+}
+
+func (n *streamNode) sameFields(fields []*KeyValue) bool {
+	return slices.EqualFunc(n.masterFields, fields, func(name string, kv *KeyValue) bool {
+		return name == kv.Key
+	})
+}
+
+// encode returns the encoding of an entry relative to the master entry of n.
+func (n *streamNode) encode(id *StreamID, fields []*KeyValue) []byte {
+	var flags byte
+	same := n.sameFields(fields)
+	if same {
+		flags |= streamItemFlagSameFields
+	}
+	buf := []byte{flags}
+	buf = binary.AppendUvarint(buf, uint64(id.millisecondsTime-n.master.millisecondsTime))
+	buf = binary.AppendVarint(buf, int64(id.sequenceNumber-n.master.sequenceNumber))
+	if !same {
+		buf = binary.AppendUvarint(buf, uint64(len(fields)))
+		for _, kv := range fields {
+			buf = appendStreamNodeString(buf, kv.Key)
+		}
+	}
+	for _, kv := range fields {
+		buf = appendStreamNodeString(buf, kv.Value)
+	}
+	return buf
+}
+
+func appendStreamNodeString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// fits reports whether an entry encoded in size bytes can be appended to n
+// without going over the limits, either of which is off when zero.
+func (n *streamNode) fits(size int, maxBytes int, maxEntries int) bool {
+	return (maxBytes <= 0 || len(n.data)+size <= maxBytes) && (maxEntries <= 0 || n.count < maxEntries)
+}
+
+func (n *streamNode) append(encoded []byte) {
+	n.data = append(n.data, encoded...)
+	n.count++
+	n.live++
+}
+
+// entries decodes the IDs of every entry in n, deleted ones included, in
+// order.
+func (n *streamNode) entries() []streamNodeEntry {
+	entries := make([]streamNodeEntry, 0, n.count)
+	for pos := 0; pos < len(n.data); {
+		e := streamNodeEntry{offset: pos, deleted: n.data[pos]&streamItemFlagDeleted != 0}
+		same := n.data[pos]&streamItemFlagSameFields != 0
+		pos++
+		msDelta, l := binary.Uvarint(n.data[pos:])
+		pos += l
+		seqDelta, l := binary.Varint(n.data[pos:])
+		pos += l
+		e.id = NewStreamID(n.master.millisecondsTime+int(msDelta), n.master.sequenceNumber+int(seqDelta))
+		e.fieldsAt = pos
+		skip := len(n.masterFields)
+		if !same {
+			count, l := binary.Uvarint(n.data[pos:])
+			pos += l
+			skip = 2 * int(count)
+		}
+		for range skip {
+			pos = n.skipString(pos)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (n *streamNode) readString(pos int) (string, int) {
+	length, l := binary.Uvarint(n.data[pos:])
+	pos += l
+	return string(n.data[pos : pos+int(length)]), pos + int(length)
+}
+
+func (n *streamNode) skipString(pos int) int {
+	length, l := binary.Uvarint(n.data[pos:])
+	return pos + l + int(length)
+}
+
+// item decodes e into a stream entry with its fields.
+func (n *streamNode) item(e streamNodeEntry) *StreamItem {
+	pos := e.fieldsAt
+	var names []string
+	if n.data[e.offset]&streamItemFlagSameFields != 0 {
+		names = n.masterFields
+	} else {
+		count, l := binary.Uvarint(n.data[pos:])
+		pos += l
+		names = make([]string, count)
+		for i := range names {
+			names[i], pos = n.readString(pos)
+		}
+	}
+	fields := make([]*KeyValue, len(names))
+	for i, name := range names {
+		var value string
+		value, pos = n.readString(pos)
+		fields[i] = &KeyValue{Key: name, Value: value}
+	}
+	return &StreamItem{id: e.id, fields: fields}
+}
+
+// items decodes every entry in n, deleted ones included, with its fields.
+func (n *streamNode) items() []StreamNodeItem {
+	entries := n.entries()
+	items := make([]StreamNodeItem, len(entries))
+	for i, e := range entries {
+		items[i] = StreamNodeItem{ID: e.id, Deleted: e.deleted, Fields: n.item(e).fields}
+	}
+	return items
+}
+
+// find returns the live entry of n with the given ID.
+func (n *streamNode) find(id *StreamID) (streamNodeEntry, bool) {
+	for _, e := range n.entries() {
+		if !e.deleted && e.id.compare(id) == 0 {
+			return e, true
+		}
+	}
+	return streamNodeEntry{}, false
+}
+
+func (n *streamNode) markDeleted(e streamNodeEntry) {
+	n.data[e.offset] |= streamItemFlagDeleted
+	n.live--
+}
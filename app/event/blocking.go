@@ -0,0 +1,129 @@
+package event
+
+import (
+	"net"
+	"slices"
+	"time"
+)
+
+// BlockedClient is a client waiting for data on one or more keys.
+type BlockedClient struct {
+	Conn     net.Conn
+	Database int
+	Keys     []string
+	// Serve is called on the event loop when one of Keys may have become
+	// ready. It returns the reply to the client, which unblocks it, or false
+	// if the key can't serve it after all.
+	Serve func(key string) ([]byte, bool)
+	// Timeout is called on the event loop if the client is still blocked
+	// once its timeout expires.
+	Timeout func()
+	timer   *time.Timer
+	done    bool
+}
+
+type blockingKey struct {
+	database int
+	key      string
+}
+
+// BlockingRegistry tracks blocked clients per key and serves them, oldest
+// first, once a key they wait on is signalled as ready. Apart from the timers
+// it starts, which hand back to the event loop through the event queue, it is
+// only used from the event loop and needs no locking.
+type BlockingRegistry struct {
+	clients    map[blockingKey][]*BlockedClient
+	ready      []blockingKey
+	eventQueue *EventQueue
+}
+
+func NewBlockingRegistry(eq *EventQueue) *BlockingRegistry {
+	return &BlockingRegistry{
+		clients:    make(map[blockingKey][]*BlockedClient),
+		eventQueue: eq,
+	}
+}
+
+// Block registers c on each of its keys. A zero timeout blocks forever.
+func (r *BlockingRegistry) Block(c *BlockedClient, timeout time.Duration) {
+	for _, key := range c.Keys {
+		bk := blockingKey{database: c.Database, key: key}
+		r.clients[bk] = append(r.clients[bk], c)
+	}
+	if timeout > 0 {
+		c.timer = time.AfterFunc(timeout, func() {
+			r.eventQueue.Add(&Event{Callback: func() {
+				if c.done {
+					return
+				}
+				r.unblock(c)
+				c.Timeout()
+			}})
+		})
+	}
+}
+
+// SignalKeyAsReady records that key may now be able to serve blocked
+// clients; they are served by the next HandleReadyKeys.
+func (r *BlockingRegistry) SignalKeyAsReady(database int, key string) {
+	bk := blockingKey{database: database, key: key}
+	if _, ok := r.clients[bk]; !ok || slices.Contains(r.ready, bk) {
+		return
+	}
+	r.ready = append(r.ready, bk)
+}
+
+// ServedReply is the reply to a blocked client that was served.
+type ServedReply struct {
+	Conn  net.Conn
+	Reply []byte
+}
+
+// HandleReadyKeys offers every signalled key to the clients blocked on it in
+// the order they blocked. It returns the replies to the clients served for
+// the caller to send once the changes serving them made are persisted.
+func (r *BlockingRegistry) HandleReadyKeys() []ServedReply {
+	served := []ServedReply{}
+	for len(r.ready) > 0 {
+		bk := r.ready[0]
+		r.ready = r.ready[1:]
+		for _, c := range slices.Clone(r.clients[bk]) {
+			if c.done {
+				continue
+			}
+			if reply, ok := c.Serve(bk.key); ok {
+				r.unblock(c)
+				served = append(served, ServedReply{Conn: c.Conn, Reply: reply})
+			}
+		}
+	}
+	return served
+}
+
+// UnblockConn drops every client blocked on conn without replying, for when
+// the connection has gone away.
+func (r *BlockingRegistry) UnblockConn(conn net.Conn) {
+	for _, clients := range r.clients {
+		for _, c := range slices.Clone(clients) {
+			if c.Conn == conn && !c.done {
+				r.unblock(c)
+			}
+		}
+	}
+}
+
+func (r *BlockingRegistry) unblock(c *BlockedClient) {
+	c.done = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	for _, key := range c.Keys {
+		bk := blockingKey{database: c.Database, key: key}
+		r.clients[bk] = slices.DeleteFunc(r.clients[bk], func(other *BlockedClient) bool {
+			return other == c
+		})
+		if len(r.clients[bk]) == 0 {
+			delete(r.clients, bk)
+		}
+	}
+}
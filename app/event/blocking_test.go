@@ -0,0 +1,86 @@
+package event
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+func TestBlockingRegistryServesInOrder(t *testing.T) {
+	r := NewBlockingRegistry(NewEventQueue())
+	served := []string{}
+	available := 1
+	for _, name := range []string{"first", "second"} {
+		r.Block(&BlockedClient{
+			Keys: []string{"k"},
+			Serve: func(key string) ([]byte, bool) {
+				if available == 0 {
+					return nil, false
+				}
+				available--
+				served = append(served, name)
+				return []byte(name), true
+			},
+		}, 0)
+	}
+	r.SignalKeyAsReady(0, "k")
+	r.HandleReadyKeys()
+	available = 1
+	r.SignalKeyAsReady(0, "k")
+	if replies := r.HandleReadyKeys(); len(replies) != 1 || string(replies[0].Reply) != "second" {
+		t.Errorf("Expected the reply to second to be returned; got %v", replies)
+	}
+	if !utils.SlicesEqual(served, []string{"first", "second"}) {
+		t.Errorf("Expected clients served in order [first second]; got %v", served)
+	}
+	if len(r.clients) != 0 {
+		t.Errorf("Expected no blocked clients left; got %d keys", len(r.clients))
+	}
+}
+
+func TestBlockingRegistryTimeout(t *testing.T) {
+	eq := NewEventQueue()
+	r := NewBlockingRegistry(eq)
+	timedOut := false
+	r.Block(&BlockedClient{
+		Keys:    []string{"k"},
+		Serve:   func(key string) ([]byte, bool) { return nil, true },
+		Timeout: func() { timedOut = true },
+	}, 10*time.Millisecond)
+	select {
+	case e := <-eq.Queue:
+		e.Callback()
+	case <-time.After(time.Second):
+		t.Fatal("Expected a timeout event")
+	}
+	if !timedOut {
+		t.Errorf("Expected the timeout callback to run")
+	}
+	r.SignalKeyAsReady(0, "k")
+	if len(r.ready) != 0 {
+		t.Errorf("Expected a timed out client to be unblocked")
+	}
+}
+
+func TestBlockingRegistryUnblockConn(t *testing.T) {
+	r := NewBlockingRegistry(NewEventQueue())
+	conn, other := net.Pipe()
+	defer other.Close()
+	served := false
+	r.Block(&BlockedClient{
+		Conn: conn,
+		Keys: []string{"a", "b"},
+		Serve: func(key string) ([]byte, bool) {
+			served = true
+			return nil, true
+		},
+	}, 0)
+	r.UnblockConn(conn)
+	r.SignalKeyAsReady(0, "a")
+	r.HandleReadyKeys()
+	if served || len(r.clients) != 0 {
+		t.Errorf("Expected the client to be dropped; served %v with %d keys left", served, len(r.clients))
+	}
+}
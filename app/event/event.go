@@ -4,7 +4,9 @@ import (
 	"net"
 	"sync"
 
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
 	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
 	"github.com/codecrafters-io/redis-starter-go/app/replication"
 	"github.com/codecrafters-io/redis-starter-go/app/utils"
 )
@@ -13,6 +15,8 @@ type Event struct {
 	Conn net.Conn
 	Cmd  utils.Command
 	Ctx  Context
+	// Callback, when set, is run on the event loop instead of a command.
+	Callback func()
 }
 
 type EventQueue struct {
@@ -58,4 +62,31 @@ type Context struct {
 	ConfigParams    map[string]string
 	ReplicationInfo *replication.ReplicationInfo
 	EventQueue      *EventQueue
+	Blocking        *BlockingRegistry
+	Saver           *rdb.Saver
+	AOF             *aof.AOF
+	rewritten       bool
+	rewrites        [][]string
+	dirty           int
+}
+
+// RewritePropagation replaces the command sent to replicas with cmds. Calling
+// it with no commands means nothing is propagated.
+func (c *Context) RewritePropagation(cmds ...[]string) {
+	c.rewritten = true
+	c.rewrites = append(c.rewrites, cmds...)
+}
+
+func (c *Context) PropagationRewrite() ([][]string, bool) {
+	return c.rewrites, c.rewritten
+}
+
+// AddDirty counts n changes the command made to the dataset, like Redis's
+// server.dirty. Only a command that made some is saved and propagated.
+func (c *Context) AddDirty(n int) {
+	c.dirty += n
+}
+
+func (c *Context) Dirty() int {
+	return c.dirty
 }
@@ -4,7 +4,9 @@ import (
 	"flag"
 	"log"
 	"os"
+	"strconv"
 
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
 	"github.com/codecrafters-io/redis-starter-go/app/logger"
 	"github.com/codecrafters-io/redis-starter-go/app/replication"
 	"github.com/codecrafters-io/redis-starter-go/app/server"
@@ -20,11 +22,43 @@ func main() {
 	dbfilename := flag.String("dbfilename", "defaultdb", "The rdb file to initialise the redis cache with.")
 	port := flag.String("port", "6379", "The port number to initialise the redis cache on.")
 	replicaof := flag.String("replicaof", "", "The \"<HOSTNAME> <PORT>\" which this redis cache is a replica of.")
+	streamRetention := flag.String("stream-retention", "", "The \"<pattern> <maxage|maxlen> <threshold> ...\" retention rules streams are trimmed to in the background.")
+	streamNodeMaxBytes := flag.String("stream-node-max-bytes", strconv.Itoa(entry.DEFAULT_STREAM_NODE_MAX_BYTES), "The most bytes a node of a stream holds, or 0 for no limit.")
+	streamNodeMaxEntries := flag.String("stream-node-max-entries", strconv.Itoa(entry.DEFAULT_STREAM_NODE_MAX_ENTRIES), "The most entries a node of a stream holds, or 0 for no limit.")
+	setMaxIntsetEntries := flag.String("set-max-intset-entries", strconv.Itoa(entry.DEFAULT_SET_MAX_INTSET_ENTRIES), "The most members a set of integers holds before it is converted to a hash table.")
+	zsetMaxListpackEntries := flag.String("zset-max-listpack-entries", strconv.Itoa(entry.DEFAULT_ZSET_MAX_LISTPACK_ENTRIES), "The most members a sorted set holds before it is converted to a skiplist.")
+	zsetMaxListpackValue := flag.String("zset-max-listpack-value", strconv.Itoa(entry.DEFAULT_ZSET_MAX_LISTPACK_VALUE), "The longest member, in bytes, a sorted set holds before it is converted to a skiplist.")
+	save := flag.String("save", "3600 1 300 100 60 10000", "The \"<seconds> <changes> ...\" points at which the dataset is saved in the background, or \"\" to never save it automatically.")
+	stopWritesOnBgsaveError := flag.String("stop-writes-on-bgsave-error", "yes", "Whether writes are refused while the last background save failed.")
+	rdbCompression := flag.String("rdbcompression", "yes", "Whether strings are LZF-compressed in RDB files.")
+	rdbChecksum := flag.String("rdbchecksum", "yes", "Whether RDB files are written with a CRC64 checksum and have it checked when loaded.")
+	appendOnly := flag.String("appendonly", "no", "Whether write commands are logged to the append-only file, which is loaded instead of the rdb file on startup.")
+	appendFilename := flag.String("appendfilename", "appendonly.aof", "The base name of the files of the append-only file.")
+	appendDirname := flag.String("appenddirname", "appendonlydir", "The directory in dir holding the files of the append-only file and their manifest.")
+	appendFsync := flag.String("appendfsync", "everysec", "When the append-only file is synced to disk: \"always\", \"everysec\" or \"no\".")
+	autoAOFRewritePercentage := flag.String("auto-aof-rewrite-percentage", "100", "How much the append-only file grows, in percent of its size after the last rewrite, before it is rewritten in the background, or 0 to never rewrite it automatically.")
+	autoAOFRewriteMinSize := flag.String("auto-aof-rewrite-min-size", "64mb", "The size the append-only file has to reach before it is rewritten automatically.")
 	flag.Parse()
 	configParams := make(map[string]string)
 	configParams["dir"] = *dbdir
 	configParams["dbfilename"] = *dbfilename
 	configParams["port"] = *port
+	configParams["stream-retention"] = *streamRetention
+	configParams["stream-node-max-bytes"] = *streamNodeMaxBytes
+	configParams["stream-node-max-entries"] = *streamNodeMaxEntries
+	configParams["set-max-intset-entries"] = *setMaxIntsetEntries
+	configParams["zset-max-listpack-entries"] = *zsetMaxListpackEntries
+	configParams["zset-max-listpack-value"] = *zsetMaxListpackValue
+	configParams["save"] = *save
+	configParams["stop-writes-on-bgsave-error"] = *stopWritesOnBgsaveError
+	configParams["rdbcompression"] = *rdbCompression
+	configParams["rdbchecksum"] = *rdbChecksum
+	configParams["appendonly"] = *appendOnly
+	configParams["appendfilename"] = *appendFilename
+	configParams["appenddirname"] = *appendDirname
+	configParams["appendfsync"] = *appendFsync
+	configParams["auto-aof-rewrite-percentage"] = *autoAOFRewritePercentage
+	configParams["auto-aof-rewrite-min-size"] = *autoAOFRewriteMinSize
 
 	replicationInfo := replication.NewReplicationInfo(*replicaof)
 	r, err := server.New(configParams, replicationInfo)
@@ -2,7 +2,9 @@ package protocol
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 )
 
 func ToSimpleString(str string) []byte {
@@ -24,6 +26,12 @@ func ToArrayBulkStrings(strs []string) []byte {
 	return ret
 }
 
+// ToArrayHeader starts an array of n elements; the caller appends the encoded
+// elements itself.
+func ToArrayHeader(n int) []byte {
+	return []byte("*" + strconv.Itoa(n) + crlf)
+}
+
 func ToBulkString(s string) []byte {
 	ret := []byte{}
 	ret = append(ret, '$')
@@ -60,3 +68,72 @@ func CommandAndArgsToBulkString(cmd string, args []string) []byte {
 	s = append(s, args...)
 	return ToArrayBulkStrings(s)
 }
+
+// FormatDouble renders f the way Redis replies with scores: the shortest
+// digits that round-trip, written without an exponent unless the number is
+// very large or very small, and "inf"/"-inf" for infinities.
+func FormatDouble(f float64) string {
+	if math.IsInf(f, 1) {
+		return "inf"
+	}
+	if math.IsInf(f, -1) {
+		return "-inf"
+	}
+	if f == 0 {
+		if math.Signbit(f) {
+			return "-0"
+		}
+		return "0"
+	}
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+	// Shortest round-trip digits as d.ddde±x, giving f = digits * 10^k.
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, expStr, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	exp, _ := strconv.Atoi(expStr)
+	n := len(digits)
+	k := exp - (n - 1)
+	absExp := exp
+	if absExp < 0 {
+		absExp = -absExp
+	}
+	if k >= 0 && absExp < n+7 {
+		return sign + digits + strings.Repeat("0", k)
+	}
+	if k < 0 && (k > -7 || absExp < 4) {
+		offset := n + k
+		if offset <= 0 {
+			return sign + "0." + strings.Repeat("0", -offset) + digits
+		}
+		return sign + digits[:offset] + "." + digits[offset:]
+	}
+	maxDigits := 18
+	if sign != "" {
+		maxDigits = 17
+	}
+	if n > maxDigits {
+		digits = digits[:maxDigits]
+		n = maxDigits
+	}
+	ret := sign + digits[:1]
+	if n > 1 {
+		ret += "." + digits[1:]
+	}
+	expSign := "+"
+	if exp < 0 {
+		expSign = "-"
+	}
+	return ret + "e" + expSign + strconv.Itoa(absExp)
+}
+
+func ToBulkDouble(f float64) []byte {
+	return ToBulkString(FormatDouble(f))
+}
+
+func NullArray() []byte {
+	return []byte("*-1\r\n")
+}
@@ -1,6 +1,7 @@
 package protocol
 
 import (
+	"math"
 	"strconv"
 	"testing"
 
@@ -41,3 +42,32 @@ func TestTBulkString(t *testing.T) {
 	}
 
 }
+
+func TestFormatDouble(t *testing.T) {
+	tests := []struct {
+		input    float64
+		expected string
+	}{
+		{0, "0"},
+		{1.5, "1.5"},
+		{-3.5, "-3.5"},
+		{0.1, "0.1"},
+		{0.30000000000000004, "0.30000000000000004"},
+		{1234567, "1234567"},
+		{1e7, "10000000"},
+		{1e8, "1e+8"},
+		{1e20, "1e+20"},
+		{0.0001, "0.0001"},
+		{1.5e-7, "1.5e-7"},
+		{123.456, "123.456"},
+		{math.Inf(1), "inf"},
+		{math.Inf(-1), "-inf"},
+	}
+
+	for _, tt := range tests {
+		got := FormatDouble(tt.input)
+		if got != tt.expected {
+			t.Errorf("Expected: %s, but got %s", tt.expected, got)
+		}
+	}
+}
@@ -28,7 +28,7 @@ func (p *Parser) Parse(reader *bufio.Reader, commandChan chan utils.Command, rep
 	for {
 		prefix, err := reader.Peek(1)
 		if err == io.EOF {
-			continue
+			return
 		} else if err != nil {
 			log.Printf("peek error: %v", err)
 			return
@@ -38,9 +38,11 @@ func (p *Parser) Parse(reader *bufio.Reader, commandChan chan utils.Command, rep
 		var str string
 		switch prefix[0] {
 		case '*':
-			strs, byteLen, err := p.parseArrayBulkStrings(reader)
+			var strs []string
+			var byteLen int
+			strs, byteLen, err = p.parseArrayBulkStrings(reader)
 			if err != nil {
-				log.Printf("Error in parsing command: %s", err)
+				break
 			}
 			if len(strs) == 3 && strings.ToLower(strs[0]) == "replconf" && strings.ToLower(strs[1]) == "ack" {
 				replicaResponseChan <- strings.Join(strs, " ")
@@ -48,15 +50,16 @@ func (p *Parser) Parse(reader *bufio.Reader, commandChan chan utils.Command, rep
 			}
 			cmd = utils.Command{CMD: strs[0], ARGS: strs[1:], ByteLen: byteLen}
 		case '+':
-			str, numBytes, err := p.parseSimpleString(reader)
+			var numBytes int
+			str, numBytes, err = p.parseSimpleString(reader)
 			if err != nil {
-				log.Printf("Error in parsing command: %s", err)
+				break
 			}
 			if isReplicaResp(str) {
 				replicaResponseChan <- str
 				continue
 			}
-			commandChan <- utils.Command{CMD: str, ByteLen: numBytes}
+			cmd = utils.Command{CMD: str, ByteLen: numBytes}
 
 		case '$':
 			str, _, err = p.parseBulkString(reader)
@@ -1,6 +1,8 @@
 package protocol
 
 import (
+	"bufio"
+	"slices"
 	"strings"
 	"testing"
 
@@ -9,48 +11,43 @@ import (
 
 func TestParser(t *testing.T) {
 	tests := []struct {
-		input        []byte
-		expectedCmd  string
-		expectedArgs []string
-		errorStr     string
+		input            string
+		expectedCmds     []utils.Command
+		expectedReplicas []string
 	}{
-		{[]byte("*2\r\n$4\r\nECHO\r\n$3\r\nhey\r\n"), "echo", []string{"hey"}, ""},
-		{[]byte("*2\n$4\r\nECHO\r\n$3\r\nhey\r\n"), "", []string{}, "int not followed by crlf"},
-		{[]byte("*2\r\n$4\r\nECHO\n$3\r\nhey\r\n"), "", []string{}, "string not followed by crlf"},
-		{[]byte("*2\r\n4\r\nECHO\r\n3\r\nhey\r\n"), "", []string{}, "string does not start with $"},
-		{[]byte("2\r\n$4\r\nECHO\r\n3\r\nhey\r\n"), "", []string{}, "command does not start with valid char"},
-		{[]byte("+PING\r\n"), "ping", []string{}, ""},
-		{[]byte("+PING"), "", []string{}, "simple string does not end with crlf"},
+		{"*2\r\n$4\r\nECHO\r\n$3\r\nhey\r\n", []utils.Command{{CMD: "ECHO", ARGS: []string{"hey"}, ByteLen: 23}}, nil},
+		{"*1\r\n$4\r\nPING\r\n*2\r\n$4\r\nECHO\r\n$2\r\nhi\r\n", []utils.Command{{CMD: "PING", ARGS: []string{}, ByteLen: 14}, {CMD: "ECHO", ARGS: []string{"hi"}, ByteLen: 22}}, nil},
+		{"+PING\r\n", []utils.Command{{CMD: "PING", ByteLen: 7}}, nil},
+		{"+OK\r\n*3\r\n$8\r\nREPLCONF\r\n$3\r\nACK\r\n$1\r\n0\r\n", nil, []string{"OK", "REPLCONF ACK 0"}},
+		// Parsing stops at the first malformed command.
+		{"*2\n$4\r\nECHO\r\n$3\r\nhey\r\n", nil, nil},
+		{"*2\r\n$4\r\nECHO\n$3\r\nhey\r\n", nil, nil},
+		{"*2\r\n4\r\nECHO\r\n3\r\nhey\r\n", nil, nil},
+		{"*1\r\n$4\r\nPING\r\n2\r\n$4\r\nECHO\r\n", []utils.Command{{CMD: "PING", ARGS: []string{}, ByteLen: 14}}, nil},
+		{"+PING", nil, nil},
 	}
 
-	p := NewParser()
-
 	for _, tt := range tests {
-		func() {
-			gotCmd, gotArgs, err := p.ParseInputToCommandAndArgs(tt.input)
-			if tt.errorStr == "" && err != nil {
-				t.Errorf("Error during parsing: %s", err)
-				return
-			}
-			if tt.errorStr != "" && err == nil {
-				t.Errorf("Expected error: %s, but got none", tt.errorStr)
-				return
-			}
-			if tt.errorStr != "" {
-				if !strings.Contains(err.Error(), tt.errorStr) {
-					t.Errorf("unexpected error; got %s; want %s", err.Error(), tt.errorStr)
-				}
-				return
-			}
-			if gotCmd != tt.expectedCmd {
-				t.Errorf("got %s; want %s", gotCmd, tt.expectedCmd)
-				return
-			}
-
-			if !utils.SlicesEqual(gotArgs, tt.expectedArgs) {
-				t.Errorf("got %s; want %s", gotArgs, tt.expectedArgs)
-			}
-
-		}()
+		commandChan := make(chan utils.Command, 10)
+		replicaResponseChan := make(chan string, 10)
+		NewParser().Parse(bufio.NewReader(strings.NewReader(tt.input)), commandChan, replicaResponseChan)
+		gotCmds := []utils.Command{}
+		for cmd := range commandChan {
+			gotCmds = append(gotCmds, cmd)
+		}
+		gotReplicas := []string{}
+		for resp := range replicaResponseChan {
+			gotReplicas = append(gotReplicas, resp)
+		}
+		if !slices.EqualFunc(gotCmds, tt.expectedCmds, commandsEqual) {
+			t.Errorf("%q: got commands %v; want %v", tt.input, gotCmds, tt.expectedCmds)
+		}
+		if !slices.Equal(gotReplicas, tt.expectedReplicas) {
+			t.Errorf("%q: got replica responses %q; want %q", tt.input, gotReplicas, tt.expectedReplicas)
+		}
 	}
 }
+
+func commandsEqual(a, b utils.Command) bool {
+	return a.CMD == b.CMD && utils.SlicesEqual(a.ARGS, b.ARGS) && a.ByteLen == b.ByteLen
+}
@@ -0,0 +1,32 @@
+package rdb
+
+// crc64Jones is the polynomial 0xad93d23594c935a9 of the CRC-64 variant
+// Redis uses to checksum RDB files, known as CRC-64/Jones, bit-reversed since
+// the checksum is computed least significant bit first.
+const crc64Jones uint64 = 0x95ac9329ac4bc9b5
+
+var crc64Table = makeCrc64Table()
+
+func makeCrc64Table() *[256]uint64 {
+	table := new([256]uint64)
+	for i := range table {
+		crc := uint64(i)
+		for range 8 {
+			if crc&1 == 1 {
+				crc = crc>>1 ^ crc64Jones
+			} else {
+				crc >>= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}
+
+// crc64 extends crc, the checksum of the bytes before p, to cover p as well.
+func crc64(crc uint64, p []byte) uint64 {
+	for _, b := range p {
+		crc = crc64Table[byte(crc)^b] ^ crc>>8
+	}
+	return crc
+}
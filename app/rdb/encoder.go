@@ -0,0 +1,380 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+const (
+	RDB_VERSION      string = "0011"
+	REDIS_VERSION    string = "7.2.0"
+	INTSET_ENC_INT16 int    = 2
+	INTSET_ENC_INT32 int    = 4
+	INTSET_ENC_INT64 int    = 8
+	// QUICKLIST_NODE_MAX_BYTES is the size lists are split into listpacks
+	// at, the 8kb of the default list-max-listpack-size.
+	QUICKLIST_NODE_MAX_BYTES int = 8192
+)
+
+// rdbWriter writes the pieces of an RDB file, keeping the CRC64 of
+// everything written so far. The first error sticks and later writes are
+// skipped.
+type rdbWriter struct {
+	w        *bufio.Writer
+	crc      uint64
+	err      error
+	compress bool
+}
+
+func (w *rdbWriter) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	w.crc = crc64(w.crc, p)
+	_, w.err = w.w.Write(p)
+}
+
+func (w *rdbWriter) writeByte(b byte) {
+	w.write([]byte{b})
+}
+
+// writeLength writes n in the RDB length encoding: 6 bits, 14 bits, or a
+// 32 or 64-bit big-endian number after a marker byte.
+func (w *rdbWriter) writeLength(n uint64) {
+	switch {
+	case n < 1<<6:
+		w.writeByte(byte(n))
+	case n < 1<<14:
+		w.write([]byte{0x40 | byte(n>>8), byte(n)})
+	case n <= math.MaxUint32:
+		w.write(binary.BigEndian.AppendUint32([]byte{0x80}, uint32(n)))
+	default:
+		w.write(binary.BigEndian.AppendUint64([]byte{0x81}, n))
+	}
+}
+
+// writeString writes s as an 8, 16 or 32-bit integer when it is the
+// canonical form of one, LZF-compressed when compression is on, it is over
+// 20 bytes and it shrinks by more than 4, and as a length-prefixed string
+// otherwise, as Redis does.
+func (w *rdbWriter) writeString(s string) {
+	if len(s) <= 11 {
+		if v, err := strconv.ParseInt(s, 10, 32); err == nil && strconv.FormatInt(v, 10) == s {
+			switch {
+			case v >= math.MinInt8 && v <= math.MaxInt8:
+				w.write([]byte{ENC_INT8, byte(v)})
+			case v >= math.MinInt16 && v <= math.MaxInt16:
+				w.write(binary.LittleEndian.AppendUint16([]byte{ENC_INT16}, uint16(v)))
+			default:
+				w.write(binary.LittleEndian.AppendUint32([]byte{ENC_INT32}, uint32(v)))
+			}
+			return
+		}
+	}
+	if w.compress && len(s) > 20 {
+		if compressed := lzfCompress([]byte(s), len(s)-4); compressed != nil {
+			w.writeByte(ENC_LZF)
+			w.writeLength(uint64(len(compressed)))
+			w.writeLength(uint64(len(s)))
+			w.write(compressed)
+			return
+		}
+	}
+	w.writeLength(uint64(len(s)))
+	w.write([]byte(s))
+}
+
+func (w *rdbWriter) writeAux(key string, value string) {
+	w.writeByte(METADATA_OPCODE)
+	w.writeString(key)
+	w.writeString(value)
+}
+
+// Options are the config parameters that decide where and how snapshots are
+// written.
+type Options struct {
+	Dir         string
+	Filename    string
+	Compression bool
+	Checksum    bool
+}
+
+// OptionsFromConfig reads the Options from the server's config parameters.
+func OptionsFromConfig(params map[string]string) Options {
+	return Options{
+		Dir:         params["dir"],
+		Filename:    params["dbfilename"],
+		Compression: !strings.EqualFold(params["rdbcompression"], "no"),
+		Checksum:    !strings.EqualFold(params["rdbchecksum"], "no"),
+	}
+}
+
+// Write serializes database as an RDB file: the header and aux fields, then
+// for each database a SELECTDB and RESIZEDB followed by its keys with their
+// expiry times, and finally the EOF opcode and the CRC64 checksum of
+// everything before it, or zero with checksums off.
+func Write(out io.Writer, database map[int]map[string]entry.Entry, opts Options) error {
+	w := &rdbWriter{w: bufio.NewWriter(out), compress: opts.Compression}
+	w.write([]byte(MAGIC_WORD + RDB_VERSION))
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	w.writeAux("redis-ver", REDIS_VERSION)
+	w.writeAux("redis-bits", "64")
+	w.writeAux("ctime", strconv.FormatInt(time.Now().Unix(), 10))
+	w.writeAux("used-mem", strconv.FormatUint(mem.Alloc, 10))
+	w.writeAux("aof-base", "0")
+
+	dbIdxs := []int{}
+	for idx, db := range database {
+		if len(db) > 0 {
+			dbIdxs = append(dbIdxs, idx)
+		}
+	}
+	slices.Sort(dbIdxs)
+	for _, idx := range dbIdxs {
+		writeDatabase(w, idx, database[idx])
+	}
+	w.writeByte(CHECKSUM_OPCODE)
+	checksum := w.crc
+	if !opts.Checksum {
+		checksum = 0
+	}
+	w.write(binary.LittleEndian.AppendUint64(nil, checksum))
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+func writeDatabase(w *rdbWriter, idx int, db map[string]entry.Entry) {
+	expires := 0
+	for _, e := range db {
+		if x, ok := e.(entry.Expiring); ok && !x.ExpiryTime().IsZero() {
+			expires++
+		}
+	}
+	w.writeByte(DATABASE_OPCODE)
+	w.writeLength(uint64(idx))
+	w.writeByte(HASH_TABLE_OPCODE)
+	w.writeLength(uint64(len(db)))
+	w.writeLength(uint64(expires))
+	for key, e := range db {
+		writeEntry(w, key, e)
+	}
+}
+
+// writeEntry writes a key and its value, preceded by its expiry time if it
+// has one.
+func writeEntry(w *rdbWriter, key string, e entry.Entry) {
+	if x, ok := e.(entry.Expiring); ok && !x.ExpiryTime().IsZero() {
+		w.writeByte(EXPIRY_MILLISECONDS)
+		w.write(binary.LittleEndian.AppendUint64(nil, uint64(x.ExpiryTime().UnixMilli())))
+	}
+	switch v := e.(type) {
+	case *entry.RedisString:
+		w.writeByte(TYPE_STRING)
+		w.writeString(key)
+		w.writeString(v.Value())
+	case *entry.Set:
+		writeSet(w, key, v)
+	case *entry.SortedSet:
+		writeSortedSet(w, key, v)
+	case *entry.List:
+		writeList(w, key, v)
+	case *entry.Hash:
+		writeHash(w, key, v)
+	case *entry.Stream:
+		writeStream(w, key, v)
+	}
+}
+
+// writeSet writes an intset-encoded set as the intset blob Redis keeps in
+// memory, and any other set as its members.
+func writeSet(w *rdbWriter, key string, s *entry.Set) {
+	members := s.Members()
+	if s.Encoding() != "intset" {
+		w.writeByte(TYPE_SET)
+		w.writeString(key)
+		w.writeLength(uint64(len(members)))
+		for _, m := range members {
+			w.writeString(m)
+		}
+		return
+	}
+	values := make([]int64, len(members))
+	encoding := INTSET_ENC_INT16
+	for i, m := range members {
+		values[i], _ = strconv.ParseInt(m, 10, 64)
+		switch {
+		case values[i] < math.MinInt32 || values[i] > math.MaxInt32:
+			encoding = INTSET_ENC_INT64
+		case (values[i] < math.MinInt16 || values[i] > math.MaxInt16) && encoding == INTSET_ENC_INT16:
+			encoding = INTSET_ENC_INT32
+		}
+	}
+	slices.Sort(values)
+	blob := binary.LittleEndian.AppendUint32(nil, uint32(encoding))
+	blob = binary.LittleEndian.AppendUint32(blob, uint32(len(values)))
+	for _, v := range values {
+		switch encoding {
+		case INTSET_ENC_INT16:
+			blob = binary.LittleEndian.AppendUint16(blob, uint16(v))
+		case INTSET_ENC_INT32:
+			blob = binary.LittleEndian.AppendUint32(blob, uint32(v))
+		default:
+			blob = binary.LittleEndian.AppendUint64(blob, uint64(v))
+		}
+	}
+	w.writeByte(TYPE_SET_INTSET)
+	w.writeString(key)
+	w.writeString(string(blob))
+}
+
+// writeSortedSet writes a listpack-encoded sorted set as a listpack of
+// alternating members and scores, and a skiplist-encoded one as members
+// with binary scores from the highest down, as Redis does.
+func writeSortedSet(w *rdbWriter, key string, z *entry.SortedSet) {
+	members := z.Slice(0, z.Len())
+	if z.Encoding() == "listpack" {
+		lp := &listpack{}
+		for _, m := range members {
+			lp.appendString(m.Member)
+			lp.appendString(protocol.FormatDouble(m.Score))
+		}
+		w.writeByte(TYPE_ZSET_LISTPACK)
+		w.writeString(key)
+		w.writeString(string(lp.bytes()))
+		return
+	}
+	w.writeByte(TYPE_ZSET_2)
+	w.writeString(key)
+	w.writeLength(uint64(len(members)))
+	for i := len(members) - 1; i >= 0; i-- {
+		w.writeString(members[i].Member)
+		w.write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(members[i].Score)))
+	}
+}
+
+// writeList writes a list as a quicklist of listpacks of up to
+// QUICKLIST_NODE_MAX_BYTES each.
+func writeList(w *rdbWriter, key string, l *entry.List) {
+	nodes := []*listpack{{}}
+	for _, element := range l.Elements() {
+		node := nodes[len(nodes)-1]
+		if node.count > 0 && len(node.entries)+len(element) > QUICKLIST_NODE_MAX_BYTES {
+			node = &listpack{}
+			nodes = append(nodes, node)
+		}
+		node.appendString(element)
+	}
+	w.writeByte(TYPE_LIST_QUICKLIST_2)
+	w.writeString(key)
+	w.writeLength(uint64(len(nodes)))
+	for _, node := range nodes {
+		w.writeLength(uint64(QUICKLIST_NODE_CONTAINER_PACKED))
+		w.writeString(string(node.bytes()))
+	}
+}
+
+// writeHash writes a hash as its fields and values. RDB version 11 has no
+// type for field expiry times, so they are not kept, but fields that have
+// already expired are left out.
+func writeHash(w *rdbWriter, key string, h *entry.Hash) {
+	now := time.Now()
+	fields := []string{}
+	for _, field := range h.Fields() {
+		if expiry := h.ExpiryTime(field); expiry.IsZero() || expiry.After(now) {
+			fields = append(fields, field)
+		}
+	}
+	w.writeByte(TYPE_HASH)
+	w.writeString(key)
+	w.writeLength(uint64(len(fields)))
+	for _, field := range fields {
+		value, _ := h.Get(field)
+		w.writeString(field)
+		w.writeString(value)
+	}
+}
+
+// latestSnapshot is how long the last Snapshot took, in nanoseconds.
+var latestSnapshot atomic.Int64
+
+// LatestSnapshotDuration returns how long the last Snapshot took, INFO's
+// latest_fork_usec.
+func LatestSnapshotDuration() time.Duration {
+	return time.Duration(latestSnapshot.Load())
+}
+
+// Snapshot returns a point-in-time copy of database that can be written out
+// while the original keeps changing, and the function to call once it has
+// been. Where Redis forks and lets the kernel copy pages on write, only the
+// maps of keys are copied here: strings are never modified in place, and
+// the collections are shared until a command writes to one, when
+// entry.Writable puts a copy of it in the keyspace. The stall that copying
+// the keys causes on the caller's goroutine is what BenchmarkSnapshot
+// measures and LatestSnapshotDuration reports.
+func Snapshot(database map[int]map[string]entry.Entry) (map[int]map[string]entry.Entry, func()) {
+	start := time.Now()
+	defer func() { latestSnapshot.Store(int64(time.Since(start))) }()
+	snapshot := make(map[int]map[string]entry.Entry, len(database))
+	for idx, db := range database {
+		snapshot[idx] = maps.Clone(db)
+	}
+	return snapshot, entry.TakeSnapshot()
+}
+
+// SaveToFile writes database to a temporary file in the directory of opts,
+// syncs it to disk and renames it to its filename, so the file there is
+// always complete.
+func SaveToFile(opts Options, database map[int]map[string]entry.Entry) error {
+	tmpPath := filepath.Join(opts.Dir, fmt.Sprintf("temp-%d-%d.rdb", os.Getpid(), time.Now().UnixNano()))
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, database, opts); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(opts.Dir, opts.Filename)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(opts.Dir)
+}
+
+// syncDir syncs dir so that a rename into it survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
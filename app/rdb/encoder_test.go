@@ -0,0 +1,161 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+)
+
+func TestCrc64(t *testing.T) {
+	if got := crc64(0, []byte("123456789")); got != 0xe9c6d914c4b8d9ca {
+		t.Errorf("Expected 0xe9c6d914c4b8d9ca; got %#x", got)
+	}
+}
+
+func TestWriteStrings(t *testing.T) {
+	expiry := time.UnixMilli(4102444800000)
+	values := map[string]string{
+		"small":    "12",
+		"negative": "-100",
+		"int16":    "-30000",
+		"int32":    "2000000000",
+		"too big":  "3000000000",
+		"padded":   "007",
+		"short":    "bazqux",
+		"medium":   strings.Repeat("m", 100),
+		"long":     strings.Repeat("l", 20000),
+	}
+	database := map[int]map[string]entry.Entry{0: {"expiring": entry.NewRedisString("bar", expiry)}, 3: {}}
+	for key, value := range values {
+		database[0][key] = entry.NewRedisString(value, time.Time{})
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, database, Options{Checksum: true}); err != nil {
+		t.Fatalf("Error writing: %s", err)
+	}
+	data := buf.Bytes()
+	checksum := binary.LittleEndian.Uint64(data[len(data)-CHECKSUM_LENGTH:])
+	if expected := crc64(0, data[:len(data)-CHECKSUM_LENGTH]); checksum != expected {
+		t.Errorf("Expected checksum %#x; got %#x", expected, checksum)
+	}
+
+	got, err := newRdbFromReader(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("Error reading back: %s", err)
+	}
+	if got.header.version != RDB_VERSION || got.metadata["redis-ver"] != REDIS_VERSION || got.metadata["redis-bits"] != "64" {
+		t.Errorf("Expected version %s written by %s; got %s and %v", RDB_VERSION, REDIS_VERSION, got.header.version, got.metadata)
+	}
+	if len(got.Database) != 1 || len(got.Database[0]) != len(values)+1 {
+		t.Fatalf("Expected only database 0 with %d keys; got %v", len(values)+1, got.Database)
+	}
+	for key, value := range values {
+		s, ok := got.Database[0][key].(*entry.RedisString)
+		if !ok || s.Value() != value || !s.ExpiryTime().IsZero() {
+			t.Errorf("%s: expected %.20s without expiry; got %v", key, value, got.Database[0][key])
+		}
+	}
+	if s := got.Database[0]["expiring"].(*entry.RedisString); !s.ExpiryTime().Equal(expiry) {
+		t.Errorf("Expected expiry %s; got %s", expiry, s.ExpiryTime())
+	}
+}
+
+func TestListpack(t *testing.T) {
+	lp := &listpack{}
+	lp.appendString("a")
+	lp.appendString("1")
+	lp.appendString("-1")
+	lp.appendString("1.5")
+	lp.appendString(strings.Repeat("x", 200))
+	expected := []byte{0x03, 0x01, 0x00, 0x00, 0x05, 0x00}
+	expected = append(expected, 0x81, 'a', 0x02)
+	expected = append(expected, 0x01, 0x01)
+	expected = append(expected, 0xDF, 0xFF, 0x02)
+	expected = append(expected, 0x83, '1', '.', '5', 0x04)
+	expected = append(expected, 0xE0, 200)
+	expected = append(expected, []byte(strings.Repeat("x", 200))...)
+	expected = append(expected, 0x01, 0x80|74)
+	expected = append(expected, LISTPACK_EOF)
+	binary.LittleEndian.PutUint32(expected, uint32(len(expected)))
+
+	if got := lp.bytes(); !bytes.Equal(got, expected) {
+		t.Errorf("Expected %x; got %x", expected, got)
+	}
+}
+
+// BenchmarkSnapshot measures how long a snapshot of a keyspace of 100k
+// strings and 1000 sets, sorted sets and streams of 100 elements each
+// stalls the event loop for.
+func BenchmarkSnapshot(b *testing.B) {
+	db := map[string]entry.Entry{}
+	for i := range 100000 {
+		db[fmt.Sprintf("string:%d", i)] = entry.NewRedisString("value", time.Time{})
+	}
+	for i := range 1000 {
+		s := entry.NewSet(entry.DEFAULT_SET_MAX_INTSET_ENTRIES)
+		z := entry.NewSortedSet(entry.DEFAULT_ZSET_MAX_LISTPACK_ENTRIES, entry.DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+		stream := entry.NewStream(entry.DEFAULT_STREAM_NODE_MAX_BYTES, entry.DEFAULT_STREAM_NODE_MAX_ENTRIES)
+		for j := range 100 {
+			member := fmt.Sprintf("member:%d", j)
+			s.Add(member)
+			z.Add(member, float64(j))
+			stream.Add(fmt.Sprintf("%d-0", j+1), []*entry.KeyValue{{Key: "field", Value: member}})
+		}
+		db[fmt.Sprintf("set:%d", i)] = s
+		db[fmt.Sprintf("zset:%d", i)] = z
+		db[fmt.Sprintf("stream:%d", i)] = stream
+	}
+	database := map[int]map[string]entry.Entry{0: db}
+	b.ResetTimer()
+	for range b.N {
+		_, release := Snapshot(database)
+		release()
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := entry.NewSet(entry.DEFAULT_SET_MAX_INTSET_ENTRIES)
+	s.Add("1")
+	z := entry.NewSortedSet(1, entry.DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+	z.Add("a", 1)
+	z.Add("b", 2)
+	database := map[int]map[string]entry.Entry{0: {"s": s, "z": z}}
+
+	snapshot, release := Snapshot(database)
+	// Commands write to what entry.Writable returns in place of the entry.
+	written := entry.Writable(s).(*entry.Set)
+	written.Add("x")
+	database[0]["s"] = written
+	database[0]["z"] = entry.Writable(z)
+	database[0]["z"].(*entry.SortedSet).Add("c", 3)
+	database[0]["new"] = entry.NewRedisString("v", time.Time{})
+	if entry.Writable(written) != written {
+		t.Errorf("Expected a copy made since the snapshot to be written to in place")
+	}
+
+	if len(snapshot[0]) != 2 {
+		t.Errorf("Expected 2 keys in the snapshot; got %d", len(snapshot[0]))
+	}
+	if got := snapshot[0]["s"].(*entry.Set); got.Len() != 1 || got.Encoding() != "intset" {
+		t.Errorf("Expected the set to keep 1 member as an intset; got %d as %s", got.Len(), got.Encoding())
+	}
+	if got := snapshot[0]["z"].(*entry.SortedSet); got.Len() != 2 || got.Encoding() != "skiplist" {
+		t.Errorf("Expected the sorted set to keep 2 members as a skiplist; got %d as %s", got.Len(), got.Encoding())
+	}
+	if got := database[0]["s"].(*entry.Set); got.Len() != 2 {
+		t.Errorf("Expected the set in the keyspace to have 2 members; got %d", got.Len())
+	}
+
+	// Once the snapshot is written out, nothing needs copying.
+	release()
+	if entry.Writable(z) != z {
+		t.Errorf("Expected the sorted set to be written to in place once the snapshot is released")
+	}
+}
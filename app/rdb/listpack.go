@@ -0,0 +1,195 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	LISTPACK_HEADER_SIZE int  = 6
+	LISTPACK_EOF         byte = 0xFF
+)
+
+// listpack builds the serialized form of a Redis listpack: a 4-byte total
+// size and 2-byte element count, both little-endian, then the elements and a
+// terminating 0xFF. Each element is its encoding and data followed by the
+// length of the two, written backwards so the listpack can be walked from
+// its end. Strings that are canonical integers are stored as integers, as
+// Redis does.
+type listpack struct {
+	entries []byte
+	count   int
+}
+
+func (lp *listpack) appendString(s string) {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(v, 10) == s {
+		lp.appendInt(v)
+		return
+	}
+	var encoded []byte
+	switch l := len(s); {
+	case l < 64:
+		encoded = append([]byte{0x80 | byte(l)}, s...)
+	case l < 4096:
+		encoded = append([]byte{0xE0 | byte(l>>8), byte(l)}, s...)
+	default:
+		encoded = binary.LittleEndian.AppendUint32([]byte{0xF0}, uint32(l))
+		encoded = append(encoded, s...)
+	}
+	lp.appendEncoded(encoded)
+}
+
+func (lp *listpack) appendInt(v int64) {
+	var encoded []byte
+	switch {
+	case v >= 0 && v <= 127:
+		encoded = []byte{byte(v)}
+	case v >= -4096 && v <= 4095:
+		u := uint16(v) & 0x1FFF
+		encoded = []byte{0xC0 | byte(u>>8), byte(u)}
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		encoded = binary.LittleEndian.AppendUint16([]byte{0xF1}, uint16(v))
+	case v >= -1<<23 && v < 1<<23:
+		u := uint32(v)
+		encoded = []byte{0xF2, byte(u), byte(u >> 8), byte(u >> 16)}
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		encoded = binary.LittleEndian.AppendUint32([]byte{0xF3}, uint32(v))
+	default:
+		encoded = binary.LittleEndian.AppendUint64([]byte{0xF4}, uint64(v))
+	}
+	lp.appendEncoded(encoded)
+}
+
+func (lp *listpack) appendEncoded(encoded []byte) {
+	lp.entries = append(lp.entries, encoded...)
+	lp.entries = appendListpackBacklen(lp.entries, len(encoded))
+	lp.count++
+}
+
+// appendListpackBacklen appends l in 7-bit groups, most significant first,
+// with the high bit set on every group but the first so the length can be
+// read from its last byte backwards.
+func appendListpackBacklen(buf []byte, l int) []byte {
+	groups := listpackBacklenSize(l)
+	for i := groups - 1; i >= 0; i-- {
+		b := byte(l>>(7*i)) & 0x7F
+		if i != groups-1 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+	}
+	return buf
+}
+
+func (lp *listpack) bytes() []byte {
+	total := LISTPACK_HEADER_SIZE + len(lp.entries) + 1
+	count := min(lp.count, math.MaxUint16)
+	buf := make([]byte, 0, total)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(total))
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(count))
+	buf = append(buf, lp.entries...)
+	return append(buf, LISTPACK_EOF)
+}
+
+// decodeListpack returns the elements of a serialized listpack as strings,
+// integers formatted in decimal.
+func decodeListpack(b []byte) ([]string, error) {
+	if len(b) < LISTPACK_HEADER_SIZE+1 {
+		return nil, fmt.Errorf("listpack of %d bytes is too short", len(b))
+	}
+	if total := int(binary.LittleEndian.Uint32(b)); total != len(b) {
+		return nil, fmt.Errorf("listpack header says %d bytes, got %d", total, len(b))
+	}
+	elements := []string{}
+	for pos := LISTPACK_HEADER_SIZE; ; {
+		if pos >= len(b) {
+			return nil, errors.New("listpack is missing its terminator")
+		}
+		if b[pos] == LISTPACK_EOF {
+			break
+		}
+		element, size, err := decodeListpackElement(b[pos:])
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		pos += size + listpackBacklenSize(size)
+	}
+	if count := int(binary.LittleEndian.Uint16(b[4:])); count != math.MaxUint16 && count != len(elements) {
+		return nil, fmt.Errorf("listpack header says %d elements, got %d", count, len(elements))
+	}
+	return elements, nil
+}
+
+// decodeListpackElement decodes the element at the start of b and returns it
+// with the size of its encoding and data.
+func decodeListpackElement(b []byte) (string, int, error) {
+	var size int
+	var value string
+	switch enc := b[0]; {
+	case enc&0x80 == 0:
+		return strconv.Itoa(int(enc)), 1, nil
+	case enc&0xC0 == 0x80:
+		size = 1 + int(enc&0x3F)
+		if size <= len(b) {
+			value = string(b[1:size])
+		}
+	case enc&0xE0 == 0xC0:
+		if len(b) < 2 {
+			break
+		}
+		v := int(enc&0x1F)<<8 | int(b[1])
+		return strconv.Itoa(v << (64 - 13) >> (64 - 13)), 2, nil
+	case enc&0xF0 == 0xE0:
+		if len(b) < 2 {
+			break
+		}
+		size = 2 + (int(enc&0x0F)<<8 | int(b[1]))
+		if size <= len(b) {
+			value = string(b[2:size])
+		}
+	case enc == 0xF0:
+		if len(b) < 5 {
+			break
+		}
+		size = 5 + int(binary.LittleEndian.Uint32(b[1:]))
+		if size <= len(b) {
+			value = string(b[5:size])
+		}
+	case enc >= 0xF1 && enc <= 0xF4:
+		size = 1 + [...]int{2, 3, 4, 8}[enc-0xF1]
+		if size > len(b) {
+			break
+		}
+		var v int64
+		switch enc {
+		case 0xF1:
+			v = int64(int16(binary.LittleEndian.Uint16(b[1:])))
+		case 0xF2:
+			v = int64(int32(uint32(b[1])<<8|uint32(b[2])<<16|uint32(b[3])<<24) >> 8)
+		case 0xF3:
+			v = int64(int32(binary.LittleEndian.Uint32(b[1:])))
+		default:
+			v = int64(binary.LittleEndian.Uint64(b[1:]))
+		}
+		return strconv.FormatInt(v, 10), size, nil
+	default:
+		return "", 0, fmt.Errorf("invalid listpack encoding %#x", enc)
+	}
+	if size == 0 || size > len(b) {
+		return "", 0, errors.New("listpack element runs past its end")
+	}
+	return value, size, nil
+}
+
+// listpackBacklenSize is how many bytes appendListpackBacklen takes for l.
+func listpackBacklenSize(l int) int {
+	size := 1
+	for v := l >> 7; v > 0; v >>= 7 {
+		size++
+	}
+	return size
+}
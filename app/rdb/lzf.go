@@ -0,0 +1,190 @@
+package rdb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The parameters Redis builds its copy of liblzf with: a 2^16-slot hash
+// table in the VERY_FAST variant, with literal runs of up to 32 bytes and
+// back references of up to 264 bytes at most 8kb back.
+const (
+	LZF_HLOG    int = 16
+	LZF_HSIZE   int = 1 << LZF_HLOG
+	LZF_MAX_LIT int = 1 << 5
+	LZF_MAX_OFF int = 1 << 13
+	LZF_MAX_REF int = (1 << 8) + (1 << 3)
+	// LZF_MAX_RATIO is the most LZF data expands by, a 3-byte back
+	// reference being the longest.
+	LZF_MAX_RATIO int = LZF_MAX_REF / 3
+)
+
+func lzfIndex(h uint32) int {
+	return int(((h >> (3*8 - LZF_HLOG)) - h*5) & uint32(LZF_HSIZE-1))
+}
+
+// lzfCompress compresses in the way lzf_compress does, byte for byte, into
+// at most outLen bytes. It returns nil when the result doesn't fit.
+func lzfCompress(in []byte, outLen int) []byte {
+	inEnd := len(in)
+	if inEnd == 0 || outLen == 0 {
+		return nil
+	}
+	// Slot values are positions in in. A zero slot never matches since a
+	// reference has to lie after the start of the input.
+	htab := make([]int, LZF_HSIZE)
+	out := make([]byte, outLen)
+	ip, op, lit := 0, 1, 0 // op starts past the length of the first run
+
+	hval := uint32(in[0])<<8 | uint32(in[1%inEnd])
+	for ip < inEnd-2 {
+		hval = hval<<8 | uint32(in[ip+2])
+		slot := lzfIndex(hval)
+		ref := htab[slot]
+		htab[slot] = ip
+		off := ip - ref - 1
+		if off >= 0 && off < LZF_MAX_OFF && ref > 0 && in[ref+2] == in[ip+2] && in[ref] == in[ip] && in[ref+1] == in[ip+1] {
+			length := 2
+			maxlen := min(inEnd-ip-length, LZF_MAX_REF)
+			noLit := 0
+			if lit == 0 {
+				noLit = 1
+			}
+			if op+3+1 >= outLen && op-noLit+3+1 >= outLen {
+				return nil
+			}
+			out[op-lit-1] = byte(lit - 1) // stop run
+			op -= noLit                   // undo run if length is zero
+
+			length = lzfMatchLength(in, ref, ip, length, maxlen)
+
+			length -= 2
+			ip++
+			if length < 7 {
+				out[op] = byte(off>>8 + length<<5)
+				op++
+			} else {
+				out[op] = byte(off>>8 + 7<<5)
+				out[op+1] = byte(length - 7)
+				op += 2
+			}
+			out[op] = byte(off)
+			op++
+			lit = 0
+			op++ // start run
+			ip += length + 1
+			if ip >= inEnd-2 {
+				break
+			}
+			ip -= 2
+			hval = uint32(in[ip])<<8 | uint32(in[ip+1])
+			hval = hval<<8 | uint32(in[ip+2])
+			htab[lzfIndex(hval)] = ip
+			ip++
+			hval = hval<<8 | uint32(in[ip+2])
+			htab[lzfIndex(hval)] = ip
+			ip++
+			continue
+		}
+		if op >= outLen {
+			return nil
+		}
+		lit++
+		out[op] = in[ip]
+		op++
+		ip++
+		if lit == LZF_MAX_LIT {
+			out[op-lit-1] = byte(lit - 1)
+			lit = 0
+			op++
+		}
+	}
+	if op+3 > outLen {
+		return nil
+	}
+	for ip < inEnd {
+		lit++
+		out[op] = in[ip]
+		op++
+		ip++
+		if lit == LZF_MAX_LIT {
+			out[op-lit-1] = byte(lit - 1)
+			lit = 0
+			op++
+		}
+	}
+	out[op-lit-1] = byte(lit - 1) // end run
+	if lit == 0 {
+		op--
+	}
+	return out[:op]
+}
+
+// lzfMatchLength extends a match of length bytes at ref and ip the way
+// lzf_compress does, first in an unrolled run of 16 when more than 16 bytes
+// may follow. Like there, that run can take the match past maxlen but never
+// past the end of the input.
+func lzfMatchLength(in []byte, ref int, ip int, length int, maxlen int) int {
+	if maxlen > 16 {
+		for range 16 {
+			length++
+			if in[ref+length] != in[ip+length] {
+				return length
+			}
+		}
+	}
+	for {
+		length++
+		if length >= maxlen || in[ref+length] != in[ip+length] {
+			return length
+		}
+	}
+}
+
+// lzfDecompress decompresses in, which must expand to exactly outLen bytes.
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	out := make([]byte, 0, outLen)
+	for ip := 0; ip < len(in); {
+		ctrl := int(in[ip])
+		ip++
+		if ctrl < 1<<5 {
+			ctrl++
+			if ip+ctrl > len(in) {
+				return nil, errors.New("lzf literal run past the end of the input")
+			}
+			if len(out)+ctrl > outLen {
+				return nil, errors.New("lzf data expands past its length")
+			}
+			out = append(out, in[ip:ip+ctrl]...)
+			ip += ctrl
+			continue
+		}
+		length := ctrl >> 5
+		if length == 7 {
+			if ip >= len(in) {
+				return nil, errors.New("lzf back reference past the end of the input")
+			}
+			length += int(in[ip])
+			ip++
+		}
+		if ip >= len(in) {
+			return nil, errors.New("lzf back reference past the end of the input")
+		}
+		ref := len(out) - (ctrl&0x1f)<<8 - 1 - int(in[ip])
+		ip++
+		length += 2
+		if ref < 0 {
+			return nil, errors.New("lzf back reference before the start of the output")
+		}
+		if len(out)+length > outLen {
+			return nil, errors.New("lzf data expands past its length")
+		}
+		for i := range length {
+			out = append(out, out[ref+i])
+		}
+	}
+	if len(out) != outLen {
+		return nil, fmt.Errorf("lzf data expands to %d bytes, expected %d", len(out), outLen)
+	}
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestLzfCompress(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []byte
+	}{
+		// Two literals, a 20-byte back reference one byte back, two literals.
+		{strings.Repeat("a", 24), []byte{0x01, 'a', 'a', 0xE0, 0x0B, 0x00, 0x01, 'a', 'a'}},
+		// Nothing repeats, so the output can't be 4 bytes shorter.
+		{"abcdefghijklmnopqrstuvwxyz", nil},
+	}
+	for _, test := range tests {
+		got := lzfCompress([]byte(test.input), len(test.input)-4)
+		if !bytes.Equal(got, test.expected) {
+			t.Errorf("Expected %q to compress to % x; got % x", test.input, test.expected, got)
+		}
+	}
+}
+
+func TestLzfRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"redis", "stream", "key:", "0123456789", "value", " "}
+	inputs := []string{strings.Repeat("ab", 5000), strings.Repeat("x", 100000)}
+	for range 50 {
+		var sb strings.Builder
+		for range rng.Intn(3000) + 25 {
+			sb.WriteString(words[rng.Intn(len(words))])
+		}
+		inputs = append(inputs, sb.String())
+	}
+	for _, input := range inputs {
+		compressed := lzfCompress([]byte(input), len(input)-4)
+		if compressed == nil {
+			t.Errorf("Expected %d repetitive bytes to compress", len(input))
+			continue
+		}
+		got, err := lzfDecompress(compressed, len(input))
+		if err != nil || string(got) != input {
+			t.Errorf("Expected %d bytes back; got %d, %v", len(input), len(got), err)
+		}
+	}
+	if _, err := lzfDecompress([]byte{0x01, 'a', 'a', 0xE0, 0x0B, 0x05}, 22); err == nil {
+		t.Errorf("Expected a reference before the start of the output to fail")
+	}
+	if _, err := lzfDecompress([]byte{0x01, 'a', 'a'}, 3); err == nil {
+		t.Errorf("Expected a length mismatch to fail")
+	}
+}
+
+func TestWriteCompressedString(t *testing.T) {
+	long := strings.Repeat("compressible ", 20)
+	for _, compression := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := &rdbWriter{w: bufio.NewWriter(&buf), compress: compression}
+		w.writeString(long)
+		w.writeString("short but over twenty")
+		w.w.Flush()
+		if compressed := buf.Bytes()[0] == ENC_LZF; compressed != compression {
+			t.Errorf("Expected compressed=%v with compression=%v", compression, compressed)
+		}
+		reader := newRdbReader(&buf)
+		for _, expected := range []string{long, "short but over twenty"} {
+			if got, err := getStringFromStringEncoding(reader); err != nil || got != expected {
+				t.Errorf("Expected %q back; got %q, %v", expected, got, err)
+			}
+		}
+	}
+}
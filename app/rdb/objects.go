@@ -0,0 +1,407 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+)
+
+const (
+	QUICKLIST_NODE_CONTAINER_PLAIN  int    = 1
+	QUICKLIST_NODE_CONTAINER_PACKED int    = 2
+	MODULE_OPCODE_EOF               int    = 0
+	MODULE_OPCODE_SINT              int    = 1
+	MODULE_OPCODE_UINT              int    = 2
+	MODULE_OPCODE_FLOAT             int    = 3
+	MODULE_OPCODE_DOUBLE            int    = 4
+	MODULE_OPCODE_STRING            int    = 5
+	MODULE_TYPE_NAME_CHARSET        string = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+)
+
+// skippedObjectError is returned for a value that was read past but not
+// loaded. The key it belongs to is left out and loading goes on.
+type skippedObjectError struct {
+	reason string
+}
+
+func (e *skippedObjectError) Error() string {
+	return e.reason
+}
+
+// getObject reads a value of any type but string, in any of the encodings
+// Redis has written it in.
+func getObject(reader *rdbReader, objType byte) (entry.Entry, error) {
+	var e interface {
+		entry.Entry
+		Len() int
+	}
+	var err error
+	switch objType {
+	case TYPE_LIST, TYPE_LIST_ZIPLIST, TYPE_LIST_QUICKLIST, TYPE_LIST_QUICKLIST_2:
+		e, err = getList(reader, objType)
+	case TYPE_SET, TYPE_SET_INTSET, TYPE_SET_LISTPACK:
+		e, err = getSet(reader, objType)
+	case TYPE_ZSET, TYPE_ZSET_2, TYPE_ZSET_ZIPLIST, TYPE_ZSET_LISTPACK:
+		e, err = getSortedSet(reader, objType)
+	case TYPE_HASH, TYPE_HASH_ZIPMAP, TYPE_HASH_ZIPLIST, TYPE_HASH_LISTPACK,
+		TYPE_HASH_METADATA_PRE_GA, TYPE_HASH_METADATA, TYPE_HASH_LISTPACK_EX_PRE_GA, TYPE_HASH_LISTPACK_EX:
+		e, err = getHash(reader, objType)
+	case TYPE_STREAM_LISTPACKS, TYPE_STREAM_LISTPACKS_2, TYPE_STREAM_LISTPACKS_3:
+		// Unlike the other types, streams are kept when empty.
+		s, err := getStream(reader, objType)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case TYPE_MODULE_2:
+		return nil, skipModule(reader)
+	case TYPE_MODULE_PRE_GA:
+		return nil, fmt.Errorf("module values of RDB type %d can't be loaded without their module", objType)
+	default:
+		return nil, fmt.Errorf("unknown RDB type %d", objType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.Len() == 0 {
+		return nil, &skippedObjectError{reason: "empty " + e.Type()}
+	}
+	return e, nil
+}
+
+// getBlob reads a string holding a serialized ziplist, listpack, intset or
+// zipmap and decodes it with decode.
+func getBlob(reader *rdbReader, decode func([]byte) ([]string, error)) ([]string, error) {
+	blob, err := getStringFromStringEncoding(reader)
+	if err != nil {
+		return nil, err
+	}
+	return decode([]byte(blob))
+}
+
+// getStrings reads a length followed by that many strings, times per.
+func getStrings(reader *rdbReader, per int) ([]string, error) {
+	n, err := getBoundedLength(reader, per)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, 0, min(n*per, 1<<16))
+	for range n * per {
+		s, err := getStringFromStringEncoding(reader)
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+func getList(reader *rdbReader, objType byte) (*entry.List, error) {
+	switch objType {
+	case TYPE_LIST:
+		elements, err := getStrings(reader, 1)
+		return entry.NewList(elements), err
+	case TYPE_LIST_ZIPLIST:
+		elements, err := getBlob(reader, decodeZiplist)
+		return entry.NewList(elements), err
+	}
+	nodes, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return nil, err
+	}
+	elements := []string{}
+	for range nodes {
+		container := QUICKLIST_NODE_CONTAINER_PACKED
+		if objType == TYPE_LIST_QUICKLIST_2 {
+			if container, err = getLengthFromStringEncoding(reader); err != nil {
+				return nil, err
+			}
+		}
+		var node []string
+		switch {
+		case container == QUICKLIST_NODE_CONTAINER_PLAIN:
+			var element string
+			element, err = getStringFromStringEncoding(reader)
+			node = []string{element}
+		case container != QUICKLIST_NODE_CONTAINER_PACKED:
+			return nil, fmt.Errorf("invalid quicklist node container %d", container)
+		case objType == TYPE_LIST_QUICKLIST:
+			node, err = getBlob(reader, decodeZiplist)
+		default:
+			node, err = getBlob(reader, decodeListpack)
+		}
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, node...)
+	}
+	return entry.NewList(elements), nil
+}
+
+func getSet(reader *rdbReader, objType byte) (*entry.Set, error) {
+	var members []string
+	var err error
+	switch objType {
+	case TYPE_SET:
+		members, err = getStrings(reader, 1)
+	case TYPE_SET_INTSET:
+		members, err = getBlob(reader, decodeIntset)
+	default:
+		members, err = getBlob(reader, decodeListpack)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := entry.NewSet(entry.DEFAULT_SET_MAX_INTSET_ENTRIES)
+	for _, m := range members {
+		s.Add(m)
+	}
+	return s, nil
+}
+
+func getSortedSet(reader *rdbReader, objType byte) (*entry.SortedSet, error) {
+	z := entry.NewSortedSet(entry.DEFAULT_ZSET_MAX_LISTPACK_ENTRIES, entry.DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+	if objType == TYPE_ZSET_ZIPLIST || objType == TYPE_ZSET_LISTPACK {
+		decode := decodeListpack
+		if objType == TYPE_ZSET_ZIPLIST {
+			decode = decodeZiplist
+		}
+		elements, err := getBlob(reader, decode)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements)%2 != 0 {
+			return nil, fmt.Errorf("sorted set has a member without a score")
+		}
+		for i := 0; i < len(elements); i += 2 {
+			score, err := strconv.ParseFloat(elements[i+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sorted set score %q", elements[i+1])
+			}
+			z.Add(elements[i], score)
+		}
+		return z, nil
+	}
+	n, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return nil, err
+	}
+	for range n {
+		member, err := getStringFromStringEncoding(reader)
+		if err != nil {
+			return nil, err
+		}
+		var score float64
+		if objType == TYPE_ZSET_2 {
+			score, err = getBinaryDouble(reader)
+		} else {
+			score, err = getStringDouble(reader)
+		}
+		if err != nil {
+			return nil, err
+		}
+		z.Add(member, score)
+	}
+	return z, nil
+}
+
+func getBinaryDouble(reader *rdbReader) (float64, error) {
+	data, err := getNBytesFromReader(reader, 8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+}
+
+// getStringDouble reads a double written as a 1-byte length and its decimal
+// form, with the lengths 253, 254 and 255 standing for NaN, +inf and -inf.
+func getStringDouble(reader *rdbReader) (float64, error) {
+	l, err := reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch l {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	data, err := getNBytesFromReader(reader, int(l))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(data), 64)
+}
+
+// getHash reads a hash. Hashes with field expiry times come as fields with
+// their times, relative to the earliest one written before them, or as a
+// listpack of field, value and time triples, a time of 0 meaning none. Fields
+// that have already expired are left out.
+func getHash(reader *rdbReader, objType byte) (*entry.Hash, error) {
+	var minExpiry int64
+	if objType == TYPE_HASH_METADATA || objType == TYPE_HASH_LISTPACK_EX {
+		data, err := getNBytesFromReader(reader, 8)
+		if err != nil {
+			return nil, err
+		}
+		minExpiry = int64(binary.LittleEndian.Uint64(data))
+	}
+	h := entry.NewHash()
+	now := time.Now()
+	set := func(field string, value string, expiry int64) {
+		if expiry == 0 {
+			h.Set(field, value, time.Time{})
+		} else if expiryTime := time.UnixMilli(expiry); expiryTime.After(now) {
+			h.Set(field, value, expiryTime)
+		}
+	}
+	switch objType {
+	case TYPE_HASH_METADATA_PRE_GA, TYPE_HASH_METADATA:
+		n, err := getLengthFromStringEncoding(reader)
+		if err != nil {
+			return nil, err
+		}
+		for range n {
+			ttl, err := getLengthFromStringEncoding(reader)
+			if err != nil {
+				return nil, err
+			}
+			expiry := int64(ttl)
+			if objType == TYPE_HASH_METADATA && ttl != 0 {
+				expiry += minExpiry - 1
+			}
+			field, err := getStringFromStringEncoding(reader)
+			if err != nil {
+				return nil, err
+			}
+			value, err := getStringFromStringEncoding(reader)
+			if err != nil {
+				return nil, err
+			}
+			set(field, value, expiry)
+		}
+		return h, nil
+	case TYPE_HASH_LISTPACK_EX_PRE_GA, TYPE_HASH_LISTPACK_EX:
+		elements, err := getBlob(reader, decodeListpack)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements)%3 != 0 {
+			return nil, fmt.Errorf("hash listpack of %d elements isn't made of field, value and expiry triples", len(elements))
+		}
+		for i := 0; i < len(elements); i += 3 {
+			expiry, err := strconv.ParseInt(elements[i+2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hash field expiry %q", elements[i+2])
+			}
+			set(elements[i], elements[i+1], expiry)
+		}
+		return h, nil
+	}
+	var elements []string
+	var err error
+	switch objType {
+	case TYPE_HASH:
+		elements, err = getStrings(reader, 2)
+	case TYPE_HASH_ZIPMAP:
+		elements, err = getBlob(reader, decodeZipmap)
+	case TYPE_HASH_ZIPLIST:
+		elements, err = getBlob(reader, decodeZiplist)
+	default:
+		elements, err = getBlob(reader, decodeListpack)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(elements)%2 != 0 {
+		return nil, fmt.Errorf("hash has a field without a value")
+	}
+	for i := 0; i < len(elements); i += 2 {
+		set(elements[i], elements[i+1], 0)
+	}
+	return h, nil
+}
+
+// skipModule reads past a module value, which is its module type ID followed
+// by the module's data, and reports it as skipped.
+func skipModule(reader *rdbReader) error {
+	id, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return err
+	}
+	if err := skipModuleData(reader); err != nil {
+		return err
+	}
+	return &skippedObjectError{reason: fmt.Sprintf("module type %s is not supported", moduleTypeName(uint64(id)))}
+}
+
+// skipModuleAux reads past the auxiliary data a module saved: its module
+// type ID, when it was saved as an unsigned number, and the module's data.
+func skipModuleAux(reader *rdbReader) error {
+	id, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return err
+	}
+	whenOpcode, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return err
+	}
+	if whenOpcode != MODULE_OPCODE_UINT {
+		return fmt.Errorf("invalid when opcode %d in the auxiliary data of module type %s", whenOpcode, moduleTypeName(uint64(id)))
+	}
+	if _, err := getLengthFromStringEncoding(reader); err != nil {
+		return err
+	}
+	if err := skipModuleData(reader); err != nil {
+		return err
+	}
+	log.Printf("Skipping auxiliary data of module type %s: modules are not supported", moduleTypeName(uint64(id)))
+	return nil
+}
+
+// skipModuleData reads past the opcode-tagged numbers and strings a module
+// saved, up to the EOF opcode.
+func skipModuleData(reader *rdbReader) error {
+	for {
+		opcode, err := getLengthFromStringEncoding(reader)
+		if err != nil {
+			return err
+		}
+		switch opcode {
+		case MODULE_OPCODE_EOF:
+			return nil
+		case MODULE_OPCODE_SINT, MODULE_OPCODE_UINT:
+			_, err = getLengthFromStringEncoding(reader)
+		case MODULE_OPCODE_FLOAT:
+			_, err = getNBytesFromReader(reader, 4)
+		case MODULE_OPCODE_DOUBLE:
+			_, err = getNBytesFromReader(reader, 8)
+		case MODULE_OPCODE_STRING:
+			_, err = getStringFromStringEncoding(reader)
+		default:
+			return fmt.Errorf("unknown module opcode %d", opcode)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// moduleTypeName decodes the 9-character name in the top 54 bits of a module
+// type ID, the low 10 bits being its encoding version.
+func moduleTypeName(id uint64) string {
+	name := make([]byte, 9)
+	id >>= 10
+	for i := len(name) - 1; i >= 0; i-- {
+		name[i] = MODULE_TYPE_NAME_CHARSET[id&63]
+		id >>= 6
+	}
+	return string(name)
+}
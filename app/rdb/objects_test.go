@@ -0,0 +1,288 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+	"github.com/codecrafters-io/redis-starter-go/app/protocol"
+)
+
+// ziplistOf serializes the encoded elements as a ziplist.
+func ziplistOf(elements ...[]byte) []byte {
+	body := []byte{}
+	prevlen, tail := 0, ZIPLIST_HEADER_SIZE
+	for _, e := range elements {
+		tail = ZIPLIST_HEADER_SIZE + len(body)
+		start := len(body)
+		if prevlen < 254 {
+			body = append(body, byte(prevlen))
+		} else {
+			body = binary.LittleEndian.AppendUint32(append(body, 0xFE), uint32(prevlen))
+		}
+		body = append(body, e...)
+		prevlen = len(body) - start
+	}
+	b := binary.LittleEndian.AppendUint32(nil, uint32(ZIPLIST_HEADER_SIZE+len(body)+1))
+	b = binary.LittleEndian.AppendUint32(b, uint32(tail))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(elements)))
+	return append(append(b, body...), ZIPLIST_END)
+}
+
+// rdbString serializes s as a length-prefixed RDB string.
+func rdbString(s string) []byte {
+	var buf bytes.Buffer
+	w := &rdbWriter{w: bufio.NewWriter(&buf)}
+	w.writeLength(uint64(len(s)))
+	w.write([]byte(s))
+	w.w.Flush()
+	return buf.Bytes()
+}
+
+func readerOf(parts ...[]byte) *rdbReader {
+	return newRdbReader(bytes.NewReader(bytes.Join(parts, nil)))
+}
+
+func TestDecodeListpack(t *testing.T) {
+	values := []string{"0", "127", "128", "-1", "-4096", "4095", "-32768", "32767", "-8388608", "8388607",
+		"-2147483648", "2147483647", "9223372036854775807", "-9223372036854775808",
+		"", "abc", "007", strings.Repeat("x", 63), strings.Repeat("y", 64), strings.Repeat("z", 5000)}
+	lp := &listpack{}
+	for _, v := range values {
+		lp.appendString(v)
+	}
+	got, err := decodeListpack(lp.bytes())
+	if err != nil {
+		t.Fatalf("Error decoding: %s", err)
+	}
+	if !slices.Equal(got, values) {
+		t.Errorf("Expected %q; got %q", values, got)
+	}
+	if _, err := decodeListpack(lp.bytes()[:20]); err == nil {
+		t.Errorf("Expected a truncated listpack to fail")
+	}
+}
+
+func TestDecodeZiplist(t *testing.T) {
+	long := strings.Repeat("l", 300)
+	zl := ziplistOf(
+		[]byte("\x03foo"),
+		append([]byte{0x41, 0x2C}, long...),
+		[]byte{0xF1},
+		[]byte{0xFD},
+		[]byte{0xFE, 0xFE},
+		[]byte{0xC0, 0x2C, 0x01},
+		[]byte{0xF0, 0x70, 0x11, 0x01},
+		[]byte{0xD0, 0xFF, 0xFF, 0xFF, 0x7F},
+		binary.LittleEndian.AppendUint64([]byte{0xE0}, uint64(1)<<40),
+	)
+	expected := []string{"foo", long, "0", "12", "-2", "300", "70000", "2147483647", "1099511627776"}
+	got, err := decodeZiplist(zl)
+	if err != nil {
+		t.Fatalf("Error decoding: %s", err)
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected %q; got %q", expected, got)
+	}
+}
+
+func TestDecodeZipmap(t *testing.T) {
+	long := strings.Repeat("v", 300)
+	zm := []byte{2, 3, 'f', 'o', 'o', 3, 2, 'b', 'a', 'r', 0, 0, 1, 'k', 254}
+	zm = binary.LittleEndian.AppendUint32(zm, 300)
+	zm = append(append(append(zm, 0), long...), ZIPMAP_END)
+	got, err := decodeZipmap(zm)
+	if err != nil {
+		t.Fatalf("Error decoding: %s", err)
+	}
+	if expected := []string{"foo", "bar", "k", long}; !slices.Equal(got, expected) {
+		t.Errorf("Expected %q; got %q", expected, got)
+	}
+}
+
+func TestDecodeIntset(t *testing.T) {
+	blob := binary.LittleEndian.AppendUint32(nil, uint32(INTSET_ENC_INT32))
+	blob = binary.LittleEndian.AppendUint32(blob, 3)
+	for _, v := range []int32{-70000, 1, 70000} {
+		blob = binary.LittleEndian.AppendUint32(blob, uint32(v))
+	}
+	got, err := decodeIntset(blob)
+	if err != nil {
+		t.Fatalf("Error decoding: %s", err)
+	}
+	if expected := []string{"-70000", "1", "70000"}; !slices.Equal(got, expected) {
+		t.Errorf("Expected %q; got %q", expected, got)
+	}
+}
+
+func TestGetObject(t *testing.T) {
+	future := time.Now().Add(time.Hour).UnixMilli()
+	past := time.Now().Add(-time.Hour).UnixMilli()
+	lp := &listpack{}
+	for _, v := range []string{"a", "1", "b", "2.5"} {
+		lp.appendString(v)
+	}
+	hashLp := &listpack{}
+	hashLp.appendString("live")
+	hashLp.appendString("1")
+	hashLp.appendInt(0)
+	hashLp.appendString("ttl")
+	hashLp.appendString("2")
+	hashLp.appendInt(future)
+	hashLp.appendString("gone")
+	hashLp.appendString("3")
+	hashLp.appendInt(past)
+	minExpiry := binary.LittleEndian.AppendUint64(nil, uint64(past))
+
+	tests := []struct {
+		name     string
+		objType  byte
+		data     *rdbReader
+		expected string
+	}{
+		{"list", TYPE_LIST, readerOf([]byte{2}, rdbString("a"), rdbString("b")), "list [a b]"},
+		{"list ziplist", TYPE_LIST_ZIPLIST, readerOf(rdbString(string(ziplistOf([]byte("\x01a"), []byte{0xF3})))), "list [a 2]"},
+		{"quicklist", TYPE_LIST_QUICKLIST, readerOf([]byte{2},
+			rdbString(string(ziplistOf([]byte("\x01a")))), rdbString(string(ziplistOf([]byte("\x01b"))))), "list [a b]"},
+		{"quicklist 2", TYPE_LIST_QUICKLIST_2, readerOf([]byte{2, 1}, rdbString("plain"), []byte{2}, rdbString(string(lp.bytes()))),
+			"list [plain a 1 b 2.5]"},
+		{"set", TYPE_SET, readerOf([]byte{2}, rdbString("x"), rdbString("y")), "set [x y]"},
+		{"set listpack", TYPE_SET_LISTPACK, readerOf(rdbString(string(lp.bytes()))), "set [1 2.5 a b]"},
+		{"zset", TYPE_ZSET, readerOf([]byte{3}, rdbString("a"), []byte{3}, []byte("1.5"), rdbString("b"), []byte{254}, rdbString("c"), []byte{255}),
+			"zset [c:-inf a:1.5 b:inf]"},
+		{"zset 2", TYPE_ZSET_2, readerOf([]byte{1}, rdbString("a"), binary.LittleEndian.AppendUint64(nil, math.Float64bits(-2))), "zset [a:-2]"},
+		{"zset ziplist", TYPE_ZSET_ZIPLIST, readerOf(rdbString(string(ziplistOf([]byte("\x01a"), []byte("\x013"))))), "zset [a:3]"},
+		{"zset listpack", TYPE_ZSET_LISTPACK, readerOf(rdbString(string(lp.bytes()))), "zset [a:1 b:2.5]"},
+		{"hash", TYPE_HASH, readerOf([]byte{1}, rdbString("f"), rdbString("v")), "hash [f=v]"},
+		{"hash zipmap", TYPE_HASH_ZIPMAP, readerOf(rdbString("\x01\x01f\x01\x00v\xff")), "hash [f=v]"},
+		{"hash ziplist", TYPE_HASH_ZIPLIST, readerOf(rdbString(string(ziplistOf([]byte("\x01f"), []byte{0xF2})))), "hash [f=1]"},
+		{"hash listpack", TYPE_HASH_LISTPACK, readerOf(rdbString(string(lp.bytes()))), "hash [a=1 b=2.5]"},
+		{"hash metadata", TYPE_HASH_METADATA, readerOf(minExpiry, []byte{3},
+			[]byte{0}, rdbString("live"), rdbString("1"),
+			binary.BigEndian.AppendUint64([]byte{0x81}, uint64(future-past+1)), rdbString("ttl"), rdbString("2"),
+			[]byte{1}, rdbString("gone"), rdbString("3")), "hash [live=1 ttl=2~]"},
+		{"hash listpack ex", TYPE_HASH_LISTPACK_EX, readerOf(minExpiry, rdbString(string(hashLp.bytes()))), "hash [live=1 ttl=2~]"},
+		{"hash listpack ex pre-GA", TYPE_HASH_LISTPACK_EX_PRE_GA, readerOf(rdbString(string(hashLp.bytes()))), "hash [live=1 ttl=2~]"},
+	}
+	for _, test := range tests {
+		e, err := getObject(test.data, test.objType)
+		if err != nil {
+			t.Errorf("%s: error loading: %s", test.name, err)
+			continue
+		}
+		if got := describeEntry(e); got != test.expected {
+			t.Errorf("%s: expected %s; got %s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestGetObjectSkipsModules(t *testing.T) {
+	// A module value of type "mymodtype" version 3 with one of each opcode.
+	id := uint64(0)
+	for _, c := range "mymodtype" {
+		id = id<<6 | uint64(strings.IndexRune(MODULE_TYPE_NAME_CHARSET, c))
+	}
+	id = id<<10 | 3
+	data := readerOf(binary.BigEndian.AppendUint64([]byte{0x81}, id),
+		[]byte{byte(MODULE_OPCODE_SINT), 5, byte(MODULE_OPCODE_UINT), 7},
+		[]byte{byte(MODULE_OPCODE_FLOAT), 0, 0, 0, 0},
+		[]byte{byte(MODULE_OPCODE_DOUBLE), 0, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{byte(MODULE_OPCODE_STRING)}, rdbString("payload"),
+		[]byte{byte(MODULE_OPCODE_EOF), 0x42})
+	_, err := getObject(data, TYPE_MODULE_2)
+	skipped, ok := err.(*skippedObjectError)
+	if !ok || !strings.Contains(skipped.Error(), "mymodtype") {
+		t.Fatalf("Expected the mymodtype value to be skipped; got %v", err)
+	}
+	if next, _ := data.ReadByte(); next != 0x42 {
+		t.Errorf("Expected to stop right after the module value; next byte is %#x", next)
+	}
+	if _, err := getObject(readerOf([]byte{0}), TYPE_MODULE_PRE_GA); err == nil {
+		t.Errorf("Expected a pre-GA module value to fail")
+	}
+}
+
+func TestWriteCollections(t *testing.T) {
+	set := entry.NewSet(entry.DEFAULT_SET_MAX_INTSET_ENTRIES)
+	set.Add("a")
+	set.Add("1")
+	intset := entry.NewSet(entry.DEFAULT_SET_MAX_INTSET_ENTRIES)
+	intset.Add("-40000")
+	intset.Add("7")
+	zset := entry.NewSortedSet(entry.DEFAULT_ZSET_MAX_LISTPACK_ENTRIES, entry.DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+	zset.Add("a", 1.5)
+	zset.Add("b", math.Inf(-1))
+	bigZset := entry.NewSortedSet(2, entry.DEFAULT_ZSET_MAX_LISTPACK_VALUE)
+	for i, m := range []string{"x", "y", "z"} {
+		bigZset.Add(m, float64(i))
+	}
+	elements := []string{}
+	for i := range 3000 {
+		elements = append(elements, strings.Repeat("e", i%10))
+	}
+	hash := entry.NewHash()
+	hash.Set("f", "v", time.Time{})
+	hash.Set("n", "12", time.Now().Add(time.Hour))
+	hash.Set("old", "x", time.Now().Add(-time.Hour))
+	database := map[int]map[string]entry.Entry{0: {
+		"set": set, "intset": intset, "zset": zset, "bigzset": bigZset,
+		"list": entry.NewList(elements), "hash": hash,
+	}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, database, Options{Compression: true}); err != nil {
+		t.Fatalf("Error writing: %s", err)
+	}
+	got, err := newRdbFromReader(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("Error reading back: %s", err)
+	}
+	expected := map[string]string{
+		"set":     "set [1 a]",
+		"intset":  "set [-40000 7]",
+		"zset":    "zset [b:-inf a:1.5]",
+		"bigzset": "zset [x:0 y:1 z:2]",
+		"hash":    "hash [f=v n=12]",
+	}
+	for key, description := range expected {
+		if e := got.Database[0][key]; e == nil || describeEntry(e) != description {
+			t.Errorf("Expected %s to be %s; got %v", key, description, e)
+		}
+	}
+	if l, ok := got.Database[0]["list"].(*entry.List); !ok || !slices.Equal(l.Elements(), elements) {
+		t.Errorf("Expected the list of %d elements back; got %v", len(elements), got.Database[0]["list"])
+	}
+}
+
+// describeEntry renders a collection for comparison, sets sorted and hash
+// fields with an expiry time marked with ~.
+func describeEntry(e entry.Entry) string {
+	items := []string{}
+	switch v := e.(type) {
+	case *entry.List:
+		items = v.Elements()
+	case *entry.Set:
+		items = v.Members()
+		slices.Sort(items)
+	case *entry.SortedSet:
+		for _, m := range v.Slice(0, v.Len()) {
+			items = append(items, m.Member+":"+protocol.FormatDouble(m.Score))
+		}
+	case *entry.Hash:
+		for _, field := range v.Fields() {
+			value, _ := v.Get(field)
+			item := field + "=" + value
+			if !v.ExpiryTime(field).IsZero() {
+				item += "~"
+			}
+			items = append(items, item)
+		}
+	}
+	return e.Type() + " [" + strings.Join(items, " ") + "]"
+}
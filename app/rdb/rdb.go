@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -14,10 +17,11 @@ import (
 )
 
 type Rdb struct {
-	header   RdbHeader
-	metadata map[string]string
-	Database map[int]map[string]entry.Entry
-	checksum string
+	header           RdbHeader
+	metadata         map[string]string
+	Database         map[int]map[string]entry.Entry
+	checksum         string
+	expectedChecksum uint64
 }
 
 type RdbHeader struct {
@@ -29,6 +33,13 @@ const (
 	MAGIC_WORD                   string = "REDIS"
 	MAGIC_LENGTH                 int    = 5
 	VERSION_LENGTH               int    = 4
+	MAX_RDB_VERSION              int    = 12
+	SLOT_INFO_OPCODE             byte   = 0xF4
+	MODULE_AUX_OPCODE            byte   = 0xF5
+	FUNCTION_PRE_GA_OPCODE       byte   = 0xF6
+	FUNCTION2_OPCODE             byte   = 0xF7
+	IDLE_OPCODE                  byte   = 0xF8
+	FREQ_OPCODE                  byte   = 0xF9
 	METADATA_OPCODE              byte   = 0xFA
 	HASH_TABLE_OPCODE            byte   = 0xFB
 	EXPIRY_MILLISECONDS          byte   = 0xFC
@@ -38,156 +49,203 @@ const (
 	DATABASE_OPCODE              byte   = 0xFE
 	CHECKSUM_OPCODE              byte   = 0xFF
 	CHECKSUM_LENGTH              int    = 8
+	ENC_INT8                     byte   = 0xC0
+	ENC_INT16                    byte   = 0xC1
+	ENC_INT32                    byte   = 0xC2
+	ENC_LZF                      byte   = 0xC3
 )
 
-func NewRdbFromFile(dir string, filename string) (*Rdb, error) {
-	file, err := os.Open(filepath.Join(dir, filename))
-	if err != nil {
-		return nil, err
-	}
-	reader := bufio.NewReader(file)
-	return newRdbFromReader(reader)
-
-}
+const (
+	TYPE_STRING                  byte = 0
+	TYPE_LIST                    byte = 1
+	TYPE_SET                     byte = 2
+	TYPE_ZSET                    byte = 3
+	TYPE_HASH                    byte = 4
+	TYPE_ZSET_2                  byte = 5
+	TYPE_MODULE_PRE_GA           byte = 6
+	TYPE_MODULE_2                byte = 7
+	TYPE_HASH_ZIPMAP             byte = 9
+	TYPE_LIST_ZIPLIST            byte = 10
+	TYPE_SET_INTSET              byte = 11
+	TYPE_ZSET_ZIPLIST            byte = 12
+	TYPE_HASH_ZIPLIST            byte = 13
+	TYPE_LIST_QUICKLIST          byte = 14
+	TYPE_STREAM_LISTPACKS        byte = 15
+	TYPE_HASH_LISTPACK           byte = 16
+	TYPE_ZSET_LISTPACK           byte = 17
+	TYPE_LIST_QUICKLIST_2        byte = 18
+	TYPE_STREAM_LISTPACKS_2      byte = 19
+	TYPE_SET_LISTPACK            byte = 20
+	TYPE_STREAM_LISTPACKS_3      byte = 21
+	TYPE_HASH_METADATA_PRE_GA    byte = 22
+	TYPE_HASH_LISTPACK_EX_PRE_GA byte = 23
+	TYPE_HASH_METADATA           byte = 24
+	TYPE_HASH_LISTPACK_EX        byte = 25
+)
 
-func newRdbFromReader(reader *bufio.Reader) (*Rdb, error) {
-	header, err := getHeader(reader)
+// NewRdbFromFile loads the RDB file opts point to, checking its checksum when
+// opts ask for it.
+func NewRdbFromFile(opts Options) (*Rdb, error) {
+	file, err := os.Open(filepath.Join(opts.Dir, opts.Filename))
 	if err != nil {
 		return nil, err
 	}
-	metadata, err := getMetadata(reader)
+	defer file.Close()
+	info, err := file.Stat()
 	if err != nil {
 		return nil, err
 	}
-	database, err := getDatabase(reader)
-	if err != nil {
-		return nil, err
-	}
-	checksum, err := getChecksum(reader)
-	if err != nil {
-		return nil, err
-	}
-	return &Rdb{
-		header:   *header,
-		metadata: metadata,
-		Database: database,
-		checksum: checksum,
-	}, nil
+	return NewRdbFromReader(bufio.NewReader(file), info.Size(), opts.Checksum)
 }
 
-func getHeader(reader *bufio.Reader) (*RdbHeader, error) {
-	magic := ""
-	for range MAGIC_LENGTH {
-		b, err := reader.ReadByte()
-		if err != nil {
-			return nil, err
-		}
-		magic += string(b)
-	}
-	if magic != MAGIC_WORD {
-		return nil, fmt.Errorf("expected %s, got %s", MAGIC_WORD, magic)
+// NewRdbFromReader loads an RDB file of size bytes from reader, checking its
+// checksum if checksum is set. A size of -1 means it isn't known, and
+// lengths read can't be checked against what is left of the file. It reads
+// no further than the end of the file, so it can load the RDB preamble of an
+// append-only file too.
+func NewRdbFromReader(reader *bufio.Reader, size int64, checksum bool) (*Rdb, error) {
+	rdbReader := newRdbReader(reader)
+	rdbReader.size = int(size)
+	r, err := readRdb(rdbReader)
+	if err != nil {
+		return nil, err
 	}
-	version := ""
-	for range VERSION_LENGTH {
-		b, err := reader.ReadByte()
-		if err != nil {
+	if checksum {
+		if err := r.verifyChecksum(); err != nil {
 			return nil, err
 		}
-		version += string(b)
 	}
-	return &RdbHeader{magic: magic, version: version}, nil
+	return r, nil
 }
 
-func getMetadata(reader *bufio.Reader) (map[string]string, error) {
-	metadata := make(map[string]string)
-	for {
-		next, err := reader.Peek(1)
-		if err != nil {
-			return nil, err
-		}
-		if next[0] != METADATA_OPCODE {
-			break
-		}
-		_, err = reader.ReadByte()
-		if err != nil {
-			return nil, err
-		}
-		name, err := getStringFromStringEncoding(reader)
-		if err != nil {
-			return nil, err
-		}
-		value, err := getStringFromStringEncoding(reader)
-		if err != nil {
-			return nil, err
-		}
-		metadata[name] = value
-	}
-	return metadata, nil
+func newRdbFromReader(reader *bufio.Reader) (*Rdb, error) {
+	return readRdb(newRdbReader(reader))
+}
+
+// readRdb reads an RDB file, reporting errors with the offset they were
+// found at.
+func readRdb(reader *rdbReader) (*Rdb, error) {
+	r := &Rdb{metadata: map[string]string{}, Database: map[int]map[string]entry.Entry{}}
+	if err := r.read(reader); err != nil {
+		return nil, fmt.Errorf("bad RDB file at byte %d: %w", reader.offset, err)
+	}
+	return r, nil
 }
 
-func getDatabase(reader *bufio.Reader) (map[int]map[string]entry.Entry, error) {
-	database := make(map[int]map[string]entry.Entry)
+func (r *Rdb) read(reader *rdbReader) error {
+	header, err := getHeader(reader)
+	if err != nil {
+		return err
+	}
+	r.header = *header
+	dbIdx := 0
 	for {
-		bs, err := reader.Peek(1)
+		next, err := reader.Peek(1)
 		if err != nil {
-			return nil, err
-		}
-		if bs[0] != DATABASE_OPCODE {
-			break
+			return err
 		}
-		idx, databaseSection, err := getDatabaseSection(reader)
-		if err != nil {
-			return nil, err
+		switch next[0] {
+		case METADATA_OPCODE:
+			reader.ReadByte()
+			name, err := getStringFromStringEncoding(reader)
+			if err != nil {
+				return err
+			}
+			value, err := getStringFromStringEncoding(reader)
+			if err != nil {
+				return err
+			}
+			r.metadata[name] = value
+		case DATABASE_OPCODE:
+			reader.ReadByte()
+			if dbIdx, err = getLengthFromStringEncoding(reader); err != nil {
+				return err
+			}
+			if r.Database[dbIdx] == nil {
+				r.Database[dbIdx] = map[string]entry.Entry{}
+			}
+		case HASH_TABLE_OPCODE:
+			reader.ReadByte()
+			if err := skipLengths(reader, 2); err != nil {
+				return err
+			}
+		case SLOT_INFO_OPCODE:
+			reader.ReadByte()
+			if err := skipLengths(reader, 3); err != nil {
+				return err
+			}
+		case MODULE_AUX_OPCODE:
+			reader.ReadByte()
+			if err := skipModuleAux(reader); err != nil {
+				return err
+			}
+		case FUNCTION2_OPCODE:
+			reader.ReadByte()
+			if _, err := getStringFromStringEncoding(reader); err != nil {
+				return err
+			}
+			log.Printf("Skipping a function library in the RDB file: functions are not supported")
+		case FUNCTION_PRE_GA_OPCODE:
+			return errors.New("pre-release function format is not supported")
+		case CHECKSUM_OPCODE:
+			reader.ReadByte()
+			return r.readChecksum(reader)
+		default:
+			if err := r.readEntry(reader, dbIdx); err != nil {
+				return err
+			}
 		}
-		database[idx] = databaseSection
 	}
-	return database, nil
 }
 
-func getDatabaseSection(reader *bufio.Reader) (int, map[string]entry.Entry, error) {
-	b, err := reader.ReadByte()
+// readEntry reads a key and its value into database dbIdx, leaving it out if
+// it has already expired or is a value that can't be loaded but can be
+// skipped.
+func (r *Rdb) readEntry(reader *rdbReader, dbIdx int) error {
+	key, e, err := getEntry(reader)
+	var skipped *skippedObjectError
+	if errors.As(err, &skipped) {
+		log.Printf("Skipping key %q: %s", key, err)
+		return nil
+	}
 	if err != nil {
-		return -1, nil, err
+		return fmt.Errorf("loading key %q: %w", key, err)
 	}
-	if b != DATABASE_OPCODE {
-		return -1, nil, fmt.Errorf("expected %b, got %b", DATABASE_OPCODE, b)
+	if entry.IsExpired(e, time.Now()) {
+		return nil
 	}
-	dbIdx, err := getLengthFromStringEncoding(reader)
-	if err != nil {
-		return -1, nil, err
+	if r.Database[dbIdx] == nil {
+		r.Database[dbIdx] = map[string]entry.Entry{}
 	}
-	b, err = reader.ReadByte()
+	r.Database[dbIdx][key] = e
+	return nil
+}
+
+func getHeader(reader *rdbReader) (*RdbHeader, error) {
+	data, err := getNBytesFromReader(reader, MAGIC_LENGTH+VERSION_LENGTH)
 	if err != nil {
-		return -1, nil, err
+		return nil, err
 	}
-	if b != HASH_TABLE_OPCODE {
-		return -1, nil, fmt.Errorf("expected %q, got %q,", HASH_TABLE_OPCODE, b)
+	magic, version := string(data[:MAGIC_LENGTH]), string(data[MAGIC_LENGTH:])
+	if magic != MAGIC_WORD {
+		return nil, fmt.Errorf("expected %s, got %s", MAGIC_WORD, magic)
 	}
-	for range 2 {
-		_, err := getLengthFromStringEncoding(reader)
-		if err != nil {
-			return -1, nil, err
-		}
+	if v, err := strconv.Atoi(version); err != nil || v < 1 || v > MAX_RDB_VERSION {
+		return nil, fmt.Errorf("can't handle RDB format version %s, the newest supported is %d", version, MAX_RDB_VERSION)
 	}
-	databaseSection := make(map[string]entry.Entry)
-	for {
-		next, err := reader.Peek(1)
-		if err != nil {
-			return -1, nil, err
-		}
-		if next[0] == DATABASE_OPCODE || next[0] == CHECKSUM_OPCODE {
-			break
-		}
-		key, entry, err := getEntry(reader)
-		if err != nil {
-			return -1, nil, err
+	return &RdbHeader{magic: magic, version: version}, nil
+}
+
+func skipLengths(reader *rdbReader, n int) error {
+	for range n {
+		if _, err := getLengthFromStringEncoding(reader); err != nil {
+			return err
 		}
-		databaseSection[key] = entry
 	}
-	return dbIdx, databaseSection, nil
+	return nil
 }
 
-func getEntry(reader *bufio.Reader) (string, entry.Entry, error) {
+func getEntry(reader *rdbReader) (string, entry.Entry, error) {
 	var expiryTime time.Time
 	next, err := reader.Peek(1)
 	if err != nil {
@@ -199,7 +257,10 @@ func getEntry(reader *bufio.Reader) (string, entry.Entry, error) {
 			return "", nil, err
 		}
 	}
-	_, err = reader.ReadByte() // Assume just string entry for now
+	if err := skipEvictionInfo(reader); err != nil {
+		return "", nil, err
+	}
+	objType, err := reader.ReadByte()
 	if err != nil {
 		return "", nil, err
 	}
@@ -207,14 +268,24 @@ func getEntry(reader *bufio.Reader) (string, entry.Entry, error) {
 	if err != nil {
 		return "", nil, err
 	}
-	val, err := getStringFromStringEncoding(reader)
+	if objType == TYPE_STRING {
+		val, err := getStringFromStringEncoding(reader)
+		if err != nil {
+			return key, nil, err
+		}
+		return key, entry.NewRedisString(val, expiryTime), nil
+	}
+	val, err := getObject(reader, objType)
 	if err != nil {
-		return "", nil, err
+		return key, nil, err
 	}
-	return key, entry.NewRedisString(val, expiryTime), nil
+	if x, ok := val.(entry.Expiring); ok && !expiryTime.IsZero() {
+		x.SetExpiryTime(expiryTime)
+	}
+	return key, val, nil
 }
 
-func getExpiryTime(reader *bufio.Reader) (time.Time, error) {
+func getExpiryTime(reader *rdbReader) (time.Time, error) {
 	b, err := reader.ReadByte()
 	if err != nil {
 		return time.Time{}, err
@@ -238,7 +309,7 @@ func getExpiryTime(reader *bufio.Reader) (time.Time, error) {
 	}
 }
 
-func getStringFromStringEncoding(reader *bufio.Reader) (string, error) {
+func getStringFromStringEncoding(reader *rdbReader) (string, error) {
 	next, err := reader.Peek(1)
 	if err != nil {
 		return "", err
@@ -246,43 +317,41 @@ func getStringFromStringEncoding(reader *bufio.Reader) (string, error) {
 	firstTwoBits := (next[0] & 0b11000000) >> 6
 	switch firstTwoBits {
 	case 0b00, 0b01, 0b10:
-		len, err := getLengthFromStringEncoding(reader)
+		len, err := getBoundedLength(reader, 1)
 		if err != nil {
 			return "", err
 		}
-		str := ""
-		for range len {
-			b, err := reader.ReadByte()
-			if err != nil {
-				return "", err
-			}
-			str += string(b)
+		data, err := getNBytesFromReader(reader, len)
+		if err != nil {
+			return "", err
 		}
-		return str, nil
+		return string(data), nil
 	case 0b11:
 		b, err := reader.ReadByte()
 		if err != nil {
 			return "", err
 		}
 		switch b {
-		case 0xC0:
+		case ENC_INT8:
 			b, err := reader.ReadByte()
 			if err != nil {
 				return "", err
 			}
-			return strconv.Itoa(int(b)), nil
-		case 0xC1:
+			return strconv.Itoa(int(int8(b))), nil
+		case ENC_INT16:
 			b, err := getNBytesFromReader(reader, 2)
 			if err != nil {
 				return "", err
 			}
-			return strconv.Itoa(int(binary.LittleEndian.Uint16(b))), nil
-		case 0xC2:
+			return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(b)))), nil
+		case ENC_INT32:
 			b, err := getNBytesFromReader(reader, 4)
 			if err != nil {
 				return "", err
 			}
-			return strconv.Itoa(int(binary.LittleEndian.Uint32(b))), nil
+			return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(b)))), nil
+		case ENC_LZF:
+			return getLzfString(reader)
 		default:
 			return "", fmt.Errorf("invalid format for bytes %b", b)
 		}
@@ -290,26 +359,79 @@ func getStringFromStringEncoding(reader *bufio.Reader) (string, error) {
 	return "", fmt.Errorf("invalid byte value %b", next)
 }
 
-func getChecksum(reader *bufio.Reader) (string, error) {
-	b, err := reader.ReadByte()
+// getLzfString reads an LZF-compressed string: its compressed and its
+// original length, then the compressed data.
+func getLzfString(reader *rdbReader) (string, error) {
+	compressedLen, err := getBoundedLength(reader, 1)
 	if err != nil {
 		return "", err
 	}
-	if b != CHECKSUM_OPCODE {
-		return "", fmt.Errorf("expected %q, got %q", CHECKSUM_OPCODE, b)
+	start := reader.offset
+	originalLen, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return "", err
 	}
-	checksum := ""
-	for range CHECKSUM_LENGTH {
-		b, err := reader.ReadByte()
-		if err != nil {
-			return "", err
-		}
-		checksum += string(b)
+	if originalLen < 0 || originalLen/LZF_MAX_RATIO > compressedLen {
+		return "", fmt.Errorf("invalid length %d at byte %d for %d bytes of LZF data", originalLen, start, compressedLen)
 	}
-	return checksum, nil
+	compressed, err := getNBytesFromReader(reader, compressedLen)
+	if err != nil {
+		return "", err
+	}
+	data, err := lzfDecompress(compressed, originalLen)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// skipEvictionInfo reads past the LRU idle time or LFU frequency a key may
+// have been saved with.
+func skipEvictionInfo(reader *rdbReader) error {
+	next, err := reader.Peek(1)
+	if err != nil {
+		return err
+	}
+	switch next[0] {
+	case IDLE_OPCODE:
+		reader.ReadByte()
+		_, err = getLengthFromStringEncoding(reader)
+	case FREQ_OPCODE:
+		reader.ReadByte()
+		_, err = reader.ReadByte()
+	}
+	return err
 }
 
-func getLengthFromStringEncoding(reader *bufio.Reader) (int, error) {
+// readChecksum reads the CRC64 at the end of the file, which files from
+// before version 5 don't have, and keeps the one of everything before it.
+func (r *Rdb) readChecksum(reader *rdbReader) error {
+	r.expectedChecksum = reader.crc
+	if version, _ := strconv.Atoi(r.header.version); version < 5 {
+		return nil
+	}
+	checksum, err := getNBytesFromReader(reader, CHECKSUM_LENGTH)
+	if err != nil {
+		return err
+	}
+	r.checksum = string(checksum)
+	return nil
+}
+
+// verifyChecksum checks the checksum at the end of the file, unless there is
+// none or it was saved with checksums off, making it zero.
+func (r *Rdb) verifyChecksum() error {
+	if len(r.checksum) != CHECKSUM_LENGTH {
+		return nil
+	}
+	checksum := binary.LittleEndian.Uint64([]byte(r.checksum))
+	if checksum != 0 && checksum != r.expectedChecksum {
+		return fmt.Errorf("wrong RDB checksum, expected %#016x, got %#016x", r.expectedChecksum, checksum)
+	}
+	return nil
+}
+
+func getLengthFromStringEncoding(reader *rdbReader) (int, error) {
 	b, err := reader.ReadByte()
 	if err != nil {
 		return -1, err
@@ -323,8 +445,7 @@ func getLengthFromStringEncoding(reader *bufio.Reader) (int, error) {
 		if err != nil {
 			return -1, err
 		}
-		v := (b&0b00111111)<<8 | nextb
-		return int(v), nil
+		return int(b&0b00111111)<<8 | int(nextb), nil
 	case 0b10:
 		var bytesToRead int
 		switch b {
@@ -347,14 +468,29 @@ func getLengthFromStringEncoding(reader *bufio.Reader) (int, error) {
 	return -1, fmt.Errorf("error extracting first two bits. ")
 }
 
-func getNBytesFromReader(r *bufio.Reader, n int) ([]byte, error) {
-	buffer := make([]byte, n)
-	nRead, err := r.Read(buffer)
+// getBoundedLength reads the length of something taking at least minSize
+// bytes per unit, refusing one that is negative or that couldn't fit in what
+// is left of the file.
+func getBoundedLength(reader *rdbReader, minSize int) (int, error) {
+	start := reader.offset
+	n, err := getLengthFromStringEncoding(reader)
 	if err != nil {
-		return nil, err
+		return -1, err
+	}
+	if n < 0 || n > reader.remaining()/minSize {
+		return -1, fmt.Errorf("invalid length %d at byte %d with %d bytes left", n, start, reader.remaining())
+	}
+	return n, nil
+}
+
+func getNBytesFromReader(r *rdbReader, n int) ([]byte, error) {
+	if n < 0 || n > r.remaining() {
+		return nil, fmt.Errorf("can't read %d bytes at byte %d with %d bytes left", n, r.offset, r.remaining())
 	}
-	if nRead != n {
-		return nil, fmt.Errorf("expected %d bytes read, got %d", nRead, n)
+	buffer := make([]byte, n)
+	nRead, err := io.ReadFull(r, buffer)
+	if err != nil {
+		return nil, fmt.Errorf("expected %d bytes read, got %d: %w", n, nRead, err)
 	}
 	return buffer, nil
 }
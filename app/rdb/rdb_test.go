@@ -3,7 +3,11 @@ package rdb
 import (
 	"bufio"
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,8 +24,8 @@ func TestFileParser(t *testing.T) {
 	expectedDatabase := make(map[int]map[string]entry.Entry)
 	expectedDatabase[0] = make(map[string]entry.Entry)
 	expectedDatabase[0]["foobar"] = entry.NewRedisString("bazqux", time.Time{})
-	expectedDatabase[0]["foo"] = entry.NewRedisString("bar", time.UnixMilli(1713824559637))
-	expectedDatabase[0]["abcde"] = entry.NewRedisString("wxyz", time.Unix(1714089298, 0))
+	expectedDatabase[0]["foo"] = entry.NewRedisString("bar", time.UnixMilli(4102444800000))
+	expectedDatabase[0]["abcde"] = entry.NewRedisString("wxyz", time.Unix(4102444800, 0))
 	expected := Rdb{
 		header:   RdbHeader{magic: "REDIS", version: "0011"},
 		metadata: map[string]string{"redis-ver": "6.0.16"},
@@ -83,7 +87,7 @@ func getDummyFileReader() *bufio.Reader {
 	bs = append(bs, []byte("bazqux")...)
 
 	bs = append(bs, 0xFC)
-	bs = append(bs, []byte{0x15, 0x72, 0xE7, 0x07, 0x8F, 0x01, 0x00, 0x00}...)
+	bs = append(bs, []byte{0x00, 0xD8, 0xC3, 0x2C, 0xBB, 0x03, 0x00, 0x00}...)
 	bs = append(bs, 0x00)
 	bs = append(bs, 0x03)
 	bs = append(bs, []byte("foo")...)
@@ -91,7 +95,7 @@ func getDummyFileReader() *bufio.Reader {
 	bs = append(bs, []byte("bar")...)
 
 	bs = append(bs, 0xFD)
-	bs = append(bs, []byte{0x52, 0xED, 0x2A, 0x66}...)
+	bs = append(bs, []byte{0x00, 0x57, 0x86, 0xF4}...)
 	bs = append(bs, 0x00)
 	bs = append(bs, 0x05)
 	bs = append(bs, []byte("abcde")...)
@@ -154,3 +158,195 @@ func entrySame(a entry.Entry, b entry.Entry) bool {
 	}
 	return A.Value() == B.Value() && A.ExpiryTime().Equal(B.ExpiryTime())
 }
+
+// writeTestRdb writes an RDB file of the given version using every opcode
+// up to version 12, ending with the checksum of its contents.
+func writeTestRdb(t *testing.T, dir string, version string, corrupt bool) {
+	var buf bytes.Buffer
+	w := &rdbWriter{w: bufio.NewWriter(&buf)}
+	w.write([]byte(MAGIC_WORD + version))
+	w.writeAux("redis-ver", "7.4.0")
+	w.writeByte(MODULE_AUX_OPCODE)
+	w.writeLength(12345 << 10)
+	w.writeLength(uint64(MODULE_OPCODE_UINT))
+	w.writeLength(2)
+	w.writeLength(uint64(MODULE_OPCODE_STRING))
+	w.writeString("aux")
+	w.writeLength(uint64(MODULE_OPCODE_EOF))
+	w.writeByte(FUNCTION2_OPCODE)
+	w.writeString("#!lua name=lib\nredis.register_function('f', function() return 1 end)")
+	w.writeByte(DATABASE_OPCODE)
+	w.writeLength(0)
+	w.writeByte(HASH_TABLE_OPCODE)
+	w.writeLength(2)
+	w.writeLength(0)
+	w.writeByte(SLOT_INFO_OPCODE)
+	w.writeLength(866)
+	w.writeLength(2)
+	w.writeLength(0)
+	w.writeByte(IDLE_OPCODE)
+	w.writeLength(1000)
+	w.writeByte(TYPE_STRING)
+	w.writeString("idle")
+	w.writeString("1")
+	w.writeAux("lua", "midway")
+	w.writeByte(EXPIRY_MILLISECONDS)
+	w.write(binary.LittleEndian.AppendUint64(nil, uint64(time.Now().Add(time.Hour).UnixMilli())))
+	w.writeByte(FREQ_OPCODE)
+	w.writeByte(5)
+	w.writeByte(TYPE_STRING)
+	w.writeString("freq")
+	w.writeString("2")
+	w.writeByte(DATABASE_OPCODE)
+	w.writeLength(2)
+	w.writeByte(TYPE_STRING)
+	w.writeString("other")
+	w.writeString("3")
+	w.writeByte(CHECKSUM_OPCODE)
+	checksum := w.crc
+	if corrupt {
+		checksum++
+	}
+	w.write(binary.LittleEndian.AppendUint64(nil, checksum))
+	w.w.Flush()
+	if err := os.WriteFile(filepath.Join(dir, "dump.rdb"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadOpcodes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestRdb(t, dir, "0012", false)
+	got, err := NewRdbFromFile(Options{Dir: dir, Filename: "dump.rdb", Checksum: true})
+	if err != nil {
+		t.Fatalf("Error reading: %s", err)
+	}
+	if got.metadata["redis-ver"] != "7.4.0" || got.metadata["lua"] != "midway" {
+		t.Errorf("Expected the aux fields from before and between the keys; got %v", got.metadata)
+	}
+	expected := map[int]map[string]string{0: {"idle": "1", "freq": "2"}, 2: {"other": "3"}}
+	if len(got.Database) != len(expected) {
+		t.Errorf("Expected databases 0 and 2; got %v", got.Database)
+	}
+	for idx, keys := range expected {
+		for key, value := range keys {
+			if s, ok := got.Database[idx][key].(*entry.RedisString); !ok || s.Value() != value {
+				t.Errorf("Expected %s=%s in database %d; got %v", key, value, idx, got.Database[idx])
+			}
+		}
+	}
+	if s, _ := got.Database[0]["freq"].(*entry.RedisString); s == nil || s.ExpiryTime().IsZero() {
+		t.Errorf("Expected freq to keep its expiry time")
+	}
+
+	// The empty file sent to replicas was written by Redis itself.
+	payload, _ := EmptyRdbFile()
+	empty, err := newRdbFromReader(bufio.NewReader(bytes.NewReader(payload[bytes.IndexByte(payload, '\n')+1:])))
+	if err != nil {
+		t.Fatalf("Error reading the empty file: %s", err)
+	}
+	if err := empty.verifyChecksum(); err != nil {
+		t.Errorf("Expected the checksum Redis wrote to match: %s", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{Dir: dir, Filename: "dump.rdb", Checksum: true}
+
+	writeTestRdb(t, dir, "0012", true)
+	if _, err := NewRdbFromFile(opts); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Expected a wrong checksum to fail; got %v", err)
+	}
+	opts.Checksum = false
+	if _, err := NewRdbFromFile(opts); err != nil {
+		t.Errorf("Expected a wrong checksum to pass with rdbchecksum off; got %s", err)
+	}
+
+	writeTestRdb(t, dir, "0013", false)
+	if _, err := NewRdbFromFile(opts); err == nil || !strings.Contains(err.Error(), "version 0013") {
+		t.Errorf("Expected version 13 to be refused; got %v", err)
+	}
+
+	data := []byte("REDIS0011\xfe\x00\x00\x03foo\x03ba")
+	_, err := newRdbFromReader(bufio.NewReader(bytes.NewReader(data)))
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("at byte %d", len(data))) || !strings.Contains(err.Error(), `"foo"`) {
+		t.Errorf("Expected a truncated value to fail at byte %d of key foo; got %v", len(data), err)
+	}
+	data = []byte("REDIS0011\xfe\x00\xf6")
+	if _, err := newRdbFromReader(bufio.NewReader(bytes.NewReader(data))); err == nil || !strings.Contains(err.Error(), "at byte 11") {
+		t.Errorf("Expected the pre-release function opcode to fail at byte 11; got %v", err)
+	}
+}
+
+func TestCorruptLengths(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{Dir: dir, Filename: "dump.rdb"}
+	key := "REDIS0011\xfe\x00\x00\x03foo"
+	tests := []struct {
+		name string
+		data string
+		at   int
+	}{
+		{"negative 64-bit length", key + "\x81\xff\xff\xff\xff\xff\xff\xff\xf0", len(key)},
+		{"length past the end", key + "\x80\x00\x00\x01\x00bar", len(key)},
+		{"LZF data past the end", key + "\xc3\x20\x03bar", len(key) + 1},
+		{"LZF length past what the data expands to", key + "\xc3\x03\x81\x00\x00\x01\x00\x00\x00\x00\x00\x02bar", len(key) + 2},
+		{"set members past the end", "REDIS0011\xfe\x00\x02\x03foo\x80\x10\x00\x00\x00\x01a", len(key)},
+	}
+	for _, test := range tests {
+		if err := os.WriteFile(filepath.Join(dir, opts.Filename), []byte(test.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewRdbFromFile(opts)
+		if err == nil || !strings.Contains(err.Error(), "invalid length") || !strings.Contains(err.Error(), fmt.Sprintf("at byte %d", test.at)) {
+			t.Errorf("%s: expected an invalid length at byte %d; got %v", test.name, test.at, err)
+		}
+	}
+}
+
+func TestReadExpiringCollections(t *testing.T) {
+	future := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+	var buf bytes.Buffer
+	w := &rdbWriter{w: bufio.NewWriter(&buf)}
+	w.write([]byte(MAGIC_WORD + "0012"))
+	w.writeByte(DATABASE_OPCODE)
+	w.writeLength(0)
+	for key, expiry := range map[string]time.Time{"expiring": future, "expired": time.Now().Add(-time.Hour), "kept": {}} {
+		if !expiry.IsZero() {
+			w.writeByte(EXPIRY_MILLISECONDS)
+			w.write(binary.LittleEndian.AppendUint64(nil, uint64(expiry.UnixMilli())))
+		}
+		w.writeByte(TYPE_SET)
+		w.writeString(key)
+		w.writeLength(1)
+		w.writeString("a")
+	}
+	w.writeByte(CHECKSUM_OPCODE)
+	w.write(binary.LittleEndian.AppendUint64(nil, 0))
+	w.w.Flush()
+
+	got, err := newRdbFromReader(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("Error reading: %s", err)
+	}
+	if _, ok := got.Database[0]["expired"]; ok || len(got.Database[0]) != 2 {
+		t.Errorf("Expected only the keys that haven't expired to be loaded; got %v", got.Database[0])
+	}
+	if s, ok := got.Database[0]["kept"].(*entry.Set); !ok || !s.ExpiryTime().IsZero() {
+		t.Errorf("Expected kept to be a set without an expiry time; got %v", got.Database[0]["kept"])
+	}
+
+	// The expiry time is written back along with the set.
+	buf.Reset()
+	if err := Write(&buf, got.Database, Options{}); err != nil {
+		t.Fatalf("Error writing: %s", err)
+	}
+	got, err = newRdbFromReader(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("Error reading back: %s", err)
+	}
+	if s, ok := got.Database[0]["expiring"].(*entry.Set); !ok || !s.ExpiryTime().Equal(future) {
+		t.Errorf("Expected expiring to be a set expiring at %s; got %v", future, got.Database[0]["expiring"])
+	}
+}
@@ -0,0 +1,55 @@
+package rdb
+
+import (
+	"bufio"
+	"io"
+	"math"
+)
+
+// rdbReader reads an RDB file, keeping track of how many bytes were read and
+// of their CRC64, so that errors can say where they happened and the
+// checksum at the end can be checked.
+type rdbReader struct {
+	r      *bufio.Reader
+	offset int
+	size   int // of the file, or -1 if it isn't known
+	crc    uint64
+}
+
+func newRdbReader(r io.Reader) *rdbReader {
+	if br, ok := r.(*bufio.Reader); ok {
+		return &rdbReader{r: br, size: -1}
+	}
+	return &rdbReader{r: bufio.NewReader(r), size: -1}
+}
+
+// remaining returns how many bytes of the file are left to read, or
+// math.MaxInt if its size isn't known.
+func (r *rdbReader) remaining() int {
+	if r.size < 0 {
+		return math.MaxInt
+	}
+	return max(r.size-r.offset, 0)
+}
+
+func (r *rdbReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.offset += n
+	r.crc = crc64(r.crc, p[:n])
+	return n, err
+}
+
+func (r *rdbReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	r.offset++
+	r.crc = crc64(r.crc, []byte{b})
+	return b, nil
+}
+
+// Peek returns the next n bytes without reading them.
+func (r *rdbReader) Peek(n int) ([]byte, error) {
+	return r.r.Peek(n)
+}
@@ -0,0 +1,188 @@
+package rdb
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+)
+
+// BGSAVE_RETRY_DELAY is how long a failed background save holds off the
+// next one a save point asks for.
+const BGSAVE_RETRY_DELAY time.Duration = 5 * time.Second
+
+var (
+	ErrBgsaveInProgress  = errors.New("ERR Background save already in progress")
+	ErrInvalidSavePoints = errors.New("Invalid save parameters")
+)
+
+// SavePoint asks for a background save once Changes writes were made and
+// Seconds have passed since the last save.
+type SavePoint struct {
+	Seconds int
+	Changes int
+}
+
+// ParseSavePoints parses the save config, a list of "seconds changes" pairs.
+// An empty list turns automatic saves off.
+func ParseSavePoints(value string) ([]SavePoint, error) {
+	args := strings.Fields(value)
+	if len(args)%2 != 0 {
+		return nil, ErrInvalidSavePoints
+	}
+	points := []SavePoint{}
+	for i := 0; i < len(args); i += 2 {
+		seconds, err := strconv.Atoi(args[i])
+		if err != nil || seconds < 0 {
+			return nil, ErrInvalidSavePoints
+		}
+		changes, err := strconv.Atoi(args[i+1])
+		if err != nil || changes < 0 {
+			return nil, ErrInvalidSavePoints
+		}
+		points = append(points, SavePoint{Seconds: seconds, Changes: changes})
+	}
+	return points, nil
+}
+
+// Saver takes the snapshots of a server, in the foreground with Save or in
+// the background with BackgroundSave, and keeps track of how they went. Only
+// one background save runs at a time. It also counts the writes made since
+// the last save, which decide when a save point is reached.
+type Saver struct {
+	mu                 sync.Mutex
+	dirty              int
+	dirtyAtBgsave      int
+	lastSave           time.Time
+	lastBgsaveTry      time.Time
+	lastBgsaveErr      error
+	bgsaveInProgress   bool
+	bgsaveStart        time.Time
+	lastBgsaveDuration time.Duration
+	saves              int
+}
+
+// SaverStatus is what INFO persistence reports about the snapshots.
+type SaverStatus struct {
+	ChangesSinceLastSave int
+	LastSave             time.Time
+	LastBgsaveOK         bool
+	BgsaveInProgress     bool
+	LastBgsaveDuration   time.Duration // -1 before the first background save
+	CurrentBgsave        time.Duration // -1 when none is running
+	Saves                int
+}
+
+// NewSaver returns a Saver that considers the dataset saved as of now, the
+// way a server that just loaded its RDB file does.
+func NewSaver() *Saver {
+	return &Saver{lastSave: time.Now(), lastBgsaveDuration: -1}
+}
+
+// Save writes database as opts say, refusing to while a background save is
+// running.
+func (s *Saver) Save(opts Options, database map[int]map[string]entry.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bgsaveInProgress {
+		return ErrBgsaveInProgress
+	}
+	if err := SaveToFile(opts, database); err != nil {
+		return err
+	}
+	s.dirty = 0
+	s.lastSave = time.Now()
+	s.lastBgsaveErr = nil
+	s.saves++
+	return nil
+}
+
+// BackgroundSave snapshots database and writes the snapshot as opts say on
+// another goroutine, so only the snapshot is taken on the caller's, stalling
+// it the way Snapshot says. done, if not nil, is called with the outcome once
+// the file is written.
+func (s *Saver) BackgroundSave(opts Options, database map[int]map[string]entry.Entry, done func(error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bgsaveInProgress {
+		return ErrBgsaveInProgress
+	}
+	snapshot, release := Snapshot(database)
+	s.bgsaveInProgress = true
+	s.bgsaveStart = time.Now()
+	s.lastBgsaveTry = s.bgsaveStart
+	s.dirtyAtBgsave = s.dirty
+	go func() {
+		err := SaveToFile(opts, snapshot)
+		release()
+		s.mu.Lock()
+		s.bgsaveInProgress = false
+		s.lastBgsaveErr = err
+		s.lastBgsaveDuration = time.Since(s.bgsaveStart)
+		if err == nil {
+			s.dirty -= s.dirtyAtBgsave
+			s.lastSave = s.bgsaveStart
+			s.saves++
+		}
+		s.mu.Unlock()
+		if done != nil {
+			done(err)
+		}
+	}()
+	return nil
+}
+
+// LastSave returns when the last successful save started.
+func (s *Saver) LastSave() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSave
+}
+
+// AddDirty counts n writes made to the dataset.
+func (s *Saver) AddDirty(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirty += n
+}
+
+// DueSavePoint returns the first of points that is reached, unless a
+// background save is running or the last one failed less than
+// BGSAVE_RETRY_DELAY ago.
+func (s *Saver) DueSavePoint(points []SavePoint) (SavePoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bgsaveInProgress {
+		return SavePoint{}, false
+	}
+	if s.lastBgsaveErr != nil && time.Since(s.lastBgsaveTry) <= BGSAVE_RETRY_DELAY {
+		return SavePoint{}, false
+	}
+	for _, point := range points {
+		if s.dirty >= point.Changes && time.Since(s.lastSave) > time.Duration(point.Seconds)*time.Second {
+			return point, true
+		}
+	}
+	return SavePoint{}, false
+}
+
+func (s *Saver) Status() SaverStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := SaverStatus{
+		ChangesSinceLastSave: s.dirty,
+		LastSave:             s.lastSave,
+		LastBgsaveOK:         s.lastBgsaveErr == nil,
+		BgsaveInProgress:     s.bgsaveInProgress,
+		LastBgsaveDuration:   s.lastBgsaveDuration,
+		CurrentBgsave:        -1,
+		Saves:                s.saves,
+	}
+	if s.bgsaveInProgress {
+		status.CurrentBgsave = time.Since(s.bgsaveStart)
+	}
+	return status
+}
@@ -0,0 +1,85 @@
+package rdb
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+)
+
+func TestParseSavePoints(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []SavePoint
+		valid    bool
+	}{
+		{"", []SavePoint{}, true},
+		{"3600 1 300 100", []SavePoint{{3600, 1}, {300, 100}}, true},
+		{"  60   10000 ", []SavePoint{{60, 10000}}, true},
+		{"3600", nil, false},
+		{"3600 1 300", nil, false},
+		{"-1 1", nil, false},
+		{"60 many", nil, false},
+	}
+	for _, test := range tests {
+		points, err := ParseSavePoints(test.value)
+		if (err == nil) != test.valid {
+			t.Errorf("Expected %q valid=%v; got error %v", test.value, test.valid, err)
+			continue
+		}
+		if test.valid && !slices.Equal(points, test.expected) {
+			t.Errorf("Expected %q to parse to %v; got %v", test.value, test.expected, points)
+		}
+	}
+}
+
+func TestDueSavePoint(t *testing.T) {
+	s := NewSaver()
+	s.lastSave = time.Now().Add(-10 * time.Second)
+	points := []SavePoint{{3600, 1}, {5, 3}}
+
+	s.AddDirty(2)
+	if point, ok := s.DueSavePoint(points); ok {
+		t.Errorf("Expected no save point with 2 changes; got %v", point)
+	}
+	s.AddDirty(1)
+	if point, ok := s.DueSavePoint(points); !ok || point != points[1] {
+		t.Errorf("Expected %v with 3 changes; got %v, %v", points[1], point, ok)
+	}
+
+	dir := t.TempDir()
+	done := make(chan error)
+	database := map[int]map[string]entry.Entry{0: {"foo": entry.NewRedisString("bar", time.Time{})}}
+	if err := s.BackgroundSave(Options{Dir: dir, Filename: "dump.rdb"}, database, func(err error) { done <- err }); err != nil {
+		t.Fatalf("Error starting background save: %s", err)
+	}
+	s.AddDirty(1)
+	if err := <-done; err != nil {
+		t.Fatalf("Error saving: %s", err)
+	}
+	if status := s.Status(); status.ChangesSinceLastSave != 1 {
+		t.Errorf("Expected the change made while saving to be left; got %d", status.ChangesSinceLastSave)
+	}
+	if point, ok := s.DueSavePoint(points); ok {
+		t.Errorf("Expected no save point right after saving; got %v", point)
+	}
+
+	s.lastSave = time.Now().Add(-10 * time.Second)
+	s.AddDirty(2)
+	if err := s.BackgroundSave(Options{Dir: filepath.Join(dir, "missing"), Filename: "dump.rdb"}, database, func(err error) { done <- err }); err != nil {
+		t.Fatalf("Error starting background save: %s", err)
+	}
+	if err := <-done; !os.IsNotExist(err) {
+		t.Fatalf("Expected saving to a missing directory to fail; got %v", err)
+	}
+	if point, ok := s.DueSavePoint(points); ok {
+		t.Errorf("Expected no save point right after a failed save; got %v", point)
+	}
+	s.lastBgsaveTry = time.Now().Add(-BGSAVE_RETRY_DELAY - time.Second)
+	if _, ok := s.DueSavePoint(points); !ok {
+		t.Errorf("Expected the save point to be retried after %s", BGSAVE_RETRY_DELAY)
+	}
+}
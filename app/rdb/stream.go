@@ -0,0 +1,440 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+)
+
+const (
+	STREAM_ITEM_FLAG_DELETED    int = 1 << 0
+	STREAM_ITEM_FLAG_SAMEFIELDS int = 1 << 1
+	STREAM_ID_SIZE              int = 16
+)
+
+// writeStream writes a stream the way Redis 7.2 does. Each node is its
+// master ID as a 16-byte big-endian key and a listpack holding first the
+// master entry
+//
+//	count deleted field-count field... 0
+//
+// and then every entry, deleted ones included, as
+//
+//	flags ms-diff seq-diff [field-count field value...] | [value...] lp-count
+//
+// where an entry with the field names of the master entry only stores its
+// values. The nodes are followed by the length, the last, first and
+// max-deleted IDs and the entries-added counter, and then by the consumer
+// groups with their pending entries and consumers.
+func writeStream(w *rdbWriter, key string, s *entry.Stream) {
+	type node struct {
+		master *entry.StreamID
+		lp     *listpack
+	}
+	nodes := []node{}
+	s.Nodes(func(master *entry.StreamID, masterFields []string, items []entry.StreamNodeItem) bool {
+		nodes = append(nodes, node{master, streamListpack(master, masterFields, items)})
+		return true
+	})
+	w.writeByte(TYPE_STREAM_LISTPACKS_3)
+	w.writeString(key)
+	w.writeLength(uint64(len(nodes)))
+	for _, n := range nodes {
+		w.writeString(string(streamIDBytes(n.master)))
+		w.writeString(string(n.lp.bytes()))
+	}
+	firstID := s.FirstID()
+	if firstID == nil {
+		firstID = entry.NewStreamID(0, 0)
+	}
+	w.writeLength(uint64(s.Len()))
+	writeStreamID(w, s.LastID())
+	writeStreamID(w, firstID)
+	writeStreamID(w, s.MaxDeletedID())
+	w.writeLength(uint64(s.EntriesAdded()))
+
+	groups := s.Groups()
+	w.writeLength(uint64(len(groups)))
+	for _, g := range groups {
+		w.writeString(g.Name)
+		writeStreamID(w, g.LastID())
+		// An unknown entries-read counter is -1, which Redis writes as
+		// the largest 64-bit length.
+		w.writeLength(uint64(g.EntriesRead()))
+		pending := g.Pending(nil)
+		w.writeLength(uint64(len(pending)))
+		for _, p := range pending {
+			w.write(streamIDBytes(p.ID))
+			w.write(binary.LittleEndian.AppendUint64(nil, uint64(p.DeliveryTime)))
+			w.writeLength(uint64(p.DeliveryCount))
+		}
+		consumers := g.Consumers()
+		w.writeLength(uint64(len(consumers)))
+		for _, c := range consumers {
+			w.writeString(c.Name)
+			w.write(binary.LittleEndian.AppendUint64(nil, uint64(c.SeenTime)))
+			w.write(binary.LittleEndian.AppendUint64(nil, uint64(c.ActiveTime)))
+			pending := g.Pending(c)
+			w.writeLength(uint64(len(pending)))
+			for _, p := range pending {
+				w.write(streamIDBytes(p.ID))
+			}
+		}
+	}
+}
+
+func streamListpack(master *entry.StreamID, masterFields []string, items []entry.StreamNodeItem) *listpack {
+	deleted := 0
+	for _, item := range items {
+		if item.Deleted {
+			deleted++
+		}
+	}
+	lp := &listpack{}
+	lp.appendInt(int64(len(items) - deleted))
+	lp.appendInt(int64(deleted))
+	lp.appendInt(int64(len(masterFields)))
+	for _, field := range masterFields {
+		lp.appendString(field)
+	}
+	lp.appendInt(0)
+	for _, item := range items {
+		same := slices.EqualFunc(masterFields, item.Fields, func(name string, kv *entry.KeyValue) bool {
+			return name == kv.Key
+		})
+		flags := 0
+		if item.Deleted {
+			flags |= STREAM_ITEM_FLAG_DELETED
+		}
+		if same {
+			flags |= STREAM_ITEM_FLAG_SAMEFIELDS
+		}
+		lp.appendInt(int64(flags))
+		lp.appendInt(int64(item.ID.MillisecondsTime() - master.MillisecondsTime()))
+		lp.appendInt(int64(item.ID.SequenceNumber() - master.SequenceNumber()))
+		if same {
+			for _, kv := range item.Fields {
+				lp.appendString(kv.Value)
+			}
+			lp.appendInt(int64(len(item.Fields) + 3))
+			continue
+		}
+		lp.appendInt(int64(len(item.Fields)))
+		for _, kv := range item.Fields {
+			lp.appendString(kv.Key)
+			lp.appendString(kv.Value)
+		}
+		lp.appendInt(int64(2*len(item.Fields) + 4))
+	}
+	return lp
+}
+
+// streamIDBytes encodes id the way stream node keys and pending entries are
+// written, as big-endian milliseconds and sequence number.
+func streamIDBytes(id *entry.StreamID) []byte {
+	b := binary.BigEndian.AppendUint64(nil, uint64(id.MillisecondsTime()))
+	return binary.BigEndian.AppendUint64(b, uint64(id.SequenceNumber()))
+}
+
+func writeStreamID(w *rdbWriter, id *entry.StreamID) {
+	w.writeLength(uint64(id.MillisecondsTime()))
+	w.writeLength(uint64(id.SequenceNumber()))
+}
+
+// getStream reads a stream written as any of the three stream types. Before
+// TYPE_STREAM_LISTPACKS_2 there was no first ID, max-deleted ID or
+// entries-added counter, so every entry is taken to be still there, and no
+// entries-read counters or consumer active times, which are estimated from
+// the last ID of the group and taken from the seen time as Redis does.
+func getStream(reader *rdbReader, objType byte) (*entry.Stream, error) {
+	s := entry.NewStream(entry.DEFAULT_STREAM_NODE_MAX_BYTES, entry.DEFAULT_STREAM_NODE_MAX_ENTRIES)
+	nodes, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return nil, err
+	}
+	for range nodes {
+		key, err := getStringFromStringEncoding(reader)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != STREAM_ID_SIZE {
+			return nil, fmt.Errorf("stream node key of %d bytes, expected %d", len(key), STREAM_ID_SIZE)
+		}
+		master := streamIDFromBytes([]byte(key))
+		elements, err := getBlob(reader, decodeListpack)
+		if err != nil {
+			return nil, err
+		}
+		masterFields, items, err := decodeStreamListpack(master, elements)
+		if err != nil {
+			return nil, fmt.Errorf("stream node %s: %w", master, err)
+		}
+		if err := s.LoadNode(master, masterFields, items); err != nil {
+			return nil, err
+		}
+	}
+	length, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return nil, err
+	}
+	lastID, err := getStreamID(reader)
+	if err != nil {
+		return nil, err
+	}
+	entriesAdded := length
+	var maxDeletedID *entry.StreamID
+	if objType != TYPE_STREAM_LISTPACKS {
+		// The first ID is the one of the first live entry, which LoadNode
+		// has already found.
+		if _, err := getStreamID(reader); err != nil {
+			return nil, err
+		}
+		if maxDeletedID, err = getStreamID(reader); err != nil {
+			return nil, err
+		}
+		if entriesAdded, err = getLengthFromStringEncoding(reader); err != nil {
+			return nil, err
+		}
+	}
+	if length != s.Len() {
+		return nil, fmt.Errorf("stream length is %d but it has %d entries", length, s.Len())
+	}
+	if err := s.SetID(lastID, entriesAdded, maxDeletedID); err != nil {
+		return nil, err
+	}
+
+	groups, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return nil, err
+	}
+	for range groups {
+		if err := getStreamGroup(reader, objType, s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func getStreamGroup(reader *rdbReader, objType byte, s *entry.Stream) error {
+	name, err := getStringFromStringEncoding(reader)
+	if err != nil {
+		return err
+	}
+	lastID, err := getStreamID(reader)
+	if err != nil {
+		return err
+	}
+	var entriesRead int
+	if objType == TYPE_STREAM_LISTPACKS {
+		entriesRead = s.EstimateEntriesRead(lastID)
+	} else if entriesRead, err = getLengthFromStringEncoding(reader); err != nil {
+		return err
+	}
+	if !s.CreateGroup(name, lastID, entriesRead) {
+		return fmt.Errorf("duplicate stream group %q", name)
+	}
+	g := s.Group(name)
+
+	pending, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return err
+	}
+	for range pending {
+		id, err := getRawStreamID(reader)
+		if err != nil {
+			return err
+		}
+		deliveryTime, err := getMillisecondTime(reader)
+		if err != nil {
+			return err
+		}
+		deliveryCount, err := getLengthFromStringEncoding(reader)
+		if err != nil {
+			return err
+		}
+		if !g.RestorePending(id, deliveryTime, deliveryCount) {
+			return fmt.Errorf("duplicate pending entry %s in stream group %q", id, name)
+		}
+	}
+
+	consumers, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return err
+	}
+	for range consumers {
+		consumer, err := getStringFromStringEncoding(reader)
+		if err != nil {
+			return err
+		}
+		seenTime, err := getMillisecondTime(reader)
+		if err != nil {
+			return err
+		}
+		activeTime := seenTime
+		if objType == TYPE_STREAM_LISTPACKS_3 {
+			if activeTime, err = getMillisecondTime(reader); err != nil {
+				return err
+			}
+		}
+		n, err := getBoundedLength(reader, STREAM_ID_SIZE)
+		if err != nil {
+			return err
+		}
+		ids := make([]*entry.StreamID, n)
+		for i := range ids {
+			if ids[i], err = getRawStreamID(reader); err != nil {
+				return err
+			}
+		}
+		if err := g.RestoreConsumer(consumer, seenTime, activeTime, ids); err != nil {
+			return fmt.Errorf("stream group %q: %w", name, err)
+		}
+	}
+	for _, p := range g.Pending(nil) {
+		if p.Consumer == nil {
+			return fmt.Errorf("entry %s pending in stream group %q has no consumer", p.ID, name)
+		}
+	}
+	return nil
+}
+
+// decodeStreamListpack decodes the elements of a stream node listpack into
+// the field names of its master entry and its entries.
+func decodeStreamListpack(master *entry.StreamID, elements []string) ([]string, []entry.StreamNodeItem, error) {
+	pos := 0
+	next := func() (string, error) {
+		if pos >= len(elements) {
+			return "", fmt.Errorf("stream listpack ends after %d elements", len(elements))
+		}
+		pos++
+		return elements[pos-1], nil
+	}
+	nextInt := func() (int, error) {
+		element, err := next()
+		if err != nil {
+			return 0, err
+		}
+		v, err := strconv.Atoi(element)
+		if err != nil {
+			return 0, fmt.Errorf("expected an integer in stream listpack, got %q", element)
+		}
+		return v, nil
+	}
+	nextStrings := func(n int) ([]string, error) {
+		if n < 0 || n > len(elements)-pos {
+			return nil, fmt.Errorf("stream listpack ends before %d more elements", n)
+		}
+		pos += n
+		return elements[pos-n : pos], nil
+	}
+
+	count, err := nextInt()
+	if err != nil {
+		return nil, nil, err
+	}
+	deleted, err := nextInt()
+	if err != nil {
+		return nil, nil, err
+	}
+	numFields, err := nextInt()
+	if err != nil {
+		return nil, nil, err
+	}
+	masterFields, err := nextStrings(numFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	if terminator, err := nextInt(); err != nil || terminator != 0 {
+		return nil, nil, fmt.Errorf("stream master entry isn't terminated by 0")
+	}
+
+	items := make([]entry.StreamNodeItem, 0, count+deleted)
+	live := 0
+	for pos < len(elements) {
+		flags, err := nextInt()
+		if err != nil {
+			return nil, nil, err
+		}
+		msDiff, err := nextInt()
+		if err != nil {
+			return nil, nil, err
+		}
+		seqDiff, err := nextInt()
+		if err != nil {
+			return nil, nil, err
+		}
+		item := entry.StreamNodeItem{
+			ID:      entry.NewStreamID(master.MillisecondsTime()+msDiff, master.SequenceNumber()+seqDiff),
+			Deleted: flags&STREAM_ITEM_FLAG_DELETED != 0,
+		}
+		if flags&STREAM_ITEM_FLAG_SAMEFIELDS != 0 {
+			values, err := nextStrings(len(masterFields))
+			if err != nil {
+				return nil, nil, err
+			}
+			for i, value := range values {
+				item.Fields = append(item.Fields, &entry.KeyValue{Key: masterFields[i], Value: value})
+			}
+		} else {
+			n, err := nextInt()
+			if err != nil {
+				return nil, nil, err
+			}
+			pairs, err := nextStrings(2 * n)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i := 0; i < len(pairs); i += 2 {
+				item.Fields = append(item.Fields, &entry.KeyValue{Key: pairs[i], Value: pairs[i+1]})
+			}
+		}
+		if _, err := nextInt(); err != nil {
+			return nil, nil, err
+		}
+		if !item.Deleted {
+			live++
+		}
+		items = append(items, item)
+	}
+	if live != count || len(items)-live != deleted {
+		return nil, nil, fmt.Errorf("stream master entry counts %d live and %d deleted entries, got %d and %d", count, deleted, live, len(items)-live)
+	}
+	return slices.Clone(masterFields), items, nil
+}
+
+func streamIDFromBytes(b []byte) *entry.StreamID {
+	return entry.NewStreamID(int(binary.BigEndian.Uint64(b)), int(binary.BigEndian.Uint64(b[8:])))
+}
+
+func getStreamID(reader *rdbReader) (*entry.StreamID, error) {
+	ms, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := getLengthFromStringEncoding(reader)
+	if err != nil {
+		return nil, err
+	}
+	return entry.NewStreamID(ms, seq), nil
+}
+
+func getRawStreamID(reader *rdbReader) (*entry.StreamID, error) {
+	data, err := getNBytesFromReader(reader, STREAM_ID_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	return streamIDFromBytes(data), nil
+}
+
+// getMillisecondTime reads a unix time in milliseconds stored as 8
+// little-endian bytes.
+func getMillisecondTime(reader *rdbReader) (int64, error) {
+	data, err := getNBytesFromReader(reader, 8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(data)), nil
+}
@@ -0,0 +1,131 @@
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/app/entry"
+)
+
+// describeStream renders the entries, metadata and groups of a stream for
+// comparison, deleted entries marked with ~.
+func describeStream(s *entry.Stream) string {
+	var b strings.Builder
+	s.Nodes(func(master *entry.StreamID, masterFields []string, items []entry.StreamNodeItem) bool {
+		fmt.Fprintf(&b, "node %s %v:", master, masterFields)
+		for _, item := range items {
+			deleted := ""
+			if item.Deleted {
+				deleted = "~"
+			}
+			fmt.Fprintf(&b, " %s%s", item.ID, deleted)
+			for _, kv := range item.Fields {
+				fmt.Fprintf(&b, " %s=%s", kv.Key, kv.Value)
+			}
+		}
+		b.WriteString("\n")
+		return true
+	})
+	fmt.Fprintf(&b, "length %d first %v last %s max-deleted %s added %d\n", s.Len(), s.FirstID(), s.LastID(), s.MaxDeletedID(), s.EntriesAdded())
+	for _, g := range s.Groups() {
+		fmt.Fprintf(&b, "group %s %s read %d:", g.Name, g.LastID(), g.EntriesRead())
+		for _, p := range g.Pending(nil) {
+			fmt.Fprintf(&b, " %s@%d*%d", p.ID, p.DeliveryTime, p.DeliveryCount)
+		}
+		for _, c := range g.Consumers() {
+			fmt.Fprintf(&b, " %s(%d,%d)", c.Name, c.SeenTime, c.ActiveTime)
+			for _, p := range g.Pending(c) {
+				fmt.Fprintf(&b, " %s", p.ID)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestWriteStream(t *testing.T) {
+	s := entry.NewStream(0, 3)
+	for i := 1; i <= 7; i++ {
+		fields := []*entry.KeyValue{{Key: "n", Value: fmt.Sprint(i)}, {Key: "text", Value: strings.Repeat("x", i*10)}}
+		if i%3 == 2 {
+			fields = []*entry.KeyValue{{Key: "other", Value: "-5"}}
+		}
+		if _, err := s.Add(fmt.Sprintf("%d-%d", 1000+i/2, i%2), fields); err != nil {
+			t.Fatalf("Error adding entry %d: %s", i, err)
+		}
+	}
+	s.Delete([]*entry.StreamID{entry.NewStreamID(1000, 1), entry.NewStreamID(1002, 1)})
+	s.Delete([]*entry.StreamID{entry.NewStreamID(1002, 0), entry.NewStreamID(1003, 0)})
+	s.CreateGroup("readers", entry.NewStreamID(0, 0), 0)
+	s.CreateGroup("idle", entry.NewStreamID(1001, 1), entry.STREAM_ENTRIES_READ_UNKNOWN)
+	g := s.Group("readers")
+	alice, _ := g.CreateConsumer("alice", 100)
+	bob, _ := g.CreateConsumer("bob", 200)
+	g.CreateConsumer("carol", 300)
+	s.ReadGroup(g, alice, 2, false, 1000)
+	s.ReadGroup(g, bob, 1, false, 2000)
+	s.Claim(g, bob, []*entry.StreamID{entry.NewStreamID(1001, 0)}, entry.StreamClaim{DeliveryTime: 3000, RetryCount: -1}, 4000)
+	empty := entry.NewStream(entry.DEFAULT_STREAM_NODE_MAX_BYTES, entry.DEFAULT_STREAM_NODE_MAX_ENTRIES)
+	empty.Add("5-5", []*entry.KeyValue{{Key: "f", Value: "v"}})
+	empty.Delete([]*entry.StreamID{entry.NewStreamID(5, 5)})
+
+	for _, compression := range []bool{false, true} {
+		var buf bytes.Buffer
+		database := map[int]map[string]entry.Entry{0: {"stream": s, "empty": empty}}
+		if err := Write(&buf, database, Options{Compression: compression, Checksum: true}); err != nil {
+			t.Fatalf("Error writing: %s", err)
+		}
+		got, err := newRdbFromReader(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("Error reading back: %s", err)
+		}
+		for key, expected := range database[0] {
+			loaded, ok := got.Database[0][key].(*entry.Stream)
+			if !ok {
+				t.Errorf("Expected %s to be read back as a stream; got %v", key, got.Database[0][key])
+				continue
+			}
+			if describeStream(loaded) != describeStream(expected.(*entry.Stream)) {
+				t.Errorf("Expected %s to read back as\n%s; got\n%s", key, describeStream(expected.(*entry.Stream)), describeStream(loaded))
+			}
+		}
+	}
+}
+
+func TestGetStreamVersion1(t *testing.T) {
+	lp := &listpack{}
+	for _, v := range []string{"2", "1", "1", "f", "0"} {
+		lp.appendString(v)
+	}
+	for _, v := range []string{"2", "0", "0", "a", "4", "3", "0", "1", "b", "4", "0", "1", "1", "1", "g", "c", "6"} {
+		lp.appendString(v)
+	}
+	pel := binary.BigEndian.AppendUint64(nil, 10)
+	pel = binary.BigEndian.AppendUint64(pel, 1)
+	parts := [][]byte{
+		{1}, rdbString(string(streamIDBytes(entry.NewStreamID(10, 0)))), rdbString(string(lp.bytes())),
+		{2, 12, 0},
+		{1}, rdbString("g"), {10, 1},
+		{1}, pel, binary.LittleEndian.AppendUint64(nil, 5000), {3},
+		{1}, rdbString("c"), binary.LittleEndian.AppendUint64(nil, 6000), {1}, pel,
+	}
+	e, err := getObject(readerOf(parts...), TYPE_STREAM_LISTPACKS)
+	if err != nil {
+		t.Fatalf("Error reading stream: %s", err)
+	}
+	expected := "node 10-0 [f]: 10-0 f=a 10-1~ f=b 11-1 g=c\n" +
+		"length 2 first 10-0 last 12-0 max-deleted 0-0 added 2\n" +
+		"group g 10-1 read -1: 10-1@5000*3 c(6000,6000) 10-1\n"
+	if got := describeStream(e.(*entry.Stream)); got != expected {
+		t.Errorf("Expected\n%s; got\n%s", expected, got)
+	}
+
+	parts[len(parts)-1] = streamIDBytes(entry.NewStreamID(11, 1))
+	if _, err := getObject(readerOf(parts...), TYPE_STREAM_LISTPACKS); err == nil {
+		t.Errorf("Expected an entry pending for an unknown consumer to fail")
+	}
+}
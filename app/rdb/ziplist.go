@@ -0,0 +1,216 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	ZIPLIST_HEADER_SIZE int  = 10
+	ZIPLIST_END         byte = 0xFF
+	ZIPMAP_BIGLEN       byte = 254
+	ZIPMAP_END          byte = 0xFF
+)
+
+var errTruncatedEncoding = errors.New("encoded value runs past its end")
+
+// decodeZiplist returns the elements of a serialized ziplist, the encoding
+// listpacks replaced in Redis 7. It has a 4-byte size, a 4-byte offset of
+// the last element and a 2-byte element count, all little-endian, then the
+// elements and a terminating 0xFF. Each element starts with the length of
+// the one before it, in 1 byte or 0xFE and 4 bytes, then its encoding.
+func decodeZiplist(b []byte) ([]string, error) {
+	if len(b) < ZIPLIST_HEADER_SIZE+1 {
+		return nil, fmt.Errorf("ziplist of %d bytes is too short", len(b))
+	}
+	if total := int(binary.LittleEndian.Uint32(b)); total != len(b) {
+		return nil, fmt.Errorf("ziplist header says %d bytes, got %d", total, len(b))
+	}
+	elements := []string{}
+	for pos := ZIPLIST_HEADER_SIZE; ; {
+		if pos >= len(b) {
+			return nil, errors.New("ziplist is missing its terminator")
+		}
+		if b[pos] == ZIPLIST_END {
+			break
+		}
+		if b[pos] == 0xFE {
+			pos += 5
+		} else {
+			pos++
+		}
+		if pos >= len(b) {
+			return nil, errTruncatedEncoding
+		}
+		element, size, err := decodeZiplistElement(b[pos:])
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+		pos += size
+	}
+	return elements, nil
+}
+
+// decodeZiplistElement decodes the encoding and data at the start of b and
+// returns the element with their size.
+func decodeZiplistElement(b []byte) (string, int, error) {
+	enc := b[0]
+	var start, length int
+	switch enc >> 6 {
+	case 0b00:
+		start, length = 1, int(enc&0x3F)
+	case 0b01:
+		if len(b) < 2 {
+			return "", 0, errTruncatedEncoding
+		}
+		start, length = 2, int(enc&0x3F)<<8|int(b[1])
+	case 0b10:
+		if len(b) < 5 {
+			return "", 0, errTruncatedEncoding
+		}
+		start, length = 5, int(binary.BigEndian.Uint32(b[1:]))
+	default:
+		return decodeZiplistInt(b)
+	}
+	if start+length > len(b) {
+		return "", 0, errTruncatedEncoding
+	}
+	return string(b[start : start+length]), start + length, nil
+}
+
+func decodeZiplistInt(b []byte) (string, int, error) {
+	enc := b[0]
+	if enc >= 0xF1 && enc <= 0xFD {
+		return strconv.Itoa(int(enc&0x0F) - 1), 1, nil
+	}
+	var size int
+	switch enc {
+	case 0xFE:
+		size = 1
+	case 0xC0:
+		size = 2
+	case 0xF0:
+		size = 3
+	case 0xD0:
+		size = 4
+	case 0xE0:
+		size = 8
+	default:
+		return "", 0, fmt.Errorf("invalid ziplist encoding %#x", enc)
+	}
+	if 1+size > len(b) {
+		return "", 0, errTruncatedEncoding
+	}
+	var v int64
+	switch size {
+	case 1:
+		v = int64(int8(b[1]))
+	case 2:
+		v = int64(int16(binary.LittleEndian.Uint16(b[1:])))
+	case 3:
+		v = int64(int32(uint32(b[1])<<8|uint32(b[2])<<16|uint32(b[3])<<24) >> 8)
+	case 4:
+		v = int64(int32(binary.LittleEndian.Uint32(b[1:])))
+	default:
+		v = int64(binary.LittleEndian.Uint64(b[1:]))
+	}
+	return strconv.FormatInt(v, 10), 1 + size, nil
+}
+
+// decodeZipmap returns the alternating keys and values of a serialized
+// zipmap, the hash encoding before Redis 2.6. It has a 1-byte count, then
+// for each pair the key length and key, the value length, a byte counting
+// the free bytes after the value, and the value, ending with 0xFF. Lengths
+// are 1 byte, or 254 and 4 little-endian bytes.
+func decodeZipmap(b []byte) ([]string, error) {
+	if len(b) < 2 {
+		return nil, fmt.Errorf("zipmap of %d bytes is too short", len(b))
+	}
+	elements := []string{}
+	for pos := 1; ; {
+		if pos >= len(b) {
+			return nil, errors.New("zipmap is missing its terminator")
+		}
+		if b[pos] == ZIPMAP_END {
+			break
+		}
+		key, next, err := decodeZipmapString(b, pos, 0)
+		if err != nil {
+			return nil, err
+		}
+		if next >= len(b) {
+			return nil, errTruncatedEncoding
+		}
+		value, next, err := decodeZipmapString(b, next, 1)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, key, value)
+		pos = next
+	}
+	if len(elements)%2 != 0 {
+		return nil, errors.New("zipmap has a key without a value")
+	}
+	return elements, nil
+}
+
+// decodeZipmapString decodes the length at pos and the string after it,
+// skipping the free-bytes counter and the free bytes for values, and returns
+// the string with where the next one starts.
+func decodeZipmapString(b []byte, pos int, freeBytes int) (string, int, error) {
+	length := int(b[pos])
+	pos++
+	if byte(length) == ZIPMAP_BIGLEN {
+		if pos+4 > len(b) {
+			return "", 0, errTruncatedEncoding
+		}
+		length = int(binary.LittleEndian.Uint32(b[pos:]))
+		pos += 4
+	}
+	free := 0
+	if freeBytes > 0 {
+		if pos >= len(b) {
+			return "", 0, errTruncatedEncoding
+		}
+		free = int(b[pos])
+		pos++
+	}
+	if pos+length+free > len(b) {
+		return "", 0, errTruncatedEncoding
+	}
+	return string(b[pos : pos+length]), pos + length + free, nil
+}
+
+// decodeIntset returns the members of a serialized intset: a 4-byte width of
+// its integers, a 4-byte count, then the integers, all little-endian.
+func decodeIntset(b []byte) ([]string, error) {
+	if len(b) < 8 {
+		return nil, fmt.Errorf("intset of %d bytes is too short", len(b))
+	}
+	width := int(binary.LittleEndian.Uint32(b))
+	count := int(binary.LittleEndian.Uint32(b[4:]))
+	if width != INTSET_ENC_INT16 && width != INTSET_ENC_INT32 && width != INTSET_ENC_INT64 {
+		return nil, fmt.Errorf("invalid intset encoding %d", width)
+	}
+	if len(b) != 8+width*count {
+		return nil, fmt.Errorf("intset of %d %d-byte integers has %d bytes", count, width, len(b))
+	}
+	members := make([]string, count)
+	for i := range members {
+		data := b[8+i*width:]
+		var v int64
+		switch width {
+		case INTSET_ENC_INT16:
+			v = int64(int16(binary.LittleEndian.Uint16(data)))
+		case INTSET_ENC_INT32:
+			v = int64(int32(binary.LittleEndian.Uint32(data)))
+		default:
+			v = int64(binary.LittleEndian.Uint64(data))
+		}
+		members[i] = strconv.FormatInt(v, 10)
+	}
+	return members, nil
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
+	"github.com/codecrafters-io/redis-starter-go/app/event"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+)
+
+// CRON_INTERVAL is how often the background jobs run, ten times a second
+// like the Redis serverCron at its default hz.
+const CRON_INTERVAL time.Duration = 100 * time.Millisecond
+
+// cron queues the background jobs on the event loop at every tick, skipping
+// ticks while the previous run is still waiting in the queue.
+func (r *redisServer) cron() {
+	ticker := time.NewTicker(CRON_INTERVAL)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !r.cronPending.CompareAndSwap(false, true) {
+			continue
+		}
+		r.EventQueue.Add(&event.Event{Callback: func() {
+			r.cronPending.Store(false)
+			ctx := r.newContext(nil, replication.CONN_TYPE_CLIENT)
+			r.retention.Cycle(&ctx)
+			r.saveIfDue()
+			r.aof.Cron(aof.OptionsFromConfig(r.configParams), r.store)
+		}})
+	}
+}
+
+// saveIfDue starts a background save when one of the save points is reached.
+func (r *redisServer) saveIfDue() {
+	points, err := rdb.ParseSavePoints(r.configParams["save"])
+	if err != nil {
+		return
+	}
+	point, ok := r.saver.DueSavePoint(points)
+	if !ok {
+		return
+	}
+	log.Printf("%d changes in %d seconds. Saving...", point.Changes, point.Seconds)
+	err = r.saver.BackgroundSave(rdb.OptionsFromConfig(r.configParams), r.store, func(err error) {
+		if err != nil {
+			log.Printf("Background saving error: %s", err)
+			return
+		}
+		log.Printf("Background saving terminated with success")
+	})
+	if err != nil {
+		log.Printf("Error starting background save: %s", err)
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
+	"github.com/codecrafters-io/redis-starter-go/app/rdb"
+	"github.com/codecrafters-io/redis-starter-go/app/replication"
+	"github.com/codecrafters-io/redis-starter-go/app/utils"
+)
+
+// loadDataFromDisk fills the store from the append-only file when it is on
+// and there is one, and from the RDB file otherwise. It then opens the
+// append-only file, if it is on, for the writes to come.
+func (r *redisServer) loadDataFromDisk() error {
+	opts := aof.OptionsFromConfig(r.configParams)
+	if opts.Enabled {
+		err := aof.Load(opts, r.store, r.replayCommand)
+		if err == nil {
+			log.Printf("DB loaded from append only file")
+			return r.aof.Open(opts, r.store)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	rdbFile, err := rdb.NewRdbFromFile(rdb.OptionsFromConfig(r.configParams))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if rdbFile != nil {
+		r.store = rdbFile.Database
+	}
+	if !opts.Enabled {
+		return nil
+	}
+	return r.aof.Open(opts, r.store)
+}
+
+// replayCommand runs a command read back from the append-only file in
+// database db.
+func (r *redisServer) replayCommand(db int, args []string) error {
+	ctx := r.newContext(nil, replication.CONN_TYPE_CLIENT)
+	ctx.CurrentDatabase = db
+	return r.commandRegistry.Execute(utils.Command{CMD: args[0], ARGS: args[1:]}, &ctx)
+}
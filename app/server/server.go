@@ -8,7 +8,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/codecrafters-io/redis-starter-go/app/aof"
 	"github.com/codecrafters-io/redis-starter-go/app/command"
 	"github.com/codecrafters-io/redis-starter-go/app/entry"
 	"github.com/codecrafters-io/redis-starter-go/app/event"
@@ -23,6 +25,7 @@ type redisServer struct {
 	clients         map[net.Conn]bool
 	clientMutex     sync.RWMutex
 	EventQueue      event.EventQueue
+	blocking        *event.BlockingRegistry
 	syncList        *syncList
 	parser          *protocol.Parser
 	commandRegistry command.CommandRegistry
@@ -30,6 +33,10 @@ type redisServer struct {
 	configParams    map[string]string
 	currentDatabase int
 	replicationInfo *replication.ReplicationInfo
+	retention       *command.StreamRetention
+	saver           *rdb.Saver
+	aof             *aof.AOF
+	cronPending     atomic.Bool
 }
 
 func New(configParams map[string]string, replInfo *replication.ReplicationInfo) (*redisServer, error) {
@@ -42,34 +49,39 @@ func New(configParams map[string]string, replInfo *replication.ReplicationInfo)
 	if err != nil {
 		return nil, err
 	}
+	if err := command.ValidateConfig(configParams); err != nil {
+		return nil, err
+	}
 	p := protocol.NewParser()
 	reg := command.NewCommandRegistry()
 
-	rdbFile, _ := rdb.NewRdbFromFile(configParams["dir"], configParams["dbfilename"])
-	var s map[int]map[string]entry.Entry
-	if rdbFile == nil {
-		s = make(map[int]map[string]entry.Entry)
-	} else {
-		s = rdbFile.Database
-	}
-	return &redisServer{
+	rs := &redisServer{
 		listener:        l,
 		clients:         make(map[net.Conn]bool),
 		EventQueue:      *event.NewEventQueue(),
 		syncList:        &syncList{},
 		parser:          p,
 		commandRegistry: reg,
-		store:           s,
+		store:           make(map[int]map[string]entry.Entry),
 		configParams:    configParams,
 		currentDatabase: 0,
 		replicationInfo: replInfo,
-	}, nil
+		retention:       command.NewStreamRetention(),
+		saver:           rdb.NewSaver(),
+		aof:             aof.New(),
+	}
+	rs.blocking = event.NewBlockingRegistry(&rs.EventQueue)
+	if err := rs.loadDataFromDisk(); err != nil {
+		return nil, err
+	}
+	return rs, nil
 }
 
 func (r *redisServer) Run() error {
 	defer r.listener.Close()
 
 	go r.eventLoop()
+	go r.cron()
 	go r.SyncWithMaster()
 
 	for {
@@ -81,7 +93,14 @@ func (r *redisServer) Run() error {
 	}
 }
 
+// handleEvent runs an event, then writes out what it fed to the
+// append-only file, the way Redis does before going back to its event loop.
 func (r *redisServer) handleEvent(event *event.Event) {
+	defer r.aof.Flush(r.configParams["appendfsync"])
+	if event.Callback != nil {
+		event.Callback()
+		return
+	}
 	err := r.commandRegistry.Handle(event.Cmd, &event.Ctx)
 	if err != nil {
 		log.Printf("Error handling command: %s", err)
@@ -108,6 +127,9 @@ func (r *redisServer) handleConnection(conn net.Conn, t replication.ConnType) {
 		r.clientMutex.Lock()
 		delete(r.clients, conn)
 		r.clientMutex.Unlock()
+		r.EventQueue.Add(&event.Event{Callback: func() {
+			r.blocking.UnblockConn(conn)
+		}})
 	}()
 
 	reader := bufio.NewReader(conn)
@@ -115,20 +137,30 @@ func (r *redisServer) handleConnection(conn net.Conn, t replication.ConnType) {
 		commandChan := make(chan utils.Command)
 		replicaRespChan := make(chan string)
 		go r.parser.Parse(reader, commandChan, replicaRespChan)
-		ctx := event.Context{
-			Conn:            conn,
-			ConnType:        t,
-			CurrentDatabase: r.currentDatabase,
-			Store:           r.store,
-			ConfigParams:    r.configParams,
-			ReplicationInfo: r.replicationInfo,
-			EventQueue:      &r.EventQueue,
-		}
+		ctx := r.newContext(conn, t)
 		r.handleChannels(commandChan, replicaRespChan, conn, ctx)
+		if _, err := reader.Peek(1); err != nil {
+			return
+		}
 	}
 
 }
 
+func (r *redisServer) newContext(conn net.Conn, t replication.ConnType) event.Context {
+	return event.Context{
+		Conn:            conn,
+		ConnType:        t,
+		CurrentDatabase: r.currentDatabase,
+		Store:           r.store,
+		ConfigParams:    r.configParams,
+		ReplicationInfo: r.replicationInfo,
+		EventQueue:      &r.EventQueue,
+		Blocking:        r.blocking,
+		Saver:           r.saver,
+		AOF:             r.aof,
+	}
+}
+
 func (r *redisServer) handleChannels(commandChan chan utils.Command, replicaRespChan chan string, conn net.Conn, ctx event.Context) {
 	for {
 		select {
@@ -5,6 +5,7 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -50,8 +51,118 @@ func SlicesEqual[T comparable](a, b []T) bool {
 	return true
 }
 
+// memoryUnits are the suffixes a memory config value can have, longest
+// first: k, m and g are powers of 1000 and kb, mb and gb powers of 1024.
+var memoryUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000}, {"b", 1},
+}
+
+// ParseMemory reads a memory config value such as "64mb" as a number of
+// bytes, the units being case-insensitive.
+func ParseMemory(value string) (int64, error) {
+	lower := strings.ToLower(value)
+	multiplier := int64(1)
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(lower, unit.suffix) {
+			lower, multiplier = strings.TrimSuffix(lower, unit.suffix), unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil || n < 0 || n > (1<<63-1)/multiplier {
+		return 0, fmt.Errorf("invalid memory value %q", value)
+	}
+	return n * multiplier, nil
+}
+
 func WriteToConnection(conn net.Conn, b []byte) {
 	if _, err := conn.Write(b); err != nil {
 		log.Printf("Error writing to connection %s", err.Error())
 	}
 }
+
+// GlobMatch reports whether s matches the glob-style pattern the way Redis
+// matches key patterns: "*" matches any run of characters, "?" any single
+// one, "[...]" a class that may be negated with "^" and hold ranges, and "\"
+// escapes the character after it.
+func GlobMatch(pattern string, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if GlobMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end, matched := matchGlobClass(pattern, s[0])
+			if !matched {
+				return false
+			}
+			pattern = pattern[end:]
+			s = s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			s = s[1:]
+		}
+		pattern = pattern[1:]
+	}
+	return len(s) == 0
+}
+
+// matchGlobClass matches c against the class pattern starts with, returning
+// the index of its closing "]" and whether c is in it.
+func matchGlobClass(pattern string, c byte) (int, bool) {
+	i := 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+	matched := false
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			matched = matched || pattern[i] == c
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			matched = matched || (c >= lo && c <= hi)
+			i += 2
+		default:
+			matched = matched || pattern[i] == c
+		}
+	}
+	if i == len(pattern) {
+		i--
+	}
+	return i, matched != negate
+}
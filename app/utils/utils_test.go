@@ -0,0 +1,62 @@
+package utils
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		s        string
+		expected bool
+	}{
+		{"*", "anything", true},
+		{"events:*", "events:2024/01", true},
+		{"events:*", "event:1", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "axb", false},
+		{"orders", "orders", true},
+		{"orders", "orders:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := GlobMatch(tt.pattern, tt.s); got != tt.expected {
+			t.Errorf("%s against %s: expected %v; got %v", tt.pattern, tt.s, tt.expected, got)
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+		valid    bool
+	}{
+		{"67108864", 64 << 20, true},
+		{"64mb", 64 << 20, true},
+		{"64MB", 64 << 20, true},
+		{"2k", 2000, true},
+		{"2kb", 2048, true},
+		{"1g", 1000 * 1000 * 1000, true},
+		{"100b", 100, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"mb", 0, false},
+		{"64 mb", 0, false},
+		{"9999999999gb", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMemory(tt.value)
+		if (err == nil) != tt.valid || got != tt.expected {
+			t.Errorf("ParseMemory(%q) = %d, %v; expected %d, valid %v", tt.value, got, err, tt.expected, tt.valid)
+		}
+	}
+}
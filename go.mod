@@ -4,4 +4,4 @@ go 1.24.0
 
 require github.com/deckarep/golang-set/v2 v2.8.0
 
-require github.com/google/btree v1.1.3 // indirect
+require github.com/google/btree v1.1.3